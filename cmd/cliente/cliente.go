@@ -2,11 +2,15 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	mensajero "mensajero/pkg"
+
+	"golang.org/x/term"
 )
 
 const (
@@ -21,13 +25,86 @@ func main() {
 	punteroUsuario := flag.String("u", "", "nombre de usuario usado por el cliente")
 	punteroPuertoServidor := flag.String("p", "", "puerto a conectarse")
 	punteroDireccionServidor := flag.String("d", "", "dirección del servidor")
+	punteroConversar := flag.Bool("c", false, "recibir los mensajes en cuanto llegan en lugar de usar obtener")
+	punteroCuentaNueva := flag.Bool("nueva", false, "crear la cuenta del usuario antes de conectarse")
+	punteroCuenta := flag.Bool("cuenta", false, "pedir la contraseña de la cuenta del usuario antes de conectarse, sin esperar a que el servidor la pida")
+	punteroArchivoSesion := flag.String("sesion", "", "archivo donde se guarda la sesión para reanudarla si el cliente termina sin salir (uno por usuario y servidor si no se indica)")
+	punteroCerrarOtras := flag.Bool("expulsar", false, "cerrar las demás sesiones del usuario al conectarse")
+	punteroAutoridades := flag.String("ca", "", "autoridades PEM con las que se verifica el certificado del servidor; conectarse con TLS")
+	punteroCertificado := flag.String("cert", "", "certificado PEM del cliente para el TLS mutuo; el usuario es su CN")
+	punteroClave := flag.String("clave", "", "clave PEM del certificado de -cert")
 	flag.Parse()
 
-	iniciar(*punteroUsuario, *punteroPuertoServidor, *punteroDireccionServidor)
+	seguridad := configuracionTLS{
+		autoridades: *punteroAutoridades,
+		certificado: *punteroCertificado,
+		clave:       *punteroClave,
+	}
+	iniciar(*punteroUsuario, *punteroPuertoServidor, *punteroDireccionServidor, *punteroConversar, *punteroCuenta, *punteroCuentaNueva, *punteroArchivoSesion, *punteroCerrarOtras, seguridad)
+}
+
+// Los archivos para conectarse con TLS, vacíos si no se usan.
+type configuracionTLS struct {
+	autoridades string
+	certificado string
+	clave       string
 }
 
-func iniciar(usuario string, puertoServidor string, direccionServidor string) {
+// Devuelve las opciones de TLS del cliente, ninguna si la conexión no se cifra.
+func (c configuracionTLS) opciones() []mensajero.OpcionCliente {
+	opciones := []mensajero.OpcionCliente{}
+	if c.autoridades != "" {
+		opciones = append(opciones, mensajero.ConTLS(c.autoridades))
+	}
+	if c.certificado != "" {
+		opciones = append(opciones, mensajero.ConCertificadoCliente(c.certificado, c.clave))
+	}
+	return opciones
+}
 
+// Pide la contraseña sin mostrarla en la terminal. Si la entrada no es una terminal, la
+// lee de la primera línea.
+func leerContrasena(usuario string) (string, error) {
+	fmt.Printf("Contraseña de %s: ", usuario)
+	descriptor := int(os.Stdin.Fd())
+	if term.IsTerminal(descriptor) {
+		contrasena, err := term.ReadPassword(descriptor)
+		fmt.Println()
+		return string(contrasena), err
+	}
+
+	// se lee de a un byte para no consumir las líneas siguientes, que son comandos
+	var linea []byte
+	caracter := make([]byte, 1)
+	for {
+		if _, err := os.Stdin.Read(caracter); err != nil || caracter[0] == '\n' {
+			return strings.TrimSuffix(string(linea), "\r"), err
+		}
+		linea = append(linea, caracter[0])
+	}
+}
+
+// Devuelve el archivo de sesión predeterminado del usuario en el servidor, en el directorio
+// de configuración del sistema, o una cadena vacía si no hay uno.
+func archivoSesionPredeterminado(usuario string, direccionServidor string, puertoServidor string) string {
+	directorio, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(directorio, "mensajero", fmt.Sprintf("%s_%s_%s.sesion", direccionServidor, puertoServidor, usuario))
+}
+
+func iniciar(usuario string, puertoServidor string, direccionServidor string, conversar bool, conCuenta bool, cuentaNueva bool, archivoSesion string, cerrarOtras bool, seguridad configuracionTLS) {
+
+	// con TLS mutuo el servidor toma el usuario del certificado
+	if usuario == "" && seguridad.certificado != "" {
+		usuarioCertificado, err := mensajero.UsuarioCertificado(seguridad.certificado)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		usuario = usuarioCertificado
+	}
 	if usuario == "" {
 		usuario = USUARIO_PREDETERMINADO
 	}
@@ -38,17 +115,77 @@ func iniciar(usuario string, puertoServidor string, direccionServidor string) {
 		puertoServidor = PUERTO_SERVIDOR_PREDETERMINADO
 	}
 
+	// la contraseña se pide de entrada si se indicó que el usuario tiene una cuenta o la va
+	// a crear; si no, solo cuando el servidor la rechaza. El certificado del cliente
+	// reemplaza a la contraseña.
+	opciones := seguridad.opciones()
+	pedirContrasena := seguridad.certificado == ""
+	if pedirContrasena && (conCuenta || cuentaNueva) {
+		contrasena, err := leerContrasena(usuario)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		opciones = append(opciones, mensajero.ConContrasena(contrasena))
+		pedirContrasena = false
+	}
+	if cuentaNueva {
+		opciones = append(opciones, mensajero.ConCuentaNueva())
+	}
+	if archivoSesion == "" {
+		archivoSesion = archivoSesionPredeterminado(usuario, direccionServidor, puertoServidor)
+	}
+	if archivoSesion != "" {
+		opciones = append(opciones, mensajero.ConArchivoSesion(archivoSesion))
+	}
+	if cerrarOtras {
+		opciones = append(opciones, mensajero.ConCerrarOtras())
+	}
+
 	direccion := fmt.Sprintf("%s:%s", direccionServidor, puertoServidor)
-	conexion, cliente, ctx, err := mensajero.ConfigurarCliente(direccion, usuario, TEMPORIZADOR_EN_SEGUNDOS_PREDETERMINADO)
+	conexion, cliente, ctx, err := mensajero.ConfigurarCliente(direccion, usuario, TEMPORIZADOR_EN_SEGUNDOS_PREDETERMINADO, opciones...)
+	if pedirContrasena && mensajero.RazonError(err) == mensajero.RAZON_CREDENCIALES_INCORRECTAS {
+		// el servidor usa cuentas: se pide la contraseña y se vuelve a intentar
+		contrasena, errContrasena := leerContrasena(usuario)
+		if errContrasena != nil {
+			fmt.Println(errContrasena)
+			return
+		}
+		opciones = append(opciones, mensajero.ConContrasena(contrasena))
+		conexion, cliente, ctx, err = mensajero.ConfigurarCliente(direccion, usuario, TEMPORIZADOR_EN_SEGUNDOS_PREDETERMINADO, opciones...)
+	}
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
 	defer conexion.Close()
 
+	// en modo conversación los mensajes se envían y se reciben por un único flujo
+	var conversacion *mensajero.Conversacion
+	if conversar {
+		conversacion, err = mensajero.AbrirConversacion(cliente, ctx)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		go func() {
+			for mensaje := range conversacion.Mensajes {
+				fmt.Printf("\n%s\n%s@ ", mensajero.FormatearMensaje(mensaje), usuario)
+			}
+		}()
+	}
+
 	fmt.Printf("Bienvenido %s. Pruebe cualquiera de los siguientes comandos\n", usuario)
-	fmt.Println("\t obtener - ver los nuevos mensajes desde la última actualización")
+	fmt.Println("\t obtener [cantidad] - ver los nuevos mensajes desde la última actualización, hasta <cantidad> si se indica")
 	fmt.Println("\t listar - ver todos los usuarios conectados")
+	fmt.Println("\t canales - ver todos los canales")
+	fmt.Println("\t /crear|/unirse|/abandonar #<canal> - Crea un canal, se une a él o lo abandona")
+	fmt.Println("\t /miembros #<canal> - ver los miembros de un canal")
+	fmt.Println("\t #<canal> <mensaje...> - Publica <mensaje> en el canal")
+	fmt.Println("\t /difundir <mensaje...> - Envía <mensaje> a todos los usuarios conectados (solo administradores)")
+	fmt.Println("\t historial <usuario> - ver los últimos mensajes intercambiados con <usuario>")
+	fmt.Println("\t sesiones - ver las sesiones abiertas del usuario, en este y otros dispositivos")
+	fmt.Println("\t /revocar <sesión> - Cierra una de las sesiones del usuario")
 	fmt.Println("\t salir - Se desconecta")
 	fmt.Println("\t <usuario> <mensaje...> - Envía <mensaje> al <usuario>")
 
@@ -59,15 +196,33 @@ func iniciar(usuario string, puertoServidor string, direccionServidor string) {
 		linea = strings.TrimSpace(linea)
 		args := strings.SplitN(linea, " ", 2)
 
-		respuesta, err := mensajero.Ejecutar(cliente, ctx, args...)
-		if err != nil {
-			fmt.Println(err)
-			switch err.(type) {
-			case *mensajero.ErrorDesconexion:
+		if conversacion != nil && mensajero.EsMensajeDirecto(args...) {
+			if _, err := conversacion.Enviar(args[0], args[1]); err != nil && informarError(err) {
 				return
-			default:
 			}
+			continue
+		}
+
+		respuesta, err := mensajero.Ejecutar(cliente, ctx, args...)
+		if err != nil && informarError(err) {
+			return
 		}
 		fmt.Println(respuesta)
 	}
 }
+
+// Muestra el error de un comando y devuelve si el cliente debe terminar, porque el usuario
+// se desconectó o su sesión ya no es válida.
+func informarError(err error) bool {
+	fmt.Println(err)
+	switch {
+	case errors.Is(err, mensajero.ErrDesconectado):
+		return true
+	case errors.Is(err, mensajero.ErrNoAutenticado):
+		fmt.Println("La sesión terminó: vuelva a iniciar el cliente para conectarse de nuevo.")
+		return true
+	case errors.Is(err, mensajero.ErrBandejaLlena), errors.Is(err, mensajero.ErrServidorNoDisponible):
+		fmt.Println("Vuelva a intentarlo más tarde.")
+	}
+	return false
+}
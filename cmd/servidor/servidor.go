@@ -3,6 +3,9 @@ package main
 import (
     "flag"
     "fmt"
+    "net/http"
+    "os"
+    "strings"
     "google.golang.org/grpc"
     mensajero "mensajero/pkg"
 )
@@ -11,6 +14,26 @@ func main() {
 
 	// para argumento -p puerto
     punteroPuertoServidor := flag.String("p", "12345", "puerto del servidor")
+    punteroCuentas := flag.String("cuentas", "", "archivo donde se guardan las cuentas de los usuarios; vacío para que cualquiera se conecte sin contraseña")
+    punteroAltas := flag.Int("altas", mensajero.ALTAS_POR_INTERVALO, "cantidad de cuentas que se pueden crear desde una misma dirección por minuto; 0 para no limitarlas")
+    punteroDiario := flag.String("diario", "", "archivo donde se guardan los mensajes para sobrevivir a un reinicio; vacío para no guardarlos")
+    punteroFsync := flag.String("fsync", "siempre", "cuándo sincronizar el diario con el disco: siempre, periodico o nunca")
+    punteroDesborde := flag.String("desborde", "rechazar", "qué hacer con los mensajes para una bandeja llena: rechazar, descartar-antiguo, descartar-nuevo o volcar")
+    punteroVolcado := flag.String("volcado", "", "directorio donde se guardan los mensajes que no entran en la bandeja con -desborde volcar; se borran los volcados que ya tenga al iniciar")
+    punteroLoteMaximo := flag.Int("lote-maximo", mensajero.LARGO_LOTE_MAXIMO, "cantidad máxima de mensajes que un cliente puede obtener en una llamada")
+    punteroEsperaMaxima := flag.Duration("espera-maxima", mensajero.ESPERA_MAXIMA, "cuánto puede esperar como máximo un cliente a que le llegue un mensaje")
+    punteroAdministradores := flag.String("admin", "", "usuarios separados por comas que pueden difundir mensajes a todos los conectados")
+    punteroHistorial := flag.Int("historial", mensajero.LARGO_ARCHIVO, "cantidad de mensajes que se guardan en el historial de cada usuario")
+    punteroSesion := flag.Duration("sesion", mensajero.DURACION_SESION, "cuánto tiempo vale un token de autenticación si el cliente no lo renueva")
+    punteroInactividad := flag.Duration("inactividad", mensajero.INACTIVIDAD_MAXIMA, "cuánto tiempo puede pasar una sesión sin usarse antes de cerrarse; 0 para no cerrarlas")
+    punteroVisibilidad := flag.Duration("visibilidad", mensajero.PLAZO_VISIBILIDAD, "cuánto tiempo tiene un usuario para confirmar un mensaje antes de que se le vuelva a entregar")
+    punteroCertificado := flag.String("cert", "", "certificado PEM del servidor para usar TLS; vacío para conexiones sin cifrar")
+    punteroClave := flag.String("clave", "", "clave PEM del certificado de -cert")
+    punteroAutoridadesClientes := flag.String("ca-clientes", "", "autoridades PEM que firman los certificados de los clientes, para exigir TLS mutuo; el usuario es el CN del certificado")
+    punteroFormatoRegistro := flag.String("registro-formato", "logfmt", "formato del registro de llamadas: logfmt o json")
+    punteroNivelRegistro := flag.String("registro-nivel", "info", "nivel mínimo del registro de llamadas: depuracion, info, advertencia, error o ninguno")
+    punteroCuerposRegistro := flag.Bool("registro-cuerpos", false, "mostrar el cuerpo de los mensajes en las solicitudes registradas con -registro-nivel depuracion")
+    punteroMetricas := flag.String("metricas", "", "dirección HTTP, como :9090, donde se sirven las métricas en formato Prometheus en /metrics; vacío para no servirlas")
     flag.Parse()
 	fmt.Println(*punteroPuertoServidor)
 
@@ -22,11 +45,109 @@ func main() {
         return
     }
 
-    servicioMensajero := mensajero.NuevoServidor()
+    opcionesRegistro := mensajero.OpcionesRegistroPredeterminadas
+    opcionesRegistro.Formato, err = mensajero.ParsearFormatoRegistro(*punteroFormatoRegistro)
+    if err != nil {
+        fmt.Println(err)
+        return
+    }
+    opcionesRegistro.Nivel, err = mensajero.ParsearNivelRegistro(*punteroNivelRegistro)
+    if err != nil {
+        fmt.Println(err)
+        return
+    }
+    opcionesRegistro.MostrarCuerpos = *punteroCuerposRegistro
+
+    opciones := []mensajero.OpcionServidor{mensajero.ConRegistro(mensajero.NuevoRegistro(os.Stdout, opcionesRegistro))}
+    if *punteroCuentas != "" {
+        credenciales, err := mensajero.AbrirAlmacenCredenciales(*punteroCuentas, mensajero.OpcionesCredencialesPredeterminadas)
+        if err != nil {
+            fmt.Println("No se pudieron abrir las cuentas: ", err)
+            return
+        }
+        opciones = append(opciones, mensajero.ConCredenciales(credenciales))
+        opciones = append(opciones, mensajero.ConLimiteAltas(*punteroAltas, mensajero.INTERVALO_ALTAS))
+    }
+    if *punteroDiario != "" {
+        opcionesDiario := mensajero.OpcionesDiarioPredeterminadas
+        opcionesDiario.Fsync, err = mensajero.ParsearPoliticaFsync(*punteroFsync)
+        if err != nil {
+            fmt.Println(err)
+            return
+        }
+        diario, err := mensajero.AbrirDiario(*punteroDiario, opcionesDiario)
+        if err != nil {
+            fmt.Println("No se pudo abrir el diario: ", err)
+            return
+        }
+        defer diario.Cerrar()
+        opciones = append(opciones, mensajero.ConDiario(diario))
+    }
+
+    politica, err := mensajero.ParsearPoliticaDesborde(*punteroDesborde)
+    if err != nil {
+        fmt.Println(err)
+        return
+    }
+    opciones = append(opciones, mensajero.ConPoliticaDesborde(politica))
+    if politica == mensajero.PoliticaVolcarADisco {
+        // el directorio se vacía al iniciar, así que no se toma uno sin que se indique
+        if *punteroVolcado == "" {
+            fmt.Println("Con -desborde volcar hay que indicar el directorio de volcado con -volcado")
+            return
+        }
+        volcado, err := mensajero.AbrirAlmacenVolcado(*punteroVolcado)
+        if err != nil {
+            fmt.Println("No se pudo abrir el directorio de volcado: ", err)
+            return
+        }
+        defer volcado.Cerrar()
+        opciones = append(opciones, mensajero.ConVolcado(volcado))
+    }
+
+    opciones = append(opciones, mensajero.ConDuracionSesion(*punteroSesion))
+    opciones = append(opciones, mensajero.ConInactividadMaxima(*punteroInactividad))
+    opciones = append(opciones, mensajero.ConPlazoVisibilidad(*punteroVisibilidad))
+    opciones = append(opciones, mensajero.ConLargoLoteMaximo(*punteroLoteMaximo))
+    opciones = append(opciones, mensajero.ConEsperaMaxima(*punteroEsperaMaxima))
+    opciones = append(opciones, mensajero.ConLargoArchivo(*punteroHistorial))
+    if *punteroAdministradores != "" {
+        opciones = append(opciones, mensajero.ConAdministradores(strings.Split(*punteroAdministradores, ",")...))
+    }
+
+    servicioMensajero := mensajero.NuevoServidor(opciones...)
+    defer servicioMensajero.Cerrar()
 
-    servidorReal := grpc.NewServer(
+    opcionesGrpc := []grpc.ServerOption{
         grpc.UnaryInterceptor(servicioMensajero.Interceptor),
-    )
+        grpc.StreamInterceptor(servicioMensajero.InterceptorFlujo),
+    }
+    if *punteroCertificado != "" {
+        credenciales, err := mensajero.CredencialesServidor(*punteroCertificado, *punteroClave, *punteroAutoridadesClientes)
+        if err != nil {
+            fmt.Println(err)
+            return
+        }
+        opcionesGrpc = append(opcionesGrpc, grpc.Creds(credenciales))
+    } else if *punteroAutoridadesClientes != "" {
+        fmt.Println("-ca-clientes requiere -cert y -clave")
+        return
+    }
+
+    if *punteroMetricas != "" {
+        rutas := http.NewServeMux()
+        rutas.Handle("/metrics", servicioMensajero.ManejadorMetricas())
+        servidorMetricas := &http.Server{Addr: *punteroMetricas, Handler: rutas}
+        go func() {
+            if err := servidorMetricas.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+                fmt.Println("No se pudieron servir las métricas: ", err)
+            }
+        }()
+        defer servidorMetricas.Close()
+        fmt.Println("Métricas en ", *punteroMetricas)
+    }
+
+    servidorReal := grpc.NewServer(opcionesGrpc...)
     mensajero.RegisterMensajeroServer(servidorReal, servicioMensajero)
     if err := servidorReal.Serve(listen); err != nil {
         fmt.Println("Falla: ", err)
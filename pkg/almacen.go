@@ -0,0 +1,531 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Cantidad de fragmentos en los que se reparte el estado del servidor. Cada fragmento
+// tiene su propio candado, de modo que las operaciones sobre usuarios distintos
+// rara vez compiten por el mismo candado.
+const NUMERO_FRAGMENTOS = 32
+
+// Devuelve el índice del fragmento que le corresponde a una clave.
+func indiceFragmento(clave string) int {
+	h := fnv.New32a()
+	h.Write([]byte(clave))
+	return int(h.Sum32() % NUMERO_FRAGMENTOS)
+}
+
+type fragmentoCadenas struct {
+	sync.RWMutex
+	valores map[string]string
+}
+
+// Un mapa de cadenas a cadenas repartido en fragmentos con su propio candado.
+type mapaFragmentado [NUMERO_FRAGMENTOS]*fragmentoCadenas
+
+func nuevoMapaFragmentado() *mapaFragmentado {
+	var m mapaFragmentado
+	for i := range m {
+		m[i] = &fragmentoCadenas{valores: make(map[string]string)}
+	}
+	return &m
+}
+
+func (m *mapaFragmentado) fragmento(clave string) *fragmentoCadenas {
+	return m[indiceFragmento(clave)]
+}
+
+func (m *mapaFragmentado) obtener(clave string) (string, bool) {
+	f := m.fragmento(clave)
+	f.RLock()
+	defer f.RUnlock()
+	valor, ok := f.valores[clave]
+	return valor, ok
+}
+
+func (m *mapaFragmentado) eliminar(clave string) {
+	f := m.fragmento(clave)
+	f.Lock()
+	delete(f.valores, clave)
+	f.Unlock()
+}
+
+// Asigna el valor solo si la clave no estaba presente; devuelve si lo asignó.
+func (m *mapaFragmentado) asignarSiFalta(clave string, valor string) bool {
+	f := m.fragmento(clave)
+	f.Lock()
+	defer f.Unlock()
+	if _, ok := f.valores[clave]; ok {
+		return false
+	}
+	f.valores[clave] = valor
+	return true
+}
+
+func (m *mapaFragmentado) claves() []string {
+	claves := []string{}
+	for _, f := range m {
+		f.RLock()
+		for clave := range f.valores {
+			claves = append(claves, clave)
+		}
+		f.RUnlock()
+	}
+	return claves
+}
+
+// DirectorioUsuarios guarda los usuarios conocidos por el servidor, estén conectados
+// o no. Un usuario entra al directorio la primera vez que se conecta y no sale de él al
+// desconectarse, de modo que se le pueden seguir enviando mensajes.
+type DirectorioUsuarios struct {
+	usuarios *mapaFragmentado
+}
+
+func NuevoDirectorioUsuarios() *DirectorioUsuarios {
+	return &DirectorioUsuarios{usuarios: nuevoMapaFragmentado()}
+}
+
+// Agrega el usuario al directorio. Devuelve false si ya estaba.
+func (d *DirectorioUsuarios) Registrar(usuario string) bool {
+	return d.usuarios.asignarSiFalta(usuario, "")
+}
+
+// Quita al usuario del directorio, por ejemplo si no se pudo completar su alta.
+func (d *DirectorioUsuarios) Olvidar(usuario string) {
+	d.usuarios.eliminar(usuario)
+}
+
+// Devuelve si el usuario es conocido por el servidor.
+func (d *DirectorioUsuarios) Existe(usuario string) bool {
+	_, ok := d.usuarios.obtener(usuario)
+	return ok
+}
+
+// Devuelve todos los usuarios conocidos por el servidor.
+func (d *DirectorioUsuarios) Usuarios() []string {
+	return d.usuarios.claves()
+}
+
+// Los errores que devuelve AlmacenSesiones.Usuario para un token que no sirve.
+var (
+	ErrTokenInvalido = errors.New("el token de autenticación no corresponde a ninguna sesión")
+	ErrSesionVencida = errors.New("la sesión venció")
+)
+
+// Devuelve el hash con el que se guarda un token: el almacén de sesiones nunca guarda los
+// tokens mismos.
+func hashToken(token string) string {
+	suma := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(suma[:])
+}
+
+// Una sesión abierta: su identificador público, el hash de su token, cuándo empezó y
+// hasta cuándo es válida, y cuándo la usó el cliente por última vez.
+type sesion struct {
+	id        string
+	hash      string
+	inicio    time.Time
+	vence     time.Time
+	actividad time.Time
+}
+
+func (s *sesion) vigente(ahora time.Time) bool {
+	return ahora.Before(s.vence)
+}
+
+type fragmentoSesiones struct {
+	sync.RWMutex
+	// las sesiones de cada usuario, indexadas por nombre de usuario y por identificador
+	sesiones map[string]map[string]*sesion
+}
+
+// A qué sesión pertenece un token.
+type claveSesion struct {
+	usuario string
+	id      string
+}
+
+type fragmentoTokens struct {
+	sync.RWMutex
+	// indexadas por el hash del token
+	claves map[string]claveSesion
+}
+
+// AlmacenSesiones guarda las sesiones abiertas de cada usuario, que puede tener varias a
+// la vez, por ejemplo una en cada dispositivo. De cada sesión guarda el hash de su token y
+// hasta cuándo vale: una sesión vencida no cuenta como abierta y su token se rechaza.
+// Es seguro para el uso concurrente desde varios manejadores de gRPC.
+type AlmacenSesiones struct {
+	sesiones [NUMERO_FRAGMENTOS]*fragmentoSesiones
+	tokens   [NUMERO_FRAGMENTOS]*fragmentoTokens
+}
+
+func NuevoAlmacenSesiones() *AlmacenSesiones {
+	a := &AlmacenSesiones{}
+	for i := range a.sesiones {
+		a.sesiones[i] = &fragmentoSesiones{sesiones: make(map[string]map[string]*sesion)}
+		a.tokens[i] = &fragmentoTokens{claves: make(map[string]claveSesion)}
+	}
+	return a
+}
+
+func (a *AlmacenSesiones) asignarToken(hash string, clave claveSesion) {
+	f := a.tokens[indiceFragmento(hash)]
+	f.Lock()
+	f.claves[hash] = clave
+	f.Unlock()
+}
+
+func (a *AlmacenSesiones) eliminarToken(hash string) {
+	f := a.tokens[indiceFragmento(hash)]
+	f.Lock()
+	delete(f.claves, hash)
+	f.Unlock()
+}
+
+func (a *AlmacenSesiones) claveDeToken(token string) (string, claveSesion, bool) {
+	hash := hashToken(token)
+	f := a.tokens[indiceFragmento(hash)]
+	f.RLock()
+	defer f.RUnlock()
+	clave, ok := f.claves[hash]
+	return hash, clave, ok
+}
+
+// Registra una nueva sesión del usuario con el identificador y el token dados, válida
+// hasta `vence`. Las sesiones vencidas del usuario se descartan.
+func (a *AlmacenSesiones) Abrir(usuario string, id string, token string, vence time.Time) {
+	a.abrir(usuario, id, token, vence, false)
+}
+
+// Como `Abrir`, pero solo si el usuario no tiene ninguna sesión vigente; devuelve si la
+// abrió.
+func (a *AlmacenSesiones) AbrirPrimera(usuario string, id string, token string, vence time.Time) bool {
+	return a.abrir(usuario, id, token, vence, true)
+}
+
+func (a *AlmacenSesiones) abrir(usuario string, id string, token string, vence time.Time, primera bool) bool {
+	// el candado del fragmento del usuario se mantiene mientras se actualiza el índice
+	// de tokens; siempre se toma en ese orden, por lo que no hay riesgo de interbloqueo
+	f := a.sesiones[indiceFragmento(usuario)]
+	f.Lock()
+	defer f.Unlock()
+
+	ahora := time.Now()
+	sesiones, ok := f.sesiones[usuario]
+	if !ok {
+		sesiones = make(map[string]*sesion)
+		f.sesiones[usuario] = sesiones
+	}
+	for idAnterior, anterior := range sesiones {
+		if !anterior.vigente(ahora) {
+			delete(sesiones, idAnterior)
+			a.eliminarToken(anterior.hash)
+		} else if primera {
+			return false
+		}
+	}
+	hash := hashToken(token)
+	sesiones[id] = &sesion{id: id, hash: hash, inicio: ahora, vence: vence, actividad: ahora}
+	a.asignarToken(hash, claveSesion{usuario: usuario, id: id})
+	return true
+}
+
+// Devuelve la sesión a la que pertenece el token, si sigue vigente. Debe llamarse con el
+// candado del fragmento del usuario tomado.
+func (a *AlmacenSesiones) buscar(f *fragmentoSesiones, hash string, clave claveSesion) (*sesion, error) {
+	actual, ok := f.sesiones[clave.usuario][clave.id]
+	if !ok || actual.hash != hash {
+		return nil, ErrTokenInvalido
+	}
+	if !actual.vigente(time.Now()) {
+		return nil, ErrSesionVencida
+	}
+	return actual, nil
+}
+
+// Extiende hasta `vence` la sesión a la que pertenece el token, si sigue vigente.
+func (a *AlmacenSesiones) Renovar(token string, vence time.Time) error {
+	hash, clave, ok := a.claveDeToken(token)
+	if !ok {
+		return ErrTokenInvalido
+	}
+
+	f := a.sesiones[indiceFragmento(clave.usuario)]
+	f.Lock()
+	defer f.Unlock()
+	actual, err := a.buscar(f, hash, clave)
+	if err != nil {
+		return err
+	}
+	actual.vence = vence
+	return nil
+}
+
+// Reemplaza el token de una sesión vigente del usuario por `tokenNuevo`, válido hasta
+// `vence`, y devuelve el identificador de la sesión. El token anterior deja de valer.
+// Devuelve ErrTokenInvalido si el token no es de una sesión del usuario.
+func (a *AlmacenSesiones) Reanudar(usuario string, token string, tokenNuevo string, vence time.Time) (string, error) {
+	hash, clave, ok := a.claveDeToken(token)
+	if !ok || clave.usuario != usuario {
+		return "", ErrTokenInvalido
+	}
+
+	f := a.sesiones[indiceFragmento(usuario)]
+	f.Lock()
+	defer f.Unlock()
+	actual, err := a.buscar(f, hash, clave)
+	if err != nil {
+		return "", err
+	}
+	a.eliminarToken(hash)
+	actual.hash, actual.vence, actual.actividad = hashToken(tokenNuevo), vence, time.Now()
+	a.asignarToken(actual.hash, clave)
+	return clave.id, nil
+}
+
+// Cierra todas las sesiones del usuario salvo la del identificador dado, invalidando sus
+// tokens, y devuelve los identificadores de las que cerró.
+func (a *AlmacenSesiones) CerrarOtras(usuario string, id string) []string {
+	f := a.sesiones[indiceFragmento(usuario)]
+	f.Lock()
+	defer f.Unlock()
+
+	cerradas := []string{}
+	for idOtra, otra := range f.sesiones[usuario] {
+		if idOtra != id {
+			delete(f.sesiones[usuario], idOtra)
+			a.eliminarToken(otra.hash)
+			cerradas = append(cerradas, idOtra)
+		}
+	}
+	if len(f.sesiones[usuario]) == 0 {
+		delete(f.sesiones, usuario)
+	}
+	return cerradas
+}
+
+// Registra que el cliente acaba de usar la sesión del usuario con el identificador dado
+// y devuelve cuándo vence. Devuelve ErrTokenInvalido si el usuario no tiene esa sesión y
+// ErrSesionVencida si venció.
+func (a *AlmacenSesiones) Actividad(usuario string, id string) (time.Time, error) {
+	f := a.sesiones[indiceFragmento(usuario)]
+	f.Lock()
+	defer f.Unlock()
+	actual, ok := f.sesiones[usuario][id]
+	if !ok {
+		return time.Time{}, ErrTokenInvalido
+	}
+	ahora := time.Now()
+	if !actual.vigente(ahora) {
+		return time.Time{}, ErrSesionVencida
+	}
+	actual.actividad = ahora
+	return actual.vence, nil
+}
+
+// Cierra las sesiones vencidas y las que no se usan desde antes de `limite`, invalidando
+// sus tokens, y devuelve a qué usuario y sesión correspondían.
+func (a *AlmacenSesiones) cerrarInactivas(limite time.Time) []claveSesion {
+	ahora := time.Now()
+	cerradas := []claveSesion{}
+	for _, f := range a.sesiones {
+		f.Lock()
+		for usuario, sesiones := range f.sesiones {
+			for id, actual := range sesiones {
+				if actual.vigente(ahora) && !actual.actividad.Before(limite) {
+					continue
+				}
+				delete(sesiones, id)
+				a.eliminarToken(actual.hash)
+				cerradas = append(cerradas, claveSesion{usuario: usuario, id: id})
+			}
+			if len(sesiones) == 0 {
+				delete(f.sesiones, usuario)
+			}
+		}
+		f.Unlock()
+	}
+	return cerradas
+}
+
+// Elimina la sesión del usuario con el identificador dado e invalida su token. Devuelve
+// false si el usuario no tenía esa sesión.
+func (a *AlmacenSesiones) Cerrar(usuario string, id string) bool {
+	f := a.sesiones[indiceFragmento(usuario)]
+	f.Lock()
+	defer f.Unlock()
+
+	actual, ok := f.sesiones[usuario][id]
+	if !ok {
+		return false
+	}
+	delete(f.sesiones[usuario], id)
+	if len(f.sesiones[usuario]) == 0 {
+		delete(f.sesiones, usuario)
+	}
+	a.eliminarToken(actual.hash)
+	return true
+}
+
+// Devuelve el usuario y el identificador de la sesión a la que pertenece el token, o
+// ErrSesionVencida si la sesión venció.
+func (a *AlmacenSesiones) Usuario(token string) (string, string, error) {
+	hash, clave, ok := a.claveDeToken(token)
+	if !ok {
+		return "", "", ErrTokenInvalido
+	}
+
+	f := a.sesiones[indiceFragmento(clave.usuario)]
+	f.RLock()
+	defer f.RUnlock()
+	if _, err := a.buscar(f, hash, clave); err != nil {
+		return "", "", err
+	}
+	return clave.usuario, clave.id, nil
+}
+
+// Devuelve las sesiones vigentes del usuario.
+func (a *AlmacenSesiones) Sesiones(usuario string) []*Sesion {
+	f := a.sesiones[indiceFragmento(usuario)]
+	f.RLock()
+	defer f.RUnlock()
+	ahora := time.Now()
+	sesiones := []*Sesion{}
+	for _, actual := range f.sesiones[usuario] {
+		if actual.vigente(ahora) {
+			sesiones = append(sesiones, &Sesion{
+				Id:     actual.id,
+				Inicio: timestamppb.New(actual.inicio),
+				Vence:  timestamppb.New(actual.vence),
+			})
+		}
+	}
+	return sesiones
+}
+
+// Devuelve los usuarios con al menos una sesión vigente al momento de la llamada.
+func (a *AlmacenSesiones) Usuarios() []string {
+	ahora := time.Now()
+	usuarios := []string{}
+	for _, f := range a.sesiones {
+		f.RLock()
+		for usuario, sesiones := range f.sesiones {
+			for _, actual := range sesiones {
+				if actual.vigente(ahora) {
+					usuarios = append(usuarios, usuario)
+					break
+				}
+			}
+		}
+		f.RUnlock()
+	}
+	return usuarios
+}
+
+// Devuelve la cantidad de sesiones vigentes, de todos los usuarios.
+func (a *AlmacenSesiones) Largo() int {
+	ahora := time.Now()
+	largo := 0
+	for _, f := range a.sesiones {
+		f.RLock()
+		for _, sesiones := range f.sesiones {
+			for _, actual := range sesiones {
+				if actual.vigente(ahora) {
+					largo++
+				}
+			}
+		}
+		f.RUnlock()
+	}
+	return largo
+}
+
+// El candado de la bandeja de un usuario, junto con el estado que protege.
+type candadoBandeja struct {
+	sync.Mutex
+	// el número de secuencia del último mensaje aceptado para el usuario
+	ultimaSecuencia uint64
+	// los mensajes entregados que el usuario todavía no confirmó, en el orden en que se
+	// entregaron
+	reservas []reserva
+}
+
+type fragmentoCandados struct {
+	sync.Mutex
+	candados map[string]*candadoBandeja
+}
+
+// Un candado por usuario, creado la primera vez que se pide. Sirve para serializar
+// operaciones compuestas sobre la bandeja de un mismo usuario sin frenar a los demás.
+type candadosPorUsuario [NUMERO_FRAGMENTOS]*fragmentoCandados
+
+func nuevosCandadosPorUsuario() *candadosPorUsuario {
+	var c candadosPorUsuario
+	for i := range c {
+		c[i] = &fragmentoCandados{candados: make(map[string]*candadoBandeja)}
+	}
+	return &c
+}
+
+func (c *candadosPorUsuario) candado(usuario string) *candadoBandeja {
+	f := c[indiceFragmento(usuario)]
+	f.Lock()
+	defer f.Unlock()
+	candado, ok := f.candados[usuario]
+	if !ok {
+		candado = &candadoBandeja{}
+		f.candados[usuario] = candado
+	}
+	return candado
+}
+
+type fragmentoAvisos struct {
+	sync.Mutex
+	canales map[string]chan struct{}
+}
+
+// Permite esperar a que llegue un mensaje nuevo para un usuario sin consultar su
+// bandeja continuamente.
+type avisosPorUsuario [NUMERO_FRAGMENTOS]*fragmentoAvisos
+
+func nuevosAvisosPorUsuario() *avisosPorUsuario {
+	var a avisosPorUsuario
+	for i := range a {
+		a[i] = &fragmentoAvisos{canales: make(map[string]chan struct{})}
+	}
+	return &a
+}
+
+// Devuelve un canal que se cierra la próxima vez que se avise al usuario. Para no
+// perder avisos debe pedirse antes de revisar la bandeja.
+func (a *avisosPorUsuario) esperar(usuario string) <-chan struct{} {
+	f := a[indiceFragmento(usuario)]
+	f.Lock()
+	defer f.Unlock()
+	canal, ok := f.canales[usuario]
+	if !ok {
+		canal = make(chan struct{})
+		f.canales[usuario] = canal
+	}
+	return canal
+}
+
+// Despierta a todos los que esperan mensajes del usuario.
+func (a *avisosPorUsuario) avisar(usuario string) {
+	f := a[indiceFragmento(usuario)]
+	f.Lock()
+	defer f.Unlock()
+	if canal, ok := f.canales[usuario]; ok {
+		close(canal)
+		delete(f.canales, usuario)
+	}
+}
@@ -0,0 +1,154 @@
+package pkg
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAbrirSesionConcurrente(t *testing.T) {
+	almacen := NuevoAlmacenSesiones()
+
+	var grupo sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		grupo.Add(1)
+		go func(i int) {
+			defer grupo.Done()
+			almacen.Abrir("usuario", fmt.Sprintf("sesion%d", i), fmt.Sprintf("token%d", i), time.Now().Add(time.Hour))
+		}(i)
+	}
+	grupo.Wait()
+
+	// cada conexión abre su propia sesión, sin pisar a las demás
+	if almacen.Largo() != 100 {
+		t.Errorf("Se esperaban 100 sesiones en el almacén, se encontraron %d", almacen.Largo())
+	}
+	if usuarios := almacen.Usuarios(); len(usuarios) != 1 {
+		t.Errorf("Se esperaba un único usuario conectado, se obtuvo %v", usuarios)
+	}
+	for i := 0; i < 100; i++ {
+		usuario, id, err := almacen.Usuario(fmt.Sprintf("token%d", i))
+		if usuario != "usuario" || id != fmt.Sprintf("sesion%d", i) || err != nil {
+			t.Errorf("El token%d debería corresponder a la sesion%d, se obtuvo %q, %q y %v", i, i, usuario, id, err)
+		}
+	}
+
+	// cerrar una sesión solo invalida su token
+	if !almacen.Cerrar("usuario", "sesion0") {
+		t.Fatalf("Se esperaba poder cerrar la sesion0")
+	}
+	if almacen.Cerrar("usuario", "sesion0") {
+		t.Errorf("La sesion0 no debería poder cerrarse dos veces")
+	}
+	if _, _, err := almacen.Usuario("token0"); err != ErrTokenInvalido {
+		t.Errorf("El token0 no debería seguir siendo válido, se obtuvo %v", err)
+	}
+	if _, _, err := almacen.Usuario("token1"); err != nil {
+		t.Errorf("El token1 debería seguir siendo válido, se obtuvo %v", err)
+	}
+	for i := 1; i < 100; i++ {
+		almacen.Cerrar("usuario", fmt.Sprintf("sesion%d", i))
+	}
+	if almacen.Largo() != 0 || len(almacen.Usuarios()) != 0 {
+		t.Errorf("Se esperaba el almacén vacío luego de cerrar las sesiones, se encontraron %d", almacen.Largo())
+	}
+}
+
+// Una sesión vencida rechaza su token, no cuenta como conectada y se descarta al abrir
+// otra; una vigente puede renovarse.
+func TestSesionVencida(t *testing.T) {
+	almacen := NuevoAlmacenSesiones()
+	almacen.Abrir("ana", "vieja", "vencido", time.Now().Add(-time.Second))
+
+	if _, _, err := almacen.Usuario("vencido"); err != ErrSesionVencida {
+		t.Errorf("Se esperaba ErrSesionVencida, se obtuvo %v", err)
+	}
+	if err := almacen.Renovar("vencido", time.Now().Add(time.Hour)); err != ErrSesionVencida {
+		t.Errorf("Se esperaba que no se pudiera renovar una sesión vencida, se obtuvo %v", err)
+	}
+	if almacen.Largo() != 0 || len(almacen.Sesiones("ana")) != 0 {
+		t.Errorf("Se esperaba que la sesión vencida no contara, se encontraron %d", almacen.Largo())
+	}
+
+	almacen.Abrir("ana", "nueva", "nuevo", time.Now().Add(50*time.Millisecond))
+	if _, _, err := almacen.Usuario("vencido"); err != ErrTokenInvalido {
+		t.Errorf("Se esperaba que el token de la sesión descartada fuera inválido, se obtuvo %v", err)
+	}
+	if err := almacen.Renovar("nuevo", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("No se pudo renovar la sesión: %s", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if usuario, id, err := almacen.Usuario("nuevo"); usuario != "ana" || id != "nueva" || err != nil {
+		t.Errorf("Se esperaba que la sesión renovada siguiera vigente, se obtuvo %q, %q y %v", usuario, id, err)
+	}
+
+	// el almacén solo guarda el hash de los tokens
+	if _, clave, ok := almacen.claveDeToken("nuevo"); !ok || clave.id != "nueva" {
+		t.Errorf("Se esperaba encontrar la sesión por el hash del token")
+	}
+	for _, f := range almacen.tokens {
+		if _, ok := f.claves["nuevo"]; ok {
+			t.Errorf("El almacén no debería guardar el token mismo")
+		}
+	}
+}
+
+// Reanudar una sesión cambia su token pero conserva su identificador, y solo puede
+// hacerlo su dueño; CerrarOtras deja abierta únicamente la sesión indicada.
+func TestReanudarSesion(t *testing.T) {
+	almacen := NuevoAlmacenSesiones()
+	almacen.Abrir("ana", "a", "tokenA", time.Now().Add(time.Hour))
+	almacen.Abrir("ana", "b", "tokenB", time.Now().Add(time.Hour))
+
+	if _, err := almacen.Reanudar("beto", "tokenA", "robado", time.Now().Add(time.Hour)); err != ErrTokenInvalido {
+		t.Errorf("Se esperaba que otro usuario no pudiera reanudar la sesión, se obtuvo %v", err)
+	}
+	id, err := almacen.Reanudar("ana", "tokenA", "tokenA2", time.Now().Add(time.Hour))
+	if id != "a" || err != nil {
+		t.Fatalf("Se esperaba reanudar la sesión a, se obtuvo %q y %v", id, err)
+	}
+	if _, _, err := almacen.Usuario("tokenA"); err != ErrTokenInvalido {
+		t.Errorf("El token anterior no debería seguir siendo válido, se obtuvo %v", err)
+	}
+	if usuario, id, err := almacen.Usuario("tokenA2"); usuario != "ana" || id != "a" || err != nil {
+		t.Errorf("Se esperaba que el token nuevo fuera de la sesión a, se obtuvo %q, %q y %v", usuario, id, err)
+	}
+
+	if cerradas := almacen.CerrarOtras("ana", "a"); len(cerradas) != 1 || cerradas[0] != "b" {
+		t.Errorf("Se esperaba cerrar solo la sesión b, se cerraron %v", cerradas)
+	}
+	if _, _, err := almacen.Usuario("tokenB"); err != ErrTokenInvalido {
+		t.Errorf("El token de la sesión cerrada no debería seguir siendo válido, se obtuvo %v", err)
+	}
+	if almacen.Largo() != 1 {
+		t.Errorf("Se esperaba una sola sesión abierta, se encontraron %d", almacen.Largo())
+	}
+}
+
+// cerrarInactivas cierra las sesiones sin actividad reciente y las vencidas, y conserva
+// las que se siguen usando.
+func TestCerrarInactivas(t *testing.T) {
+	almacen := NuevoAlmacenSesiones()
+	almacen.Abrir("ana", "inactiva", "tokenInactiva", time.Now().Add(time.Hour))
+	almacen.Abrir("carla", "vencida", "tokenVencida", time.Now().Add(-time.Second))
+	time.Sleep(10 * time.Millisecond)
+	limite := time.Now()
+	almacen.Abrir("ana", "nueva", "tokenNueva", time.Now().Add(time.Hour))
+	almacen.Abrir("beto", "usada", "tokenUsada", time.Now().Add(time.Hour))
+	almacen.Actividad("beto", "usada")
+
+	cerradas := map[string]bool{}
+	for _, clave := range almacen.cerrarInactivas(limite) {
+		cerradas[clave.usuario+"/"+clave.id] = true
+	}
+	if len(cerradas) != 2 || !cerradas["ana/inactiva"] || !cerradas["carla/vencida"] {
+		t.Errorf("Se esperaba cerrar la sesión inactiva de ana y la vencida de carla, se cerraron %v", cerradas)
+	}
+	if _, _, err := almacen.Usuario("tokenInactiva"); err != ErrTokenInvalido {
+		t.Errorf("El token de la sesión inactiva no debería seguir siendo válido, se obtuvo %v", err)
+	}
+	if almacen.Largo() != 2 {
+		t.Errorf("Se esperaban 2 sesiones abiertas, se encontraron %d", almacen.Largo())
+	}
+}
@@ -0,0 +1,136 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// El error que devuelve BandejaEntrada.Depositar cuando la bandeja no tiene lugar.
+var ErrBandejaLlena = errors.New("la bandeja de entrada está llena")
+
+// BandejaEntrada es la cola de mensajes pendientes de un usuario. Las implementaciones
+// deben ser seguras para el uso concurrente: varios remitentes pueden depositar a la vez
+// mientras el dueño de la bandeja retira sus mensajes.
+type BandejaEntrada interface {
+	// Agrega un mensaje al final de la bandeja. No debe bloquear esperando lugar: si la
+	// bandeja está llena devuelve ErrBandejaLlena y el servidor aplica la política de
+	// desborde del destinatario. Una bandeja sin límite de tamaño nunca lo devuelve.
+	Depositar(ctx context.Context, msg *MensajeApp) error
+	// Consume y devuelve, en orden de llegada, hasta `maximo` mensajes sin bloquear.
+	Retirar(maximo int) ([]*MensajeApp, error)
+	// Vuelve a poner al principio de la bandeja, en el mismo orden, mensajes retirados que
+	// no se pudieron entregar. Los mensajes devueltos no respetan la capacidad: ya habían
+	// sido aceptados.
+	Devolver(mensajes []*MensajeApp) error
+	// Devuelve la cantidad de mensajes pendientes.
+	Largo() int
+}
+
+// AlmacenBandejas guarda las bandejas de entrada de todos los usuarios. Permite
+// reemplazar las bandejas en memoria por otras persistentes, sin límite de tamaño o
+// instrumentadas sin modificar el servidor; ver `ConAlmacenBandejas`.
+type AlmacenBandejas interface {
+	// Crea una bandeja vacía para el usuario, reemplazando la anterior si existía.
+	Crear(usuario string) (BandejaEntrada, error)
+	// Devuelve la bandeja del usuario, si existe.
+	Bandeja(usuario string) (BandejaEntrada, bool)
+}
+
+// AlmacenConCapacidad es un AlmacenBandejas cuyas bandejas tienen todas la misma
+// capacidad. Las métricas del servidor la usan para mostrar cuánto les falta a las
+// bandejas para llenarse; los almacenes sin límite no necesitan implementarlo.
+type AlmacenConCapacidad interface {
+	AlmacenBandejas
+	// Devuelve cuántos mensajes entran en cada bandeja.
+	Capacidad() int
+}
+
+// Una bandeja de entrada en memoria con capacidad fija.
+type bandejaMemoria struct {
+	candado   sync.Mutex
+	capacidad int
+	mensajes  []*MensajeApp
+}
+
+func (b *bandejaMemoria) Depositar(_ context.Context, msg *MensajeApp) error {
+	b.candado.Lock()
+	defer b.candado.Unlock()
+	if len(b.mensajes) >= b.capacidad {
+		return ErrBandejaLlena
+	}
+	b.mensajes = append(b.mensajes, msg)
+	return nil
+}
+
+func (b *bandejaMemoria) Retirar(maximo int) ([]*MensajeApp, error) {
+	b.candado.Lock()
+	defer b.candado.Unlock()
+	if maximo > len(b.mensajes) {
+		maximo = len(b.mensajes)
+	}
+	mensajes := make([]*MensajeApp, maximo)
+	copy(mensajes, b.mensajes)
+	// se limpian las posiciones retiradas para que el arreglo no retenga los mensajes
+	for i := range b.mensajes[:maximo] {
+		b.mensajes[i] = nil
+	}
+	b.mensajes = b.mensajes[maximo:]
+	return mensajes, nil
+}
+
+func (b *bandejaMemoria) Devolver(mensajes []*MensajeApp) error {
+	b.candado.Lock()
+	defer b.candado.Unlock()
+	b.mensajes = append(append([]*MensajeApp(nil), mensajes...), b.mensajes...)
+	return nil
+}
+
+func (b *bandejaMemoria) Largo() int {
+	b.candado.Lock()
+	defer b.candado.Unlock()
+	return len(b.mensajes)
+}
+
+type fragmentoBandejas struct {
+	sync.RWMutex
+	bandejas map[string]BandejaEntrada
+}
+
+// El almacén predeterminado: bandejas en memoria, repartidas en fragmentos con su
+// propio candado.
+type almacenBandejasMemoria struct {
+	capacidad  int
+	fragmentos [NUMERO_FRAGMENTOS]*fragmentoBandejas
+}
+
+// Devuelve un almacén que guarda las bandejas en memoria con la capacidad indicada. Es
+// el almacén que usa `NuevoServidor` si no se indica otro, con capacidad LARGO_BUZON.
+func NuevoAlmacenBandejasMemoria(capacidad int) AlmacenBandejas {
+	a := &almacenBandejasMemoria{capacidad: capacidad}
+	for i := range a.fragmentos {
+		a.fragmentos[i] = &fragmentoBandejas{bandejas: make(map[string]BandejaEntrada)}
+	}
+	return a
+}
+
+func (a *almacenBandejasMemoria) Crear(usuario string) (BandejaEntrada, error) {
+	bandeja := &bandejaMemoria{capacidad: a.capacidad}
+	f := a.fragmentos[indiceFragmento(usuario)]
+	f.Lock()
+	f.bandejas[usuario] = bandeja
+	f.Unlock()
+	return bandeja, nil
+}
+
+func (a *almacenBandejasMemoria) Capacidad() int {
+	return a.capacidad
+}
+
+func (a *almacenBandejasMemoria) Bandeja(usuario string) (BandejaEntrada, bool) {
+	f := a.fragmentos[indiceFragmento(usuario)]
+	f.RLock()
+	defer f.RUnlock()
+	bandeja, ok := f.bandejas[usuario]
+	return bandeja, ok
+}
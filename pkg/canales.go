@@ -0,0 +1,268 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+)
+
+// El error que devuelven las operaciones de DirectorioCanales sobre un canal que no existe.
+var ErrCanalInexistente = errors.New("el canal no existe")
+
+type fragmentoCanales struct {
+	sync.RWMutex
+	miembros map[string]map[string]bool
+}
+
+// DirectorioCanales guarda los canales del servidor con sus miembros. Es seguro para el
+// uso concurrente desde varios manejadores de gRPC.
+type DirectorioCanales struct {
+	fragmentos [NUMERO_FRAGMENTOS]*fragmentoCanales
+}
+
+func NuevoDirectorioCanales() *DirectorioCanales {
+	d := &DirectorioCanales{}
+	for i := range d.fragmentos {
+		d.fragmentos[i] = &fragmentoCanales{miembros: make(map[string]map[string]bool)}
+	}
+	return d
+}
+
+// Crea un canal sin miembros. Devuelve false si ya existía.
+func (d *DirectorioCanales) Crear(canal string) bool {
+	f := d.fragmentos[indiceFragmento(canal)]
+	f.Lock()
+	defer f.Unlock()
+	if _, ok := f.miembros[canal]; ok {
+		return false
+	}
+	f.miembros[canal] = make(map[string]bool)
+	return true
+}
+
+// Devuelve si el canal existe.
+func (d *DirectorioCanales) Existe(canal string) bool {
+	f := d.fragmentos[indiceFragmento(canal)]
+	f.RLock()
+	defer f.RUnlock()
+	_, ok := f.miembros[canal]
+	return ok
+}
+
+// Agrega al usuario como miembro del canal. Devuelve false si ya era miembro.
+func (d *DirectorioCanales) Unirse(canal string, usuario string) (bool, error) {
+	f := d.fragmentos[indiceFragmento(canal)]
+	f.Lock()
+	defer f.Unlock()
+	miembros, ok := f.miembros[canal]
+	if !ok {
+		return false, ErrCanalInexistente
+	}
+	if miembros[usuario] {
+		return false, nil
+	}
+	miembros[usuario] = true
+	return true, nil
+}
+
+// Quita al usuario de los miembros del canal. Devuelve false si no era miembro.
+func (d *DirectorioCanales) Abandonar(canal string, usuario string) (bool, error) {
+	f := d.fragmentos[indiceFragmento(canal)]
+	f.Lock()
+	defer f.Unlock()
+	miembros, ok := f.miembros[canal]
+	if !ok {
+		return false, ErrCanalInexistente
+	}
+	if !miembros[usuario] {
+		return false, nil
+	}
+	delete(miembros, usuario)
+	return true, nil
+}
+
+// Devuelve los miembros del canal.
+func (d *DirectorioCanales) Miembros(canal string) ([]string, error) {
+	f := d.fragmentos[indiceFragmento(canal)]
+	f.RLock()
+	defer f.RUnlock()
+	miembros, ok := f.miembros[canal]
+	if !ok {
+		return nil, ErrCanalInexistente
+	}
+	lista := []string{}
+	for usuario := range miembros {
+		lista = append(lista, usuario)
+	}
+	return lista, nil
+}
+
+// Devuelve si el usuario es miembro del canal.
+func (d *DirectorioCanales) EsMiembro(canal string, usuario string) bool {
+	f := d.fragmentos[indiceFragmento(canal)]
+	f.RLock()
+	defer f.RUnlock()
+	return f.miembros[canal][usuario]
+}
+
+// Devuelve los nombres de todos los canales.
+func (d *DirectorioCanales) Canales() []string {
+	canales := []string{}
+	for _, f := range d.fragmentos {
+		f.RLock()
+		for canal := range f.miembros {
+			canales = append(canales, canal)
+		}
+		f.RUnlock()
+	}
+	return canales
+}
+
+// Convierte los errores del directorio de canales en errores de gRPC.
+func errorCanal(canal string, err error) error {
+	if errors.Is(err, ErrCanalInexistente) {
+		return nuevoError(codes.NotFound, RAZON_CANAL_INEXISTENTE, map[string]string{"canal": canal}, "El canal %s no existe", canal)
+	}
+	return err
+}
+
+func validarCanal(canal *Canal) error {
+	if canal.Nombre == "" {
+		return nuevoError(codes.InvalidArgument, RAZON_ARGUMENTO_INVALIDO, nil, "el nombre del canal no puede estar vacío")
+	}
+	return nil
+}
+
+// Implementación de CrearCanal definido en el archivo `.proto`.
+// Como en UnirseCanal y AbandonarCanal, el cambio se registra en el diario antes de
+// hacerlo en `s.Canales`, para que uno que no llegó al diario no quede vivo en memoria y
+// desaparezca al reiniciar.
+func (s *Servidor) CrearCanal(ctx context.Context, canal *Canal) (*Correcto, error) {
+	usuario := ctx.Value("nombreUsuario").(string)
+	if err := validarCanal(canal); err != nil {
+		return nil, err
+	}
+
+	// el candado mantiene el diario en el mismo orden que el directorio de canales, y
+	// que el directorio no cambie entre que se consulta y se modifica
+	s.candadoCanales.Lock()
+	defer s.candadoCanales.Unlock()
+
+	if s.Canales.Existe(canal.Nombre) {
+		return nil, nuevoError(codes.AlreadyExists, RAZON_CANAL_EXISTENTE, map[string]string{"canal": canal.Nombre}, "El canal %s ya existe", canal.Nombre)
+	}
+	if s.diario != nil {
+		if err := s.diario.Canal(canal.Nombre); err != nil {
+			return nil, nuevoError(codes.Internal, RAZON_INTERNA, nil, "no se pudo registrar el canal: %s", err)
+		}
+	}
+	s.Canales.Crear(canal.Nombre)
+	if s.diario != nil {
+		// el canal ya está en el diario, así que queda creado aunque sin su creador
+		if err := s.diario.Union(usuario, canal.Nombre); err != nil {
+			return nil, nuevoError(codes.Internal, RAZON_INTERNA, nil, "no se pudo registrar la unión al canal: %s", err)
+		}
+	}
+	s.Canales.Unirse(canal.Nombre, usuario)
+	return &Correcto{Ok: true}, nil
+}
+
+// Implementación de UnirseCanal definido en el archivo `.proto`.
+func (s *Servidor) UnirseCanal(ctx context.Context, canal *Canal) (*Correcto, error) {
+	usuario := ctx.Value("nombreUsuario").(string)
+
+	s.candadoCanales.Lock()
+	defer s.candadoCanales.Unlock()
+
+	if !s.Canales.Existe(canal.Nombre) {
+		return nil, errorCanal(canal.Nombre, ErrCanalInexistente)
+	}
+	if s.Canales.EsMiembro(canal.Nombre, usuario) {
+		return &Correcto{Ok: true}, nil
+	}
+	if s.diario != nil {
+		if err := s.diario.Union(usuario, canal.Nombre); err != nil {
+			return nil, nuevoError(codes.Internal, RAZON_INTERNA, nil, "no se pudo registrar la unión al canal: %s", err)
+		}
+	}
+	if _, err := s.Canales.Unirse(canal.Nombre, usuario); err != nil {
+		return nil, errorCanal(canal.Nombre, err)
+	}
+	return &Correcto{Ok: true}, nil
+}
+
+// Implementación de AbandonarCanal definido en el archivo `.proto`.
+func (s *Servidor) AbandonarCanal(ctx context.Context, canal *Canal) (*Correcto, error) {
+	usuario := ctx.Value("nombreUsuario").(string)
+
+	s.candadoCanales.Lock()
+	defer s.candadoCanales.Unlock()
+
+	if !s.Canales.Existe(canal.Nombre) {
+		return nil, errorCanal(canal.Nombre, ErrCanalInexistente)
+	}
+	if !s.Canales.EsMiembro(canal.Nombre, usuario) {
+		return &Correcto{Ok: false}, nil
+	}
+	if s.diario != nil {
+		if err := s.diario.Abandono(usuario, canal.Nombre); err != nil {
+			return nil, nuevoError(codes.Internal, RAZON_INTERNA, nil, "no se pudo registrar el abandono del canal: %s", err)
+		}
+	}
+	if _, err := s.Canales.Abandonar(canal.Nombre, usuario); err != nil {
+		return nil, errorCanal(canal.Nombre, err)
+	}
+	return &Correcto{Ok: true}, nil
+}
+
+// Implementación de ListarCanales definido en el archivo `.proto`.
+func (s *Servidor) ListarCanales(ctx context.Context, _ *Vacio) (*ListaCanales, error) {
+	return &ListaCanales{Canales: s.Canales.Canales()}, nil
+}
+
+// Implementación de ListarMiembros definido en el archivo `.proto`.
+func (s *Servidor) ListarMiembros(ctx context.Context, canal *Canal) (*ListaUsuarios, error) {
+	miembros, err := s.Canales.Miembros(canal.Nombre)
+	if err != nil {
+		return nil, errorCanal(canal.Nombre, err)
+	}
+	return &ListaUsuarios{Usuarios: miembros}, nil
+}
+
+// Implementación de Publicar definido en el archivo `.proto`.
+// Cada miembro recibe su propia copia del mensaje, con el mismo identificador y la
+// misma fecha.
+func (s *Servidor) Publicar(ctx context.Context, msg *MensajeApp) (*RespuestaPublicar, error) {
+	usuario := ctx.Value("nombreUsuario").(string)
+	canal := msg.Canal
+	if err := validarCanal(&Canal{Nombre: canal}); err != nil {
+		return nil, err
+	}
+
+	miembros, err := s.Canales.Miembros(canal)
+	if err != nil {
+		return nil, errorCanal(canal, err)
+	}
+	if !s.Canales.EsMiembro(canal, usuario) {
+		return nil, nuevoError(codes.PermissionDenied, RAZON_SIN_PERMISO, map[string]string{"canal": canal}, "Solo los miembros de %s pueden publicar en él", canal)
+	}
+
+	if err := s.sellar(usuario, msg); err != nil {
+		return nil, err
+	}
+	msg.Canal = canal
+
+	return s.repartir(ctx, usuario, msg, miembros), nil
+}
+
+// Reconstruye los canales a partir del estado guardado en el diario.
+func (s *Servidor) restaurarCanales() {
+	for canal, miembros := range s.diario.Canales() {
+		s.Canales.Crear(canal)
+		for _, usuario := range miembros {
+			s.Canales.Unirse(canal, usuario)
+		}
+	}
+}
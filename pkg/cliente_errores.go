@@ -0,0 +1,99 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Las categorías de los errores del cliente. Los errores que devuelven `Ejecutar`,
+// `ConfigurarCliente` y `Conversacion` se comparan con ellas con errors.Is para decidir qué
+// hacer: reintentar más tarde si la bandeja del destinatario está llena o el servidor no
+// está disponible, volver a conectarse si la sesión no es válida, o terminar si el usuario
+// se desconectó. ErrBandejaLlena y ErrCanalInexistente son los mismos errores que usa el
+// servidor.
+var (
+	ErrNoAutenticado           = errors.New("la sesión no es válida: vuelva a conectarse")
+	ErrDestinatarioInexistente = errors.New("el destinatario no existe")
+	ErrServidorNoDisponible    = errors.New("el servidor no está disponible")
+	ErrDesconectado            = errors.New("la sesión se cerró")
+)
+
+// ErrorCliente es el error de una operación del cliente que falló. Conserva el error
+// original, por lo que status.Code y status.Convert siguen devolviendo el código y los
+// detalles que envió el servidor.
+type ErrorCliente struct {
+	// Qué intentaba hacer el cliente, como "enviar" u "obtener los mensajes"
+	Operacion string
+	// La categoría del error, una de las de arriba, o nil si no corresponde a ninguna
+	Tipo error
+	// El error que produjo la falla
+	Causa error
+}
+
+func (e *ErrorCliente) Error() string {
+	return fmt.Sprintf("error al %s: %s", e.Operacion, e.Causa)
+}
+
+// Permite comparar el error con su categoría usando errors.Is.
+func (e *ErrorCliente) Is(objetivo error) bool {
+	return e.Tipo != nil && objetivo == e.Tipo
+}
+
+func (e *ErrorCliente) Unwrap() error {
+	return e.Causa
+}
+
+// Devuelve el estado de gRPC del error original, para status.Code y status.Convert.
+func (e *ErrorCliente) GRPCStatus() *status.Status {
+	return status.Convert(e.Causa)
+}
+
+// Devuelve la categoría que corresponde a un error de gRPC según su código y, si hace
+// falta distinguir, la razón de su ErrorInfo.
+func categoriaError(err error) error {
+	switch status.Code(err) {
+	case codes.Unauthenticated:
+		return ErrNoAutenticado
+	case codes.Unavailable:
+		return ErrServidorNoDisponible
+	case codes.ResourceExhausted:
+		if RazonError(err) == RAZON_BANDEJA_LLENA {
+			return ErrBandejaLlena
+		}
+	case codes.NotFound:
+		switch RazonError(err) {
+		case RAZON_USUARIO_INEXISTENTE:
+			return ErrDestinatarioInexistente
+		case RAZON_CANAL_INEXISTENTE:
+			return ErrCanalInexistente
+		}
+	}
+	return nil
+}
+
+// Convierte el error de una operación en un ErrorCliente con su categoría. Los errores
+// que ya son del cliente, incluido ErrorDesconexion, no cambian.
+func errorCliente(operacion string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var errCliente *ErrorCliente
+	var desconexion *ErrorDesconexion
+	if errors.As(err, &errCliente) || errors.As(err, &desconexion) {
+		return err
+	}
+	return &ErrorCliente{Operacion: operacion, Tipo: categoriaError(err), Causa: err}
+}
+
+// Devuelve el error de un envío que el servidor descartó porque la bandeja del
+// destinatario estaba llena.
+func bandejaLlena(usuario string) error {
+	return &ErrorCliente{
+		Operacion: "enviar",
+		Tipo:      ErrBandejaLlena,
+		Causa:     fmt.Errorf("la bandeja de entrada de %s está llena y el mensaje se descartó", usuario),
+	}
+}
@@ -0,0 +1,189 @@
+package pkg
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Conversacion es una conversación abierta con la RPC Conversar: los mensajes que
+// llegan al usuario se reciben por el canal Mensajes en cuanto el servidor los entrega,
+// sin necesidad de llamar a Obtener. Cada mensaje se confirma al dejarlo en el canal.
+type Conversacion struct {
+	cliente MensajeroClient
+	ctx     context.Context
+	flujo   Mensajero_ConversarClient
+	// Los mensajes recibidos. Debe consumirse: si se llena, la conversación deja de leer
+	// del servidor, incluidos los resultados que espera Enviar. Se cierra cuando termina
+	// la conversación.
+	Mensajes <-chan *MensajeApp
+	mensajes chan *MensajeApp
+
+	// serializa los envíos y la cola de quienes esperan su resultado, que el servidor
+	// devuelve en el mismo orden
+	candado    sync.Mutex
+	pendientes []chan *ResultadoEnvio
+
+	terminada chan struct{}
+	err       error
+}
+
+// Abre una conversación con el servidor. `ctx` debe ser el contexto devuelto por
+// `Registrar`, que lleva el token de autenticación; cancelarlo termina la conversación.
+func AbrirConversacion(cliente MensajeroClient, ctx context.Context) (*Conversacion, error) {
+	flujo, err := cliente.Conversar(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	mensajes := make(chan *MensajeApp, LARGO_BUZON)
+	c := &Conversacion{
+		cliente:   cliente,
+		ctx:       ctx,
+		flujo:     flujo,
+		Mensajes:  mensajes,
+		mensajes:  mensajes,
+		terminada: make(chan struct{}),
+	}
+	go c.leer()
+	return c, nil
+}
+
+func (c *Conversacion) leer() {
+	defer close(c.terminada)
+	defer close(c.mensajes)
+	for {
+		evento, err := c.flujo.Recv()
+		if err != nil {
+			if err != io.EOF {
+				c.err = err
+			}
+			return
+		}
+
+		switch e := evento.Evento.(type) {
+		case *EventoConversacion_Mensaje:
+			c.mensajes <- e.Mensaje
+			confirmar(c.cliente, c.ctx, e.Mensaje)
+		case *EventoConversacion_Resultado:
+			c.candado.Lock()
+			if len(c.pendientes) > 0 {
+				c.pendientes[0] <- e.Resultado
+				c.pendientes = c.pendientes[1:]
+			}
+			c.candado.Unlock()
+		}
+	}
+}
+
+// Envía un mensaje al usuario indicado, espera el resultado del envío y devuelve el
+// identificador que el servidor asignó al mensaje.
+func (c *Conversacion) Enviar(usuario string, cuerpo string) (string, error) {
+	espera := make(chan *ResultadoEnvio, 1)
+
+	c.candado.Lock()
+	err := c.flujo.Send(&MensajeApp{Usuario: usuario, Cuerpo: cuerpo})
+	if err == nil {
+		c.pendientes = append(c.pendientes, espera)
+	}
+	c.candado.Unlock()
+	if err != nil {
+		return "", errorCliente("enviar", err)
+	}
+
+	select {
+	case resultado := <-espera:
+		if codigo := codes.Code(resultado.Codigo); codigo != codes.OK {
+			return "", errorCliente("enviar", nuevoError(codigo, resultado.Razon, nil, "%s", resultado.Error))
+		}
+		if !resultado.Ok {
+			return resultado.Id, bandejaLlena(usuario)
+		}
+		return resultado.Id, nil
+	case <-c.terminada:
+		if c.err == nil {
+			return "", &ErrorDesconexion{}
+		}
+		return "", &ErrorDesconexion{RazonesAdicionales: c.err.Error()}
+	}
+}
+
+// Termina la conversación y espera a que el servidor la cierre. Devuelve el error que
+// haya terminado la conversación antes, si lo hubo.
+func (c *Conversacion) Cerrar() error {
+	c.candado.Lock()
+	c.flujo.CloseSend()
+	c.candado.Unlock()
+	<-c.terminada
+	return c.err
+}
+
+// Confirma un mensaje recibido por un flujo. Si la confirmación falla el servidor vuelve
+// a entregar el mensaje cuando vence su plazo, por lo que el error se ignora.
+func confirmar(cliente MensajeroClient, ctx context.Context, msg *MensajeApp) {
+	cliente.Confirmar(ctx, &Confirmacion{Ids: []string{msg.Id}})
+}
+
+// Suscripcion es una suscripción abierta con la RPC Suscribir: los mensajes que llegan
+// al usuario se reciben por el canal Mensajes en cuanto el servidor los entrega. Cada
+// mensaje se confirma cuando se lee del canal.
+type Suscripcion struct {
+	// Los mensajes recibidos. No tiene capacidad, de modo que la suscripción solo lee del
+	// servidor cuando se consume; mientras tanto los mensajes esperan en la bandeja del
+	// usuario. Se cierra cuando termina la suscripción.
+	Mensajes <-chan *MensajeApp
+
+	cancelar  context.CancelFunc
+	terminada chan struct{}
+	err       error
+}
+
+// Abre una suscripción a la bandeja de entrada del usuario. `ctx` debe ser el contexto
+// devuelto por `Registrar`, que lleva el token de autenticación.
+func Suscribirse(cliente MensajeroClient, ctx context.Context) (*Suscripcion, error) {
+	// los mensajes se confirman con el contexto original, para que Cerrar no cancele la
+	// confirmación del último mensaje leído
+	ctxFlujo, cancelar := context.WithCancel(ctx)
+	flujo, err := cliente.Suscribir(ctxFlujo, &Vacio{})
+	if err != nil {
+		cancelar()
+		return nil, err
+	}
+
+	mensajes := make(chan *MensajeApp)
+	s := &Suscripcion{
+		Mensajes:  mensajes,
+		cancelar:  cancelar,
+		terminada: make(chan struct{}),
+	}
+	go func() {
+		defer close(s.terminada)
+		defer close(mensajes)
+		for {
+			msg, err := flujo.Recv()
+			if err != nil {
+				// la cancelación de Cerrar no es un error
+				if err != io.EOF && ctxFlujo.Err() == nil {
+					s.err = err
+				}
+				return
+			}
+			select {
+			case mensajes <- msg:
+				confirmar(cliente, ctx, msg)
+			case <-ctxFlujo.Done():
+				return
+			}
+		}
+	}()
+	return s, nil
+}
+
+// Termina la suscripción. Devuelve el error que la haya terminado antes, si lo hubo.
+func (s *Suscripcion) Cerrar() error {
+	s.cancelar()
+	<-s.terminada
+	return s.err
+}
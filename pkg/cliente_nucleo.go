@@ -3,6 +3,9 @@ package pkg
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -10,9 +13,39 @@ import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
+// El formato en que el cliente muestra la fecha de los mensajes, en la hora local.
+const FORMATO_FECHA = "2006-01-02 15:04:05"
+
+// Devuelve el mensaje como lo muestra el cliente: la fecha en que lo aceptó el servidor,
+// el canal si se publicó en uno o si es una difusión, el remitente y el cuerpo.
+func FormatearMensaje(mensaje *MensajeApp) string {
+	fecha := mensaje.Fecha.AsTime().Local().Format(FORMATO_FECHA)
+	if mensaje.Difusion {
+		return fmt.Sprintf("[%s] [difusión] [%s]: %s", fecha, mensaje.Usuario, mensaje.Cuerpo)
+	}
+	if mensaje.Canal != "" {
+		return fmt.Sprintf("[%s] [%s%s] [%s]: %s", fecha, PREFIJO_CANAL, mensaje.Canal, mensaje.Usuario, mensaje.Cuerpo)
+	}
+	return fmt.Sprintf("[%s] [%s]: %s", fecha, mensaje.Usuario, mensaje.Cuerpo)
+}
+
+// Devuelve la sesión como la muestra el cliente: su identificador, desde cuándo está
+// abierta y hasta cuándo vale, marcando la sesión desde la que se consulta.
+func FormatearSesion(sesion *Sesion) string {
+	inicio := sesion.Inicio.AsTime().Local().Format(FORMATO_FECHA)
+	vence := sesion.Vence.AsTime().Local().Format(FORMATO_FECHA)
+	if sesion.Actual {
+		return fmt.Sprintf("%s desde %s hasta %s (esta sesión)", sesion.Id, inicio, vence)
+	}
+	return fmt.Sprintf("%s desde %s hasta %s", sesion.Id, inicio, vence)
+}
+
 type ErrorDesconexion struct {
 	RazonesAdicionales string
 }
@@ -21,6 +54,11 @@ func (e *ErrorDesconexion) Error() string {
 	return fmt.Sprintf("El servidor se ha desconectado: errores, si los hay %s", e.RazonesAdicionales)
 }
 
+// Permite reconocer la desconexión con errors.Is(err, ErrDesconectado).
+func (e *ErrorDesconexion) Is(objetivo error) bool {
+	return objetivo == ErrDesconectado
+}
+
 /*
 Regístrese como nuevo usuario con el servidor activo.
 Obtenga el token de autenticación usando cliente.Conectar() y guárdelo en un objeto de `contexto`.
@@ -29,23 +67,199 @@ Se valida este objeto de contexto en el lado del servidor.
 TODO: Implementar `Registrar`. Debe llamar a la RPC `Conectar` y usar el paquete `metadata`
 apropiadamente para colocar el token de autenticación devuelto en un objeto context.Context.
 
+Registrar se conecta sin contraseña, como lo permiten los servidores que no usan cuentas;
+ver `RegistrarConContrasena`.
 */
 func Registrar(cliente MensajeroClient, usuario string) (context.Context, error) {
+	return RegistrarConContrasena(cliente, usuario, "")
+}
+
+// Como `Registrar`, con la contraseña de la cuenta del usuario.
+func RegistrarConContrasena(cliente MensajeroClient, usuario string, contrasena string) (context.Context, error) {
 	in := new(Registracion)
 	usuarioOrigen := in.ProtoReflect().Descriptor().Fields().ByName("usuarioOrigen")
 	in.ProtoReflect().Set(usuarioOrigen, protoreflect.ValueOfString(usuario))
+	in.Contrasena = contrasena
 
-	token, err := cliente.Conectar(context.Background(), in)
+	ctx, _, err := conectar(cliente, in)
+	return ctx, err
+
+}
+
+// Llama a Conectar y devuelve un contexto con el token obtenido, junto con el token.
+func conectar(cliente MensajeroClient, registracion *Registracion) (context.Context, *TokenAutenticacion, error) {
+	token, err := cliente.Conectar(context.Background(), registracion)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	ctx := metadata.NewOutgoingContext(context.Background(), metadata.Pairs("token", token.Token))
-	return ctx, nil
+	return ctx, token, nil
+}
+
+// Cuánto espera `MantenerSesion` para reintentar una renovación que falló.
+const REINTENTO_RENOVACION = 10 * time.Second
+
+// Mantiene en segundo plano la sesión del contexto devuelto por `Registrar`: la renueva
+// cada vez que pasa la mitad del plazo que le queda y, si el servidor cierra las sesiones
+// inactivas, le da señales de vida con Latido con la frecuencia que pide. Sigue hasta que
+// se llame a la función devuelta, la sesión termine, por ejemplo con "salir", o falle una
+// llamada porque se cerró la conexión. `ConfigurarCliente` la llama por su cuenta y la
+// detiene al cerrarse la conexión.
+func MantenerSesion(cliente MensajeroClient, ctx context.Context) (detener func()) {
+	ctx, cancelar := context.WithCancel(ctx)
+	go func() {
+		renovacion := time.Now()
+		var latido time.Duration
+		for {
+			espera := time.Until(renovacion)
+			if latido > 0 && latido < espera {
+				espera = latido
+			}
+			select {
+			case <-time.After(espera):
+			case <-ctx.Done():
+				return
+			}
+
+			var err error
+			if time.Now().Before(renovacion) {
+				_, err = cliente.Latido(ctx, &Vacio{})
+			} else {
+				var token *TokenAutenticacion
+				token, err = cliente.Renovar(ctx, &Vacio{})
+				if err == nil {
+					renovacion = time.Now().Add(time.Until(token.Vence.AsTime()) / 2)
+					latido = token.Latido.AsDuration()
+				} else {
+					renovacion = time.Now().Add(REINTENTO_RENOVACION)
+				}
+			}
+			// la sesión terminó o la conexión se cerró
+			if codigo := status.Code(err); codigo == codes.Unauthenticated || codigo == codes.Canceled {
+				return
+			}
+		}
+	}()
+	return cancelar
+}
+
+// Una opción de configuración para `ConfigurarCliente`.
+type OpcionCliente func(*configuracionCliente)
+
+type configuracionCliente struct {
+	contrasena    string
+	cuentaNueva   bool
+	archivoSesion string
+	cerrarOtras   bool
+	// la conexión usa TLS si se indicó alguna de las opciones de TLS
+	tls                bool
+	archivoAutoridades string
+	archivoCertificado string
+	archivoClave       string
+	registro           *Registro
+}
+
+// Indica la contraseña con la que se conecta el cliente, para los servidores que usan
+// cuentas.
+func ConContrasena(contrasena string) OpcionCliente {
+	return func(c *configuracionCliente) {
+		c.contrasena = contrasena
+	}
+}
+
+// Hace que el cliente cree la cuenta del usuario, con la contraseña de `ConContrasena`,
+// antes de conectarse.
+func ConCuentaNueva() OpcionCliente {
+	return func(c *configuracionCliente) {
+		c.cuentaNueva = true
+	}
+}
+
+// Hace que el cliente guarde el token de su sesión en el archivo indicado y que, al
+// conectarse, reanude la sesión guardada si sigue vigente. Así un cliente que terminó sin
+// desconectarse recupera su sesión en lugar de dejarla abierta hasta que venza. El
+// archivo solo puede leerlo su dueño, ya que el token da acceso a la sesión.
+func ConArchivoSesion(ruta string) OpcionCliente {
+	return func(c *configuracionCliente) {
+		c.archivoSesion = ruta
+	}
+}
+
+// Hace que el cliente cierre las demás sesiones del usuario al conectarse, por ejemplo
+// las que quedaron abiertas en un dispositivo que ya no se usa.
+func ConCerrarOtras() OpcionCliente {
+	return func(c *configuracionCliente) {
+		c.cerrarOtras = true
+	}
+}
+
+// Hace que el cliente se conecte con TLS y verifique el certificado del servidor con las
+// autoridades del archivo PEM indicado, o con las del sistema si está vacío.
+func ConTLS(archivoAutoridades string) OpcionCliente {
+	return func(c *configuracionCliente) {
+		c.tls = true
+		c.archivoAutoridades = archivoAutoridades
+	}
+}
+
+// Hace que el cliente se conecte con TLS mutuo, presentando el certificado y la clave
+// indicados. El servidor toma el nombre del usuario del certificado, por lo que el usuario
+// de `ConfigurarCliente` puede quedar vacío. Se combina con `ConTLS` para indicar las
+// autoridades del servidor.
+func ConCertificadoCliente(archivoCertificado string, archivoClave string) OpcionCliente {
+	return func(c *configuracionCliente) {
+		c.tls = true
+		c.archivoCertificado = archivoCertificado
+		c.archivoClave = archivoClave
+	}
+}
 
+// Indica el registro en el que el cliente anota los problemas que no le impiden
+// conectarse. Sin esta opción los escribe en la salida de errores con
+// OpcionesRegistroPredeterminadas.
+func ConRegistroCliente(registro *Registro) OpcionCliente {
+	return func(c *configuracionCliente) {
+		c.registro = registro
+	}
+}
+
+// Devuelve el token guardado en el archivo de sesión, o una cadena vacía si no hay uno.
+func leerSesionGuardada(ruta string) string {
+	if ruta == "" {
+		return ""
+	}
+	datos, err := os.ReadFile(ruta)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(datos))
+}
+
+// Guarda el token en el archivo de sesión, creando su directorio si hace falta.
+func guardarSesion(ruta string, token string) error {
+	if err := os.MkdirAll(filepath.Dir(ruta), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(ruta, []byte(token+"\n"), 0600)
 }
 
 // Una función auxiliar que devuelve una conexión de cliente activa con el servidor.
-func ConfigurarCliente(direccion string, usuario string, temporizador int) (*grpc.ClientConn, MensajeroClient, context.Context, error) {
+func ConfigurarCliente(direccion string, usuario string, temporizador int, opciones ...OpcionCliente) (*grpc.ClientConn, MensajeroClient, context.Context, error) {
+	configuracion := configuracionCliente{
+		registro: NuevoRegistro(os.Stderr, OpcionesRegistroPredeterminadas),
+	}
+	for _, opcion := range opciones {
+		opcion(&configuracion)
+	}
+
+	credenciales := insecure.NewCredentials()
+	if configuracion.tls {
+		var err error
+		credenciales, err = CredencialesCliente(configuracion.archivoAutoridades, configuracion.archivoCertificado, configuracion.archivoClave)
+		if err != nil {
+			return &grpc.ClientConn{}, nil, nil, err
+		}
+	}
 
 	// Establece una conexión con el servidor
 	temporizadorEnSegundos := time.Duration(temporizador) * time.Second
@@ -54,48 +268,227 @@ func ConfigurarCliente(direccion string, usuario string, temporizador int) (*grp
 	conexion, err := grpc.DialContext(
 		ctx,
 		direccion,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithTransportCredentials(credenciales),
 		grpc.WithBlock(),
 	)
 	if err != nil {
-		return &grpc.ClientConn{}, nil, nil, fmt.Errorf("no se puede conectar con el servidor: %s", err)
+		return &grpc.ClientConn{}, nil, nil, &ErrorCliente{Operacion: "conectar con el servidor", Tipo: ErrServidorNoDisponible, Causa: err}
 	}
 
 	cliente := NewMensajeroClient(conexion)
 
-	// registra el cliente como un nuevo usuario
-	ctx, err = Registrar(cliente, usuario)
+	if configuracion.cuentaNueva {
+		registracion := &Registracion{UsuarioOrigen: usuario, Contrasena: configuracion.contrasena}
+		if _, err := cliente.CrearCuenta(context.Background(), registracion); err != nil {
+			conexion.Close()
+			return &grpc.ClientConn{}, nil, nil, errorCliente("crear la cuenta", err)
+		}
+	}
+
+	// registra el cliente como un nuevo usuario, o reanuda su sesión anterior
+	registracion := &Registracion{
+		UsuarioOrigen: usuario,
+		Contrasena:    configuracion.contrasena,
+		TokenAnterior: leerSesionGuardada(configuracion.archivoSesion),
+		CerrarOtras:   configuracion.cerrarOtras,
+	}
+	ctx, token, err := conectar(cliente, registracion)
+	if status.Code(err) == codes.NotFound && registracion.TokenAnterior != "" {
+		// la sesión guardada ya no existe: se abre una nueva
+		registracion.TokenAnterior = ""
+		ctx, token, err = conectar(cliente, registracion)
+	}
 	if err != nil {
-		return &grpc.ClientConn{}, nil, nil, fmt.Errorf("no se puede registrar con el servidor: %s", err)
+		conexion.Close()
+		return &grpc.ClientConn{}, nil, nil, errorCliente("registrar con el servidor", err)
+	}
+	if configuracion.archivoSesion != "" {
+		if err := guardarSesion(configuracion.archivoSesion, token.Token); err != nil {
+			configuracion.registro.evento(NivelAdvertencia, "No se pudo guardar la sesión", [2]string{"archivo", configuracion.archivoSesion}, [2]string{"error", err.Error()})
+		}
 	}
+	// la sesión se mantiene mientras la conexión siga abierta
+	detener := MantenerSesion(cliente, ctx)
+	go detenerAlCerrar(conexion, detener)
 
 	return conexion, cliente, ctx, nil
 }
 
+// Espera a que se cierre la conexión y llama a `detener`, para que lo que la usa en
+// segundo plano, como `MantenerSesion`, no la sobreviva.
+func detenerAlCerrar(conexion *grpc.ClientConn, detener func()) {
+	for estado := conexion.GetState(); estado != connectivity.Shutdown; estado = conexion.GetState() {
+		conexion.WaitForStateChange(context.Background(), estado)
+	}
+	detener()
+}
+
+// Devuelve los mensajes obtenidos como los muestra `Ejecutar`, uno por línea, y confirma
+// al servidor que se recibieron.
+func mostrarYConfirmar(cliente MensajeroClient, ctx context.Context, mensajes *MensajesApp) (string, error) {
+	todos := []string{}
+	ids := []string{}
+	for _, mensaje := range mensajes.Mensajes {
+		todos = append(todos, FormatearMensaje(mensaje))
+		ids = append(ids, mensaje.Id)
+	}
+
+	// los mensajes que no se confirmen vuelven a entregarse cuando vence su plazo
+	if len(ids) > 0 {
+		if _, err := cliente.Confirmar(ctx, &Confirmacion{Ids: ids}); err != nil {
+			return "", errorCliente("confirmar los mensajes", err)
+		}
+	}
+
+	return fmt.Sprintf("%s\n", strings.Join(todos, "\n")), nil
+}
+
+// El prefijo con el que el cliente distingue los canales de los usuarios.
+const PREFIJO_CANAL = "#"
+
+// El prefijo de los comandos de `Ejecutar` que llevan un argumento, como "/unirse #sala".
+// Sin él, dos argumentos son un mensaje directo, salvo los comandos de `comandoSinPrefijo`.
+const PREFIJO_COMANDO = "/"
+
+func ejecutarCanal(cliente MensajeroClient, ctx context.Context, comando string, nombre string) (string, error) {
+	canal := &Canal{Nombre: nombre}
+	switch comando {
+	case "crear":
+		_, err := cliente.CrearCanal(ctx, canal)
+		return "", errorCliente("crear el canal", err)
+	case "unirse":
+		_, err := cliente.UnirseCanal(ctx, canal)
+		return "", errorCliente("unirse al canal", err)
+	case "abandonar":
+		correcto, err := cliente.AbandonarCanal(ctx, canal)
+		if err != nil {
+			return "", errorCliente("abandonar el canal", err)
+		}
+		if !correcto.Ok {
+			return "", fmt.Errorf("no es miembro de %s%s", PREFIJO_CANAL, nombre)
+		}
+		return "", nil
+	default:
+		miembros, err := cliente.ListarMiembros(ctx, canal)
+		if err != nil {
+			return "", errorCliente("listar los miembros del canal", err)
+		}
+		return fmt.Sprintf("%s\n", strings.Join(miembros.Usuarios, ",")), nil
+	}
+}
+
+// Devuelve si los argumentos de `Ejecutar` son un mensaje directo para un usuario y no
+// un comando ni una publicación en un canal.
+func EsMensajeDirecto(argumentos ...string) bool {
+	return len(argumentos) == 2 && !strings.HasPrefix(argumentos[0], PREFIJO_COMANDO) && !strings.HasPrefix(argumentos[0], PREFIJO_CANAL) && !comandoSinPrefijo(argumentos...)
+}
+
+// Devuelve si los argumentos son un comando con un argumento que también se acepta sin
+// PREFIJO_COMANDO: "obtener <cantidad>", siempre que la cantidad sea un número, e
+// "historial <usuario>", ya que así los aceptaba el cliente antes de que hubiera comandos
+// con prefijo.
+func comandoSinPrefijo(argumentos ...string) bool {
+	if len(argumentos) != 2 {
+		return false
+	}
+	switch argumentos[0] {
+	case "obtener":
+		_, err := strconv.Atoi(argumentos[1])
+		return err == nil
+	case "historial":
+		return true
+	}
+	return false
+}
+
+// Lleva a cabo un comando de `Ejecutar` con un argumento, sin el prefijo de los comandos.
+func ejecutarComando(cliente MensajeroClient, ctx context.Context, comando string, argumento string) (string, error) {
+	switch comando {
+
+	// "obtener <cantidad>" pide una cantidad de mensajes en lugar del lote del servidor
+	case "obtener":
+		largo, err := strconv.Atoi(argumento)
+		if err != nil {
+			return "", fmt.Errorf("la cantidad de mensajes debe ser un número, se recibió %q", argumento)
+		}
+		mensajes, err := cliente.ObtenerLimitado(ctx, &ObtenerConLimite{Largo: int32(largo)})
+		if err != nil {
+			return "", errorCliente("obtener los mensajes", err)
+		}
+		return mostrarYConfirmar(cliente, ctx, mensajes)
+
+	case "crear", "unirse", "abandonar", "miembros":
+		if !strings.HasPrefix(argumento, PREFIJO_CANAL) {
+			return "", fmt.Errorf("el nombre del canal debe empezar con %s, se recibió %q", PREFIJO_CANAL, argumento)
+		}
+		return ejecutarCanal(cliente, ctx, comando, strings.TrimPrefix(argumento, PREFIJO_CANAL))
+
+	// "/difundir <mensaje>" envía el mensaje a todos los usuarios conectados
+	case "difundir":
+		respuesta, err := cliente.Difundir(ctx, &MensajeApp{Cuerpo: argumento})
+		if err != nil {
+			return "", errorCliente("difundir", err)
+		}
+		if len(respuesta.NoEntregados) > 0 {
+			return "", fmt.Errorf("error al difundir: no se pudo entregar el mensaje a %s", strings.Join(respuesta.NoEntregados, ","))
+		}
+		return "", nil
+
+	// "historial <usuario>" muestra los últimos mensajes con el usuario, del más antiguo al
+	// más reciente
+	case "historial":
+		pagina, err := cliente.Historial(ctx, &ConsultaHistorial{Usuario: argumento})
+		if err != nil {
+			return "", errorCliente("consultar el historial", err)
+		}
+		todos := []string{}
+		for i := len(pagina.Entradas) - 1; i >= 0; i-- {
+			todos = append(todos, FormatearMensaje(pagina.Entradas[i].Mensaje))
+		}
+		return fmt.Sprintf("%s\n", strings.Join(todos, "\n")), nil
+
+	// "/revocar <sesión>" cierra una sesión del usuario, por ejemplo la de otro dispositivo
+	case "revocar":
+		correcto, err := cliente.RevocarSesion(ctx, &Sesion{Id: argumento})
+		if err != nil {
+			return "", errorCliente("revocar", err)
+		}
+		if !correcto.Ok {
+			return "", fmt.Errorf("error al revocar: no hay una sesión %s", argumento)
+		}
+		return "", nil
+	}
+	return "", fmt.Errorf("comando desconocido: %s%s", PREFIJO_COMANDO, comando)
+}
+
 // Una función auxiliar que lleva a cabo las acciones indicadas por los argumentos.
 // Los argumentos pueden ser un slice de cadena de uno o dos elementos.
-// Si contiene dos elementos, el cliente envía un mensaje al servidor:
+// Si contiene un elemento, es un comando como "obtener" o "listar", con o sin el
+// prefijo "/". Si contiene dos elementos, el cliente envía un mensaje al servidor:
 // el primer elemento se trata como el usuario al que se envía y
-// el segundo elemento es el mensaje completo que se envía.
+// el segundo elemento es el mensaje completo que se envía. Las excepciones son
+// "#<canal> <mensaje>", que publica el mensaje en el canal, y los comandos con un
+// argumento, que empiezan con "/": "/obtener <cantidad>", que obtiene hasta esa cantidad
+// de mensajes, los comandos sobre canales ("/crear", "/unirse", "/abandonar" y "/miembros"
+// seguidos de "#<canal>"), "/difundir <mensaje>", que lo envía a todos los usuarios
+// conectados, "/historial <usuario>", que muestra los últimos mensajes directos
+// intercambiados con el usuario, y "/revocar <sesión>", que cierra otra sesión del
+// usuario. "obtener <cantidad>" e "historial <usuario>" se aceptan también sin el prefijo.
 // Devuelve una cadena para mostrar al usuario los resultados de la operación.
 func Ejecutar(cliente MensajeroClient, ctx context.Context, argumentos ...string) (string, error) {
 
 	if len(argumentos) == 1 {
-		switch argumentos[0] {
+		// un comando de una palabra no se confunde con un mensaje directo, así que el
+		// prefijo es opcional
+		switch strings.TrimPrefix(argumentos[0], PREFIJO_COMANDO) {
 
 		case "obtener":
 
 			mensajes, err := cliente.Obtener(ctx, &Vacio{})
 			if err != nil {
-				return "", err
+				return "", errorCliente("obtener los mensajes", err)
 			}
-
-			todos := []string{}
-			for _, mensaje := range mensajes.Mensajes {
-				todos = append(todos, fmt.Sprintf("[%s]: %s", mensaje.Usuario, mensaje.Cuerpo))
-			}
-
-			return fmt.Sprintf("%s\n", strings.Join(todos, "\n")), nil
+			return mostrarYConfirmar(cliente, ctx, mensajes)
 
 		case "listar":
 			// TODO: ¡Implemente la llamada RPC del cliente para listar!
@@ -110,7 +503,7 @@ func Ejecutar(cliente MensajeroClient, ctx context.Context, argumentos ...string
 
 			usuarios, err := cliente.Listar(ctx, &Vacio{})
 			if err != nil {
-				return "", err
+				return "", errorCliente("listar los usuarios", err)
 			}
 
 			todos := []string{}
@@ -120,24 +513,81 @@ func Ejecutar(cliente MensajeroClient, ctx context.Context, argumentos ...string
 
 			return fmt.Sprintf("%s\n", strings.Join(todos, ",")), nil
 
+		case "canales":
+
+			canales, err := cliente.ListarCanales(ctx, &Vacio{})
+			if err != nil {
+				return "", errorCliente("listar los canales", err)
+			}
+
+			todos := []string{}
+			for _, canal := range canales.Canales {
+				todos = append(todos, PREFIJO_CANAL+canal)
+			}
+
+			return fmt.Sprintf("%s\n", strings.Join(todos, ",")), nil
+
+		case "sesiones":
+
+			sesiones, err := cliente.ListarSesiones(ctx, &Vacio{})
+			if err != nil {
+				return "", errorCliente("listar las sesiones", err)
+			}
+
+			todas := []string{}
+			for _, sesion := range sesiones.Sesiones {
+				todas = append(todas, FormatearSesion(sesion))
+			}
+
+			return fmt.Sprintf("%s\n", strings.Join(todas, "\n")), nil
+
 		case "salir":
 
 			correcto, err := cliente.Desconectar(ctx, &Vacio{})
-			if err != nil || !correcto.Ok {
+			if err != nil {
 				return "", &ErrorDesconexion{RazonesAdicionales: err.Error()}
 			}
+			if !correcto.Ok {
+				return "", &ErrorDesconexion{RazonesAdicionales: "el servidor no confirmó la desconexión"}
+			}
 			return "", &ErrorDesconexion{RazonesAdicionales: ""}
 		}
 	}
 
-	if len(argumentos) == 2 {
+	if len(argumentos) > 0 && strings.HasPrefix(argumentos[0], PREFIJO_COMANDO) {
+		if len(argumentos) != 2 {
+			return "", fmt.Errorf("comando desconocido: %s", argumentos[0])
+		}
+		return ejecutarComando(cliente, ctx, strings.TrimPrefix(argumentos[0], PREFIJO_COMANDO), argumentos[1])
+	}
+	if comandoSinPrefijo(argumentos...) {
+		return ejecutarComando(cliente, ctx, argumentos[0], argumentos[1])
+	}
+
+	// "#<canal> <mensaje>" publica el mensaje en el canal
+	if len(argumentos) == 2 && strings.HasPrefix(argumentos[0], PREFIJO_CANAL) {
+		canal := strings.TrimPrefix(argumentos[0], PREFIJO_CANAL)
+		respuesta, err := cliente.Publicar(ctx, &MensajeApp{Canal: canal, Cuerpo: argumentos[1]})
+		if err != nil {
+			return "", errorCliente("publicar", err)
+		}
+		if len(respuesta.NoEntregados) > 0 {
+			return "", fmt.Errorf("error al publicar: no se pudo entregar el mensaje a %s", strings.Join(respuesta.NoEntregados, ","))
+		}
+		return "", nil
+	}
+
+	if EsMensajeDirecto(argumentos...) {
 		exitoso, err := cliente.Enviar(ctx, &MensajeApp{
 			Usuario: argumentos[0],
 			Cuerpo:  argumentos[1],
 		})
 
-		if err != nil || !exitoso.Ok {
-			return "", fmt.Errorf("error al enviar: errores, si los hay: %s", err)
+		if err != nil {
+			return "", errorCliente("enviar", err)
+		}
+		if !exitoso.Ok {
+			return "", bandejaLlena(argumentos[0])
 		}
 	}
 
@@ -0,0 +1,32 @@
+package pkg
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Lo que ConfigurarCliente deja en segundo plano se detiene cuando se cierra la conexión,
+// y no antes.
+func TestDetenerAlCerrar(t *testing.T) {
+	conexion, err := grpc.Dial("localhost:1", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("No se pudo crear la conexión: %s", err)
+	}
+	detenido := make(chan struct{})
+	go detenerAlCerrar(conexion, func() { close(detenido) })
+
+	select {
+	case <-detenido:
+		t.Fatalf("Se esperaba que no se detuviera con la conexión abierta")
+	case <-time.After(50 * time.Millisecond):
+	}
+	conexion.Close()
+	select {
+	case <-detenido:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Se esperaba que se detuviera al cerrar la conexión")
+	}
+}
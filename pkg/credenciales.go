@@ -0,0 +1,275 @@
+package pkg
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+)
+
+// El error que devuelve AlmacenCredenciales.Crear si el usuario ya tiene una cuenta.
+var ErrCuentaExistente = errors.New("el usuario ya tiene una cuenta")
+
+// El largo en bytes de la sal y del hash de cada contraseña.
+const LARGO_SAL = 16
+const LARGO_HASH_SCRYPT = 32
+
+// La cantidad máxima de hashes de scrypt que AlmacenCredenciales calcula a la vez. Cada
+// uno ocupa 128·N·r bytes, 32 MiB con las opciones predeterminadas, y cualquiera puede
+// pedirlos sin estar autenticado; los que superan el límite esperan su turno.
+const CALCULOS_SIMULTANEOS = 4
+
+// La cantidad predeterminada de cuentas que se pueden crear desde una misma dirección en
+// cada INTERVALO_ALTAS. CrearCuenta no requiere autenticarse y cada cuenta ocupa uno de los
+// CALCULOS_SIMULTANEOS, por lo que sin este límite un solo cliente podría demorar los
+// inicios de sesión de todos.
+const ALTAS_POR_INTERVALO = 5
+const INTERVALO_ALTAS = time.Minute
+
+// Los parámetros de scrypt con los que AlmacenCredenciales protege las contraseñas
+// nuevas. Cada cuenta guarda los suyos, de modo que pueden cambiarse sin invalidar las
+// cuentas existentes.
+type OpcionesCredenciales struct {
+	// El parámetro N de scrypt: una potencia de 2 que fija el costo en tiempo y memoria.
+	Costo int
+	// Los parámetros r y p de scrypt.
+	Bloque      int
+	Paralelismo int
+}
+
+// Las opciones usadas si no se indica otra cosa, las recomendadas para contraseñas
+// interactivas.
+var OpcionesCredencialesPredeterminadas = OpcionesCredenciales{
+	Costo:       1 << 15,
+	Bloque:      8,
+	Paralelismo: 1,
+}
+
+// La credencial de una cuenta: el hash de la contraseña con su sal y los parámetros con
+// los que se calculó. La contraseña misma no se guarda.
+type credencial struct {
+	Sal  []byte `json:"sal"`
+	Hash []byte `json:"hash"`
+	N    int    `json:"n"`
+	R    int    `json:"r"`
+	P    int    `json:"p"`
+}
+
+func (c credencial) calcular(contrasena string) ([]byte, error) {
+	return scrypt.Key([]byte(contrasena), c.Sal, c.N, c.R, c.P, len(c.Hash))
+}
+
+// AlmacenCredenciales guarda las cuentas de los usuarios en un archivo, con sus
+// contraseñas protegidas con scrypt y una sal aleatoria por cuenta. Cada cuenta nueva
+// reescribe el archivo completo, por lo que está pensado para una cantidad moderada de
+// cuentas. Es seguro para el uso concurrente.
+type AlmacenCredenciales struct {
+	ruta     string
+	opciones OpcionesCredenciales
+	candado  sync.Mutex
+	cuentas  map[string]credencial
+	// se usa para verificar a los usuarios sin cuenta con el mismo costo que al resto,
+	// de modo que el tiempo de respuesta no revele qué cuentas existen
+	ficticia credencial
+	// un semáforo con un lugar por cada hash que se puede calcular a la vez
+	calculos chan struct{}
+}
+
+// Abre el almacén guardado en el archivo indicado, o uno vacío si el archivo no existe
+// todavía; el archivo se crea con la primera cuenta.
+func AbrirAlmacenCredenciales(ruta string, opciones OpcionesCredenciales) (*AlmacenCredenciales, error) {
+	a := &AlmacenCredenciales{
+		ruta:     ruta,
+		opciones: opciones,
+		cuentas:  make(map[string]credencial),
+		calculos: make(chan struct{}, CALCULOS_SIMULTANEOS),
+	}
+	datos, err := os.ReadFile(ruta)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(datos, &a.cuentas); err != nil {
+			return nil, err
+		}
+	}
+	if a.ficticia, err = a.nuevaCredencial(""); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *AlmacenCredenciales) nuevaCredencial(contrasena string) (credencial, error) {
+	c := credencial{
+		Sal:  make([]byte, LARGO_SAL),
+		Hash: make([]byte, LARGO_HASH_SCRYPT),
+		N:    a.opciones.Costo,
+		R:    a.opciones.Bloque,
+		P:    a.opciones.Paralelismo,
+	}
+	if _, err := rand.Read(c.Sal); err != nil {
+		return c, err
+	}
+	hash, err := a.calcular(c, contrasena)
+	c.Hash = hash
+	return c, err
+}
+
+// Calcula el hash de la contraseña con los parámetros de la credencial, esperando a que
+// haya lugar entre los CALCULOS_SIMULTANEOS.
+func (a *AlmacenCredenciales) calcular(c credencial, contrasena string) ([]byte, error) {
+	a.calculos <- struct{}{}
+	defer func() { <-a.calculos }()
+	return c.calcular(contrasena)
+}
+
+// Crea una cuenta para el usuario con la contraseña indicada y la guarda en el archivo
+// antes de volver. Devuelve ErrCuentaExistente si el usuario ya tenía una.
+func (a *AlmacenCredenciales) Crear(usuario string, contrasena string) error {
+	// el hash se calcula sin el candado, ya que es deliberadamente lento
+	nueva, err := a.nuevaCredencial(contrasena)
+	if err != nil {
+		return err
+	}
+
+	a.candado.Lock()
+	defer a.candado.Unlock()
+	if _, ok := a.cuentas[usuario]; ok {
+		return ErrCuentaExistente
+	}
+	a.cuentas[usuario] = nueva
+	if err := a.guardar(); err != nil {
+		delete(a.cuentas, usuario)
+		return err
+	}
+	return nil
+}
+
+// Escribe las cuentas en un archivo temporal y lo renombra, para que una falla a mitad
+// de camino no deje el archivo incompleto. Debe llamarse con el candado tomado.
+func (a *AlmacenCredenciales) guardar() error {
+	datos, err := json.Marshal(a.cuentas)
+	if err != nil {
+		return err
+	}
+	temporal, err := os.CreateTemp(filepath.Dir(a.ruta), filepath.Base(a.ruta)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temporal.Name())
+	if _, err := temporal.Write(datos); err != nil {
+		temporal.Close()
+		return err
+	}
+	if err := temporal.Sync(); err != nil {
+		temporal.Close()
+		return err
+	}
+	if err := temporal.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temporal.Name(), a.ruta)
+}
+
+// Devuelve si el usuario tiene una cuenta y la contraseña es la suya.
+func (a *AlmacenCredenciales) Verificar(usuario string, contrasena string) bool {
+	a.candado.Lock()
+	c, existe := a.cuentas[usuario]
+	a.candado.Unlock()
+	if !existe {
+		c = a.ficticia
+	}
+
+	hash, err := a.calcular(c, contrasena)
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(hash, c.Hash) == 1 && existe
+}
+
+// Cuenta las cuentas creadas desde cada dirección en el intervalo en curso. Los intervalos
+// son fijos y comunes a todas las direcciones, de modo que al empezar uno nuevo se olvidan
+// las del anterior.
+type limiteAltas struct {
+	sync.Mutex
+	maximo    int
+	intervalo time.Duration
+	inicio    time.Time
+	altas     map[string]int
+}
+
+func nuevoLimiteAltas(maximo int, intervalo time.Duration) *limiteAltas {
+	return &limiteAltas{maximo: maximo, intervalo: intervalo, altas: make(map[string]int)}
+}
+
+// Registra un alta desde la dirección indicada y devuelve si no supera el límite o, si lo
+// supera, cuánto falta para el próximo intervalo. Con un máximo de 0 no hay límite.
+func (l *limiteAltas) permitir(direccion string) (bool, time.Duration) {
+	if l.maximo <= 0 {
+		return true, 0
+	}
+	l.Lock()
+	defer l.Unlock()
+	ahora := time.Now()
+	if ahora.Sub(l.inicio) >= l.intervalo {
+		l.inicio = ahora
+		l.altas = make(map[string]int)
+	}
+	if l.altas[direccion] >= l.maximo {
+		return false, l.inicio.Add(l.intervalo).Sub(ahora)
+	}
+	l.altas[direccion]++
+	return true, 0
+}
+
+// Devuelve la dirección IP del cliente de la llamada, sin el puerto.
+func direccionCliente(ctx context.Context) string {
+	par, ok := peer.FromContext(ctx)
+	if !ok || par.Addr == nil {
+		return ""
+	}
+	direccion := par.Addr.String()
+	if host, _, err := net.SplitHostPort(direccion); err == nil {
+		return host
+	}
+	return direccion
+}
+
+// Implementación de CrearCuenta definido en el archivo `.proto`.
+// Cualquiera puede llamarlo sin autenticarse, por lo que cada dirección puede crear como
+// máximo s.limiteAltas cuentas por intervalo; las demás se rechazan con
+// codes.ResourceExhausted antes de calcular el hash de la contraseña. Las solicitudes con
+// un usuario o una contraseña inválidos se rechazan antes y no cuentan para el límite.
+func (s *Servidor) CrearCuenta(ctx context.Context, r *Registracion) (*Correcto, error) {
+	if s.credenciales == nil {
+		return nil, nuevoError(codes.FailedPrecondition, RAZON_SIN_CUENTAS, nil, "el servidor no usa cuentas: basta con conectarse")
+	}
+	if err := validarUsuario(r.UsuarioOrigen); err != nil {
+		return nil, err
+	}
+	if r.Contrasena == "" {
+		return nil, nuevoError(codes.InvalidArgument, RAZON_ARGUMENTO_INVALIDO, nil, "la contraseña no puede estar vacía")
+	}
+	if permitida, espera := s.limiteAltas.permitir(direccionCliente(ctx)); !permitida {
+		return nil, conReintento(nuevoError(codes.ResourceExhausted, RAZON_LIMITE_ALTAS, nil, "se crearon demasiadas cuentas desde esta dirección, vuelva a intentarlo más tarde"), espera)
+	}
+
+	err := s.credenciales.Crear(r.UsuarioOrigen, r.Contrasena)
+	if errors.Is(err, ErrCuentaExistente) {
+		return nil, nuevoError(codes.AlreadyExists, RAZON_CUENTA_EXISTENTE, map[string]string{"usuario": r.UsuarioOrigen}, "El usuario %s ya tiene una cuenta", r.UsuarioOrigen)
+	}
+	if err != nil {
+		return nil, nuevoError(codes.Internal, RAZON_INTERNA, nil, "no se pudo guardar la cuenta: %s", err)
+	}
+	return &Correcto{Ok: true}, nil
+}
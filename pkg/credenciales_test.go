@@ -0,0 +1,93 @@
+package pkg
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Un costo bajo para que las pruebas no demoren.
+var opcionesCredencialesPrueba = OpcionesCredenciales{Costo: 1 << 10, Bloque: 8, Paralelismo: 1}
+
+// Las cuentas sobreviven a reabrir el almacén y solo la contraseña correcta las verifica.
+func TestCredencialesPersisten(t *testing.T) {
+	ruta := filepath.Join(t.TempDir(), "cuentas.json")
+	almacen, err := AbrirAlmacenCredenciales(ruta, opcionesCredencialesPrueba)
+	if err != nil {
+		t.Fatalf("No se pudo abrir el almacén: %s", err)
+	}
+	if err := almacen.Crear("ana", "secreto"); err != nil {
+		t.Fatalf("No se pudo crear la cuenta: %s", err)
+	}
+	if err := almacen.Crear("ana", "otro"); err != ErrCuentaExistente {
+		t.Errorf("Se esperaba ErrCuentaExistente al repetir la cuenta, se obtuvo %v", err)
+	}
+
+	almacen, err = AbrirAlmacenCredenciales(ruta, opcionesCredencialesPrueba)
+	if err != nil {
+		t.Fatalf("No se pudo reabrir el almacén: %s", err)
+	}
+	casos := []struct {
+		usuario, contrasena string
+		valida              bool
+	}{
+		{"ana", "secreto", true},
+		{"ana", "otro", false},
+		{"ana", "", false},
+		{"beto", "secreto", false},
+		{"beto", "", false},
+	}
+	for _, caso := range casos {
+		if valida := almacen.Verificar(caso.usuario, caso.contrasena); valida != caso.valida {
+			t.Errorf("Con %s y %q se esperaba %t, se obtuvo %t", caso.usuario, caso.contrasena, caso.valida, valida)
+		}
+	}
+}
+
+// Con todos los lugares para calcular hashes ocupados, una verificación espera a que se
+// libere uno.
+func TestCredencialesLimitanCalculos(t *testing.T) {
+	almacen, err := AbrirAlmacenCredenciales(filepath.Join(t.TempDir(), "cuentas.json"), opcionesCredencialesPrueba)
+	if err != nil {
+		t.Fatalf("No se pudo abrir el almacén: %s", err)
+	}
+	for i := 0; i < CALCULOS_SIMULTANEOS; i++ {
+		almacen.calculos <- struct{}{}
+	}
+
+	terminada := make(chan bool)
+	go func() { terminada <- almacen.Verificar("ana", "secreto") }()
+	select {
+	case <-terminada:
+		t.Fatalf("Se esperaba que la verificación esperara a que hubiera lugar")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	<-almacen.calculos
+	select {
+	case <-terminada:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Se esperaba que la verificación terminara al liberarse un lugar")
+	}
+}
+
+// Cada dirección puede crear hasta el máximo de cuentas por intervalo, sin afectar a las
+// demás, y vuelve a poder al empezar el intervalo siguiente.
+func TestLimiteAltas(t *testing.T) {
+	limite := nuevoLimiteAltas(2, 50*time.Millisecond)
+	for i := 0; i < 2; i++ {
+		if permitida, _ := limite.permitir("10.0.0.1"); !permitida {
+			t.Fatalf("Se esperaba que se permitiera el alta %d", i+1)
+		}
+	}
+	if permitida, espera := limite.permitir("10.0.0.1"); permitida || espera <= 0 {
+		t.Fatalf("Se esperaba que se rechazara la tercera alta con una espera, se obtuvo %v y %s", permitida, espera)
+	}
+	if permitida, _ := limite.permitir("10.0.0.2"); !permitida {
+		t.Fatal("Se esperaba que se permitiera el alta desde otra dirección")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if permitida, _ := limite.permitir("10.0.0.1"); !permitida {
+		t.Fatal("Se esperaba que se permitiera el alta en el intervalo siguiente")
+	}
+}
@@ -0,0 +1,551 @@
+package pkg
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Cuándo se fuerza la escritura del diario al disco con fsync.
+type PoliticaFsync int
+
+const (
+	// Cada registro se sincroniza antes de responder al cliente: un `Enviar` confirmado
+	// sobrevive a una caída del sistema operativo.
+	FsyncSiempre PoliticaFsync = iota
+	// Los registros se sincronizan cada `OpcionesDiario.IntervaloFsync`; una caída puede
+	// perder los mensajes confirmados en el último intervalo.
+	FsyncPeriodico
+	// El sistema operativo decide cuándo escribir; solo sobrevive a la caída del proceso.
+	FsyncNunca
+)
+
+// Interpreta los valores aceptados por la opción -fsync de cmd/servidor.
+func ParsearPoliticaFsync(valor string) (PoliticaFsync, error) {
+	switch valor {
+	case "siempre":
+		return FsyncSiempre, nil
+	case "periodico":
+		return FsyncPeriodico, nil
+	case "nunca":
+		return FsyncNunca, nil
+	}
+	return 0, fmt.Errorf("política de fsync desconocida %q, se esperaba siempre, periodico o nunca", valor)
+}
+
+type OpcionesDiario struct {
+	Fsync PoliticaFsync
+	// Cada cuánto se sincroniza el diario con FsyncPeriodico.
+	IntervaloFsync time.Duration
+	// El diario se compacta cuando tiene al menos esta cantidad de registros y más del
+	// doble de los necesarios para reconstruir el estado actual.
+	UmbralCompactacion int
+}
+
+// Las opciones usadas si no se indica otra cosa.
+var OpcionesDiarioPredeterminadas = OpcionesDiario{
+	Fsync:              FsyncSiempre,
+	IntervaloFsync:     time.Second,
+	UmbralCompactacion: 10000,
+}
+
+// Los tipos de registro del diario.
+const (
+	// se registró el usuario en el directorio y se creó su bandeja de entrada; al
+	// compactar lleva además el número de secuencia del último mensaje aceptado
+	registroAlta byte = iota + 1
+	// se aceptó un mensaje para el usuario
+	registroDeposito
+	// el último mensaje aceptado para el usuario no llegó a su bandeja
+	registroAnulacion
+	// se confirmó o se descartó el mensaje con el identificador indicado
+	registroRetiro
+	// se creó el canal; en lugar del usuario el registro lleva el nombre del canal
+	registroCanal
+	// el usuario se unió al canal indicado
+	registroUnion
+	// el usuario abandonó el canal indicado
+	registroAbandono
+)
+
+// Cada registro se guarda como: largo de los datos (4 bytes), CRC32 de los datos
+// (4 bytes) y los datos. Un registro incompleto o corrupto al final del archivo indica
+// que el proceso murió mientras lo escribía y se descarta al abrir el diario.
+const largoCabeceraRegistro = 8
+
+// Un largo mayor indica una cabecera corrupta.
+const largoMaximoRegistro = 64 << 20
+
+// Diario es un registro en disco de solo agregado con las bandejas creadas, los
+// mensajes aceptados y confirmados por el servidor y los canales con sus miembros. Al
+// iniciar, el servidor lo reproduce para reconstruir las bandejas de entrada y los
+// canales; ver `ConDiario`.
+//
+// Además del archivo, el diario mantiene en memoria el estado vivo (usuarios, mensajes
+// pendientes, último número de secuencia y canales), que es lo único que se reescribe al
+// compactar.
+type Diario struct {
+	candado   sync.Mutex
+	ruta      string
+	opciones  OpcionesDiario
+	archivo   archivoDiario
+	escritor  *bufio.Writer
+	registros int
+	// si hay registros escritos que todavía no se sincronizaron
+	sucio bool
+
+	usuarios   map[string]bool
+	pendientes map[string][]pendiente
+	secuencias map[string]uint64
+	canales    map[string]map[string]bool
+
+	detener chan struct{}
+	listo   sync.WaitGroup
+}
+
+// Lo que el diario necesita del archivo donde escribe; es un *os.File salvo en las pruebas.
+type archivoDiario interface {
+	io.Writer
+	io.Seeker
+	Truncate(largo int64) error
+	Sync() error
+	Close() error
+}
+
+// Un mensaje pendiente en el estado vivo, tal como se registró.
+type pendiente struct {
+	id    string
+	datos []byte
+}
+
+// Abre el diario en la ruta indicada, creándolo si no existe, y recupera el estado
+// registrado en él.
+func AbrirDiario(ruta string, opciones OpcionesDiario) (*Diario, error) {
+	d := &Diario{
+		ruta:       ruta,
+		opciones:   opciones,
+		usuarios:   make(map[string]bool),
+		pendientes: make(map[string][]pendiente),
+		secuencias: make(map[string]uint64),
+		canales:    make(map[string]map[string]bool),
+		detener:    make(chan struct{}),
+	}
+
+	archivo, err := os.OpenFile(ruta, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	valido, err := d.reproducir(bufio.NewReader(archivo))
+	if err != nil {
+		archivo.Close()
+		return nil, err
+	}
+	// se descarta lo que haya quedado a medio escribir para que los registros
+	// nuevos queden a continuación del último registro válido
+	if err := archivo.Truncate(valido); err != nil {
+		archivo.Close()
+		return nil, err
+	}
+	if _, err := archivo.Seek(valido, io.SeekStart); err != nil {
+		archivo.Close()
+		return nil, err
+	}
+
+	d.archivo = archivo
+	d.escritor = bufio.NewWriter(archivo)
+
+	if opciones.Fsync == FsyncPeriodico {
+		d.listo.Add(1)
+		go d.sincronizarPeriodicamente()
+	}
+
+	return d, nil
+}
+
+// Lee los registros del diario y devuelve la posición en la que termina el último
+// registro válido.
+func (d *Diario) reproducir(lector *bufio.Reader) (int64, error) {
+	var valido int64
+	cabecera := make([]byte, largoCabeceraRegistro)
+	for {
+		if _, err := io.ReadFull(lector, cabecera); err != nil {
+			// fin del archivo o cabecera incompleta
+			return valido, nil
+		}
+		largo := binary.BigEndian.Uint32(cabecera[0:4])
+		suma := binary.BigEndian.Uint32(cabecera[4:8])
+		if largo > largoMaximoRegistro {
+			return valido, nil
+		}
+		datos := make([]byte, largo)
+		if _, err := io.ReadFull(lector, datos); err != nil || crc32.ChecksumIEEE(datos) != suma {
+			return valido, nil
+		}
+		if err := d.aplicar(datos); err != nil {
+			return 0, err
+		}
+		d.registros++
+		valido += largoCabeceraRegistro + int64(largo)
+	}
+}
+
+// Actualiza el estado vivo con un registro.
+func (d *Diario) aplicar(datos []byte) error {
+	tipo, usuario, resto, err := decodificarRegistro(datos)
+	if err != nil {
+		return err
+	}
+
+	switch tipo {
+	case registroAlta:
+		d.usuarios[usuario] = true
+		if len(resto) > 0 {
+			secuencia, n := binary.Uvarint(resto)
+			if n <= 0 {
+				return errors.New("registro de alta inválido en el diario")
+			}
+			d.secuencias[usuario] = secuencia
+		}
+	case registroDeposito:
+		// un depósito sin el alta del usuario no tiene bandeja a la que ir
+		if d.usuarios[usuario] {
+			msg := &MensajeApp{}
+			if err := proto.Unmarshal(resto, msg); err != nil {
+				return err
+			}
+			d.pendientes[usuario] = append(d.pendientes[usuario], pendiente{id: msg.Id, datos: resto})
+			d.secuencias[usuario] = msg.Secuencia
+		}
+	case registroAnulacion:
+		if n := len(d.pendientes[usuario]); n > 0 {
+			d.pendientes[usuario] = d.pendientes[usuario][:n-1]
+		}
+	case registroRetiro:
+		// los mensajes se confirman casi siempre en orden, por lo que se buscan desde el
+		// principio
+		pendientes := d.pendientes[usuario]
+		for i := range pendientes {
+			if pendientes[i].id == string(resto) {
+				d.pendientes[usuario] = append(pendientes[:i:i], pendientes[i+1:]...)
+				break
+			}
+		}
+	case registroCanal:
+		if _, ok := d.canales[usuario]; !ok {
+			d.canales[usuario] = make(map[string]bool)
+		}
+	case registroUnion:
+		if miembros, ok := d.canales[string(resto)]; ok {
+			miembros[usuario] = true
+		}
+	case registroAbandono:
+		delete(d.canales[string(resto)], usuario)
+	default:
+		return fmt.Errorf("tipo de registro desconocido en el diario: %d", tipo)
+	}
+	return nil
+}
+
+func codificarRegistro(tipo byte, usuario string, resto []byte) []byte {
+	datos := make([]byte, 1+binary.MaxVarintLen64, 1+binary.MaxVarintLen64+len(usuario)+len(resto))
+	datos[0] = tipo
+	n := binary.PutUvarint(datos[1:], uint64(len(usuario)))
+	datos = append(datos[:1+n], usuario...)
+	return append(datos, resto...)
+}
+
+// Escribe un registro con su cabecera.
+func escribirMarco(w io.Writer, datos []byte) error {
+	cabecera := make([]byte, largoCabeceraRegistro)
+	binary.BigEndian.PutUint32(cabecera[0:4], uint32(len(datos)))
+	binary.BigEndian.PutUint32(cabecera[4:8], crc32.ChecksumIEEE(datos))
+	if _, err := w.Write(cabecera); err != nil {
+		return err
+	}
+	_, err := w.Write(datos)
+	return err
+}
+
+func decodificarRegistro(datos []byte) (byte, string, []byte, error) {
+	if len(datos) < 1 {
+		return 0, "", nil, errors.New("registro vacío en el diario")
+	}
+	largo, n := binary.Uvarint(datos[1:])
+	if n <= 0 || uint64(len(datos)-1-n) < largo {
+		return 0, "", nil, errors.New("registro con usuario inválido en el diario")
+	}
+	inicio := 1 + n
+	fin := inicio + int(largo)
+	return datos[0], string(datos[inicio:fin]), datos[fin:], nil
+}
+
+// Escribe un registro y lo aplica al estado vivo. Debe llamarse con el candado tomado.
+//
+// Si el registro no se puede escribir o sincronizar, el archivo vuelve a su largo anterior
+// para seguir coincidiendo con el estado vivo. Si tampoco eso es posible y el registro
+// quedó completo en el archivo, se aplica igual, ya que se reproducirá al abrir el diario.
+func (d *Diario) escribir(tipo byte, usuario string, resto []byte) error {
+	if d.archivo == nil {
+		return errors.New("el diario está cerrado")
+	}
+	// el escritor se vacía después de cada registro, por lo que la posición del archivo
+	// es el final del último registro escrito
+	largo, err := d.archivo.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	datos := codificarRegistro(tipo, usuario, resto)
+	err = escribirMarco(d.escritor, datos)
+	if err == nil {
+		err = d.escritor.Flush()
+	}
+	if err != nil {
+		d.deshacer(largo)
+		return err
+	}
+	if d.opciones.Fsync == FsyncSiempre {
+		if err := d.archivo.Sync(); err != nil {
+			if d.deshacer(largo) != nil {
+				d.aplicar(datos)
+				d.registros++
+			}
+			return err
+		}
+	} else {
+		d.sucio = true
+	}
+
+	if err := d.aplicar(datos); err != nil {
+		return err
+	}
+	d.registros++
+	return d.compactarSiHaceFalta()
+}
+
+// Descarta lo que se haya escrito después de `largo`, incluso lo que quedó en el escritor,
+// para que el próximo registro se escriba a continuación del último válido.
+func (d *Diario) deshacer(largo int64) error {
+	d.escritor.Reset(d.archivo)
+	if err := d.archivo.Truncate(largo); err != nil {
+		return err
+	}
+	_, err := d.archivo.Seek(largo, io.SeekStart)
+	return err
+}
+
+func (d *Diario) registrar(tipo byte, usuario string, resto []byte) error {
+	d.candado.Lock()
+	defer d.candado.Unlock()
+	return d.escribir(tipo, usuario, resto)
+}
+
+// Registra que el usuario entró al directorio con su bandeja de entrada.
+func (d *Diario) Alta(usuario string) error {
+	return d.registrar(registroAlta, usuario, nil)
+}
+
+// Registra que se aceptó un mensaje para el usuario.
+func (d *Diario) Deposito(usuario string, msg *MensajeApp) error {
+	datos, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return d.registrar(registroDeposito, usuario, datos)
+}
+
+// Registra que el último mensaje aceptado para el usuario no llegó a su bandeja.
+func (d *Diario) Anulacion(usuario string) error {
+	return d.registrar(registroAnulacion, usuario, nil)
+}
+
+// Registra que el mensaje con el identificador indicado salió de la bandeja del usuario,
+// porque el usuario confirmó que lo recibió o porque se descartó.
+func (d *Diario) Retiro(usuario string, id string) error {
+	return d.registrar(registroRetiro, usuario, []byte(id))
+}
+
+// Registra que se creó el canal.
+func (d *Diario) Canal(canal string) error {
+	return d.registrar(registroCanal, canal, nil)
+}
+
+// Registra que el usuario se unió al canal.
+func (d *Diario) Union(usuario string, canal string) error {
+	return d.registrar(registroUnion, usuario, []byte(canal))
+}
+
+// Registra que el usuario abandonó el canal.
+func (d *Diario) Abandono(usuario string, canal string) error {
+	return d.registrar(registroAbandono, usuario, []byte(canal))
+}
+
+// Devuelve los canales registrados con sus miembros.
+func (d *Diario) Canales() map[string][]string {
+	d.candado.Lock()
+	defer d.candado.Unlock()
+
+	canales := make(map[string][]string, len(d.canales))
+	for canal, miembros := range d.canales {
+		canales[canal] = []string{}
+		for usuario := range miembros {
+			canales[canal] = append(canales[canal], usuario)
+		}
+	}
+	return canales
+}
+
+// Devuelve el número de secuencia del último mensaje aceptado para el usuario, o 0 si
+// todavía no recibió ninguno.
+func (d *Diario) UltimaSecuencia(usuario string) uint64 {
+	d.candado.Lock()
+	defer d.candado.Unlock()
+	return d.secuencias[usuario]
+}
+
+// Devuelve el estado registrado: los usuarios con bandeja de entrada y, para cada uno,
+// sus mensajes pendientes en orden de llegada.
+func (d *Diario) Estado() (map[string][]*MensajeApp, error) {
+	d.candado.Lock()
+	defer d.candado.Unlock()
+
+	estado := make(map[string][]*MensajeApp, len(d.usuarios))
+	for usuario := range d.usuarios {
+		mensajes := []*MensajeApp{}
+		for _, p := range d.pendientes[usuario] {
+			msg := &MensajeApp{}
+			if err := proto.Unmarshal(p.datos, msg); err != nil {
+				return nil, err
+			}
+			mensajes = append(mensajes, msg)
+		}
+		estado[usuario] = mensajes
+	}
+	return estado, nil
+}
+
+// Compacta el diario si acumuló demasiados registros que ya no aportan al estado.
+// Debe llamarse con el candado tomado.
+func (d *Diario) compactarSiHaceFalta() error {
+	vivos := len(d.usuarios) + len(d.canales)
+	for _, pendientes := range d.pendientes {
+		vivos += len(pendientes)
+	}
+	for _, miembros := range d.canales {
+		vivos += len(miembros)
+	}
+	if d.registros < d.opciones.UmbralCompactacion || d.registros <= 2*vivos {
+		return nil
+	}
+	return d.compactar()
+}
+
+// Reescribe el diario con los registros mínimos para reconstruir el estado vivo. El
+// archivo nuevo se escribe aparte y reemplaza al anterior de manera atómica, por lo que
+// una caída durante la compactación deja intacto el diario original.
+func (d *Diario) compactar() error {
+	temporal := d.ruta + ".compactando"
+	archivo, err := os.OpenFile(temporal, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	descartar := func(err error) error {
+		archivo.Close()
+		os.Remove(temporal)
+		return err
+	}
+
+	escritor := bufio.NewWriter(archivo)
+	registros := 0
+	for usuario := range d.usuarios {
+		secuencia := make([]byte, binary.MaxVarintLen64)
+		n := binary.PutUvarint(secuencia, d.secuencias[usuario])
+		if err := escribirMarco(escritor, codificarRegistro(registroAlta, usuario, secuencia[:n])); err != nil {
+			return descartar(err)
+		}
+		registros++
+		for _, p := range d.pendientes[usuario] {
+			if err := escribirMarco(escritor, codificarRegistro(registroDeposito, usuario, p.datos)); err != nil {
+				return descartar(err)
+			}
+			registros++
+		}
+	}
+	for canal, miembros := range d.canales {
+		if err := escribirMarco(escritor, codificarRegistro(registroCanal, canal, nil)); err != nil {
+			return descartar(err)
+		}
+		registros++
+		for usuario := range miembros {
+			if err := escribirMarco(escritor, codificarRegistro(registroUnion, usuario, []byte(canal))); err != nil {
+				return descartar(err)
+			}
+			registros++
+		}
+	}
+	if err := escritor.Flush(); err != nil {
+		return descartar(err)
+	}
+	if err := archivo.Sync(); err != nil {
+		return descartar(err)
+	}
+	if err := os.Rename(temporal, d.ruta); err != nil {
+		return descartar(err)
+	}
+	if directorio, err := os.Open(filepath.Dir(d.ruta)); err == nil {
+		directorio.Sync()
+		directorio.Close()
+	}
+
+	d.archivo.Close()
+	d.archivo, d.escritor = archivo, escritor
+	d.registros = registros
+	d.sucio = false
+	return nil
+}
+
+func (d *Diario) sincronizarPeriodicamente() {
+	defer d.listo.Done()
+	reloj := time.NewTicker(d.opciones.IntervaloFsync)
+	defer reloj.Stop()
+	for {
+		select {
+		case <-reloj.C:
+			d.candado.Lock()
+			if d.archivo != nil && d.sucio {
+				d.archivo.Sync()
+				d.sucio = false
+			}
+			d.candado.Unlock()
+		case <-d.detener:
+			return
+		}
+	}
+}
+
+// Sincroniza y cierra el diario.
+func (d *Diario) Cerrar() error {
+	close(d.detener)
+	d.listo.Wait()
+
+	d.candado.Lock()
+	defer d.candado.Unlock()
+	if d.archivo == nil {
+		return nil
+	}
+	err := d.archivo.Sync()
+	if errCierre := d.archivo.Close(); err == nil {
+		err = errCierre
+	}
+	d.archivo = nil
+	return err
+}
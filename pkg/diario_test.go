@@ -0,0 +1,290 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func abrirDiarioPrueba(t *testing.T, ruta string, opciones OpcionesDiario) *Diario {
+	diario, err := AbrirDiario(ruta, opciones)
+	if err != nil {
+		t.Fatalf("No se pudo abrir el diario: %s", err)
+	}
+	return diario
+}
+
+func cuerpos(mensajes []*MensajeApp) []string {
+	resultado := []string{}
+	for _, msg := range mensajes {
+		resultado = append(resultado, msg.Cuerpo)
+	}
+	return resultado
+}
+
+func TestDiarioReproduceEstado(t *testing.T) {
+	ruta := filepath.Join(t.TempDir(), "diario")
+
+	diario := abrirDiarioPrueba(t, ruta, OpcionesDiarioPredeterminadas)
+	diario.Alta("ana")
+	diario.Alta("beto")
+	for i := 0; i < 5; i++ {
+		diario.Deposito("ana", &MensajeApp{Id: fmt.Sprintf("%d", i), Usuario: "beto", Cuerpo: fmt.Sprintf("%d", i)})
+	}
+	diario.Retiro("ana", "0")
+	diario.Retiro("ana", "1")
+	diario.Deposito("ana", &MensajeApp{Usuario: "beto", Cuerpo: "anulado"})
+	diario.Anulacion("ana")
+	diario.Deposito("beto", &MensajeApp{Usuario: "ana", Cuerpo: "para beto"})
+	diario.Deposito("carla", &MensajeApp{Usuario: "ana", Cuerpo: "sin bandeja"})
+	diario.Cerrar()
+
+	diario = abrirDiarioPrueba(t, ruta, OpcionesDiarioPredeterminadas)
+	defer diario.Cerrar()
+	estado, err := diario.Estado()
+	if err != nil {
+		t.Fatalf("No se pudo leer el estado: %s", err)
+	}
+
+	if _, ok := estado["carla"]; ok || len(estado) != 2 {
+		t.Errorf("Se esperaban solo las bandejas de ana y beto, se obtuvo %+v", estado)
+	}
+	if obtenido := fmt.Sprint(cuerpos(estado["beto"])); obtenido != "[para beto]" {
+		t.Errorf("Se esperaba el mensaje [para beto] para beto, se obtuvo %s", obtenido)
+	}
+	if obtenido := fmt.Sprint(cuerpos(estado["ana"])); obtenido != "[2 3 4]" {
+		t.Errorf("Se esperaban los mensajes [2 3 4] para ana, se obtuvo %s", obtenido)
+	}
+}
+
+// Simula una caída mientras se escribía el último registro: el registro incompleto se
+// descarta y los siguientes se escriben a continuación del último registro válido.
+func TestDiarioDescartaRegistroIncompleto(t *testing.T) {
+	ruta := filepath.Join(t.TempDir(), "diario")
+
+	diario := abrirDiarioPrueba(t, ruta, OpcionesDiarioPredeterminadas)
+	diario.Alta("ana")
+	diario.Deposito("ana", &MensajeApp{Usuario: "beto", Cuerpo: "completo"})
+	diario.Deposito("ana", &MensajeApp{Usuario: "beto", Cuerpo: "incompleto"})
+	diario.Cerrar()
+
+	info, err := os.Stat(ruta)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := os.Truncate(ruta, info.Size()-3); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	diario = abrirDiarioPrueba(t, ruta, OpcionesDiarioPredeterminadas)
+	diario.Deposito("ana", &MensajeApp{Usuario: "beto", Cuerpo: "posterior"})
+	diario.Cerrar()
+
+	diario = abrirDiarioPrueba(t, ruta, OpcionesDiarioPredeterminadas)
+	defer diario.Cerrar()
+	estado, _ := diario.Estado()
+	if obtenido := fmt.Sprint(cuerpos(estado["ana"])); obtenido != "[completo posterior]" {
+		t.Errorf("Se esperaban los mensajes [completo posterior], se obtuvo %s", obtenido)
+	}
+}
+
+func TestDiarioCompacta(t *testing.T) {
+	ruta := filepath.Join(t.TempDir(), "diario")
+	opciones := OpcionesDiarioPredeterminadas
+	opciones.Fsync = FsyncNunca
+	opciones.UmbralCompactacion = 100
+
+	diario := abrirDiarioPrueba(t, ruta, opciones)
+	diario.Alta("ana")
+	for i := 0; i < 1000; i++ {
+		diario.Deposito("ana", &MensajeApp{Id: fmt.Sprintf("%d", i), Usuario: "beto", Cuerpo: fmt.Sprintf("%d", i)})
+		diario.Retiro("ana", fmt.Sprintf("%d", i))
+	}
+	diario.Deposito("ana", &MensajeApp{Usuario: "beto", Cuerpo: "último"})
+
+	if diario.registros >= opciones.UmbralCompactacion {
+		t.Errorf("Se esperaba que el diario se compactara, tiene %d registros", diario.registros)
+	}
+	diario.Cerrar()
+
+	diario = abrirDiarioPrueba(t, ruta, opciones)
+	defer diario.Cerrar()
+	estado, _ := diario.Estado()
+	if obtenido := fmt.Sprint(cuerpos(estado["ana"])); obtenido != "[último]" {
+		t.Errorf("Se esperaba el mensaje [último] luego de compactar, se obtuvo %s", obtenido)
+	}
+}
+
+// Un archivo cuyo fsync falla mientras `fallar` sea verdadero.
+type archivoSinSync struct {
+	archivoDiario
+	fallar bool
+}
+
+func (a *archivoSinSync) Sync() error {
+	if a.fallar {
+		return errors.New("sin fsync")
+	}
+	return a.archivoDiario.Sync()
+}
+
+// Un registro que no se pudo sincronizar no queda en el archivo ni en el estado vivo, y
+// los siguientes se escriben a continuación del último registro sincronizado.
+func TestDiarioFallaSync(t *testing.T) {
+	ruta := filepath.Join(t.TempDir(), "diario")
+
+	diario := abrirDiarioPrueba(t, ruta, OpcionesDiarioPredeterminadas)
+	diario.Alta("ana")
+	diario.Deposito("ana", &MensajeApp{Usuario: "beto", Cuerpo: "antes"})
+	registros := diario.registros
+
+	archivo := &archivoSinSync{archivoDiario: diario.archivo, fallar: true}
+	diario.archivo = archivo
+	diario.escritor.Reset(archivo)
+	if err := diario.Deposito("ana", &MensajeApp{Usuario: "beto", Cuerpo: "sin sync"}); err == nil {
+		t.Fatalf("Se esperaba un error al fallar el fsync")
+	}
+	if diario.registros != registros {
+		t.Errorf("Se esperaban %d registros luego de fallar el fsync, hay %d", registros, diario.registros)
+	}
+	estado, _ := diario.Estado()
+	if obtenido := fmt.Sprint(cuerpos(estado["ana"])); obtenido != "[antes]" {
+		t.Errorf("Se esperaba el mensaje [antes] luego de fallar el fsync, se obtuvo %s", obtenido)
+	}
+
+	archivo.fallar = false
+	if err := diario.Deposito("ana", &MensajeApp{Usuario: "beto", Cuerpo: "después"}); err != nil {
+		t.Fatalf("No se pudo registrar el mensaje: %s", err)
+	}
+	diario.Cerrar()
+
+	diario = abrirDiarioPrueba(t, ruta, OpcionesDiarioPredeterminadas)
+	defer diario.Cerrar()
+	estado, _ = diario.Estado()
+	if obtenido := fmt.Sprint(cuerpos(estado["ana"])); obtenido != "[antes después]" {
+		t.Errorf("Se esperaban los mensajes [antes después] al reabrir, se obtuvo %s", obtenido)
+	}
+}
+
+// Si el diario no puede registrar un cambio en los canales, el cambio tampoco se hace en
+// memoria y el servidor responde con codes.Internal.
+func TestCanalesSoloConDiario(t *testing.T) {
+	diario := abrirDiarioPrueba(t, filepath.Join(t.TempDir(), "diario"), OpcionesDiarioPredeterminadas)
+	defer diario.Cerrar()
+	s := NuevoServidor(ConDiario(diario), ConInactividadMaxima(0))
+	defer s.Cerrar()
+	ctxAna := context.WithValue(context.Background(), "nombreUsuario", "ana")
+	ctxBeto := context.WithValue(context.Background(), "nombreUsuario", "beto")
+	if _, err := s.CrearCanal(ctxAna, &Canal{Nombre: "sala"}); err != nil {
+		t.Fatalf("No se pudo crear el canal: %s", err)
+	}
+
+	archivo := &archivoSinSync{archivoDiario: diario.archivo, fallar: true}
+	diario.archivo = archivo
+	diario.escritor.Reset(archivo)
+	if _, err := s.CrearCanal(ctxAna, &Canal{Nombre: "otra"}); status.Code(err) != codes.Internal {
+		t.Errorf("Se esperaba Internal al crear un canal sin diario, se obtuvo %+v", err)
+	}
+	if s.Canales.Existe("otra") {
+		t.Errorf("Se esperaba que el canal que no llegó al diario no existiera")
+	}
+	if _, err := s.UnirseCanal(ctxBeto, &Canal{Nombre: "sala"}); status.Code(err) != codes.Internal {
+		t.Errorf("Se esperaba Internal al unirse a un canal sin diario, se obtuvo %+v", err)
+	}
+	if s.Canales.EsMiembro("sala", "beto") {
+		t.Errorf("Se esperaba que la unión que no llegó al diario no se hiciera")
+	}
+	if _, err := s.AbandonarCanal(ctxAna, &Canal{Nombre: "sala"}); status.Code(err) != codes.Internal {
+		t.Errorf("Se esperaba Internal al abandonar un canal sin diario, se obtuvo %+v", err)
+	}
+	if !s.Canales.EsMiembro("sala", "ana") {
+		t.Errorf("Se esperaba que el abandono que no llegó al diario no se hiciera")
+	}
+}
+
+// Un almacén que no puede crear bandejas.
+type almacenSinLugar struct {
+	AlmacenBandejas
+}
+
+func (almacenSinLugar) Crear(string) (BandejaEntrada, error) {
+	return nil, errors.New("sin lugar")
+}
+
+// Si la bandeja no se puede crear, el diario no registra el alta y al reiniciar no aparece
+// una bandeja que nunca existió.
+func TestAltaSoloConBandeja(t *testing.T) {
+	ruta := filepath.Join(t.TempDir(), "diario")
+	diario := abrirDiarioPrueba(t, ruta, OpcionesDiarioPredeterminadas)
+	s := NuevoServidor(ConDiario(diario), ConAlmacenBandejas(almacenSinLugar{NuevoAlmacenBandejasMemoria(LARGO_BUZON)}), ConInactividadMaxima(0))
+	if err := s.crearBandeja("ana"); err == nil {
+		t.Fatalf("Se esperaba un error al crear la bandeja")
+	}
+	diario.Cerrar()
+
+	diario = abrirDiarioPrueba(t, ruta, OpcionesDiarioPredeterminadas)
+	defer diario.Cerrar()
+	estado, err := diario.Estado()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if _, ok := estado["ana"]; ok {
+		t.Errorf("Se esperaba que el diario no tuviera el alta de una bandeja que no se creó")
+	}
+}
+
+// El número de secuencia del último mensaje aceptado sobrevive a la compactación, aunque
+// ya no queden mensajes pendientes.
+func TestDiarioConservaUltimaSecuencia(t *testing.T) {
+	ruta := filepath.Join(t.TempDir(), "diario")
+	opciones := OpcionesDiarioPredeterminadas
+	opciones.Fsync = FsyncNunca
+	opciones.UmbralCompactacion = 100
+
+	diario := abrirDiarioPrueba(t, ruta, opciones)
+	diario.Alta("ana")
+	for i := 1; i <= 1000; i++ {
+		diario.Deposito("ana", &MensajeApp{Id: fmt.Sprintf("%d", i), Usuario: "beto", Cuerpo: fmt.Sprintf("%d", i), Secuencia: uint64(i)})
+		diario.Retiro("ana", fmt.Sprintf("%d", i))
+	}
+	diario.Cerrar()
+
+	diario = abrirDiarioPrueba(t, ruta, opciones)
+	defer diario.Cerrar()
+	if secuencia := diario.UltimaSecuencia("ana"); secuencia != 1000 {
+		t.Errorf("Se esperaba la secuencia 1000 luego de compactar, se obtuvo %d", secuencia)
+	}
+}
+
+// Los canales y sus miembros se reproducen al abrir el diario, también luego de compactarlo.
+func TestDiarioCanales(t *testing.T) {
+	ruta := filepath.Join(t.TempDir(), "diario")
+	opciones := OpcionesDiarioPredeterminadas
+	opciones.Fsync = FsyncNunca
+	opciones.UmbralCompactacion = 100
+
+	diario := abrirDiarioPrueba(t, ruta, opciones)
+	diario.Canal("sala")
+	diario.Union("ana", "sala")
+	diario.Union("beto", "sala")
+	diario.Canal("vacio")
+	for i := 0; i < 100; i++ {
+		diario.Union("carla", "sala")
+		diario.Abandono("carla", "sala")
+	}
+	diario.Abandono("beto", "sala")
+	diario.Cerrar()
+
+	diario = abrirDiarioPrueba(t, ruta, opciones)
+	defer diario.Cerrar()
+	canales := diario.Canales()
+	if obtenido := fmt.Sprint(canales["sala"]); len(canales) != 2 || obtenido != "[ana]" || len(canales["vacio"]) != 0 {
+		t.Errorf("Se esperaban los canales sala con [ana] y vacio sin miembros, se obtuvo %+v", canales)
+	}
+}
@@ -0,0 +1,429 @@
+package pkg
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+)
+
+// Qué hace el servidor cuando la bandeja de entrada del destinatario está llena.
+type PoliticaDesborde int
+
+const (
+	// El envío falla con codes.ResourceExhausted; el remitente puede reintentarlo más tarde.
+	PoliticaRechazar PoliticaDesborde = iota
+	// Se descarta el mensaje más antiguo de la bandeja para hacer lugar al nuevo.
+	PoliticaDescartarAntiguo
+	// Se descarta el mensaje nuevo y Enviar responde con Ok en false.
+	PoliticaDescartarNuevo
+	// El mensaje se guarda en disco hasta que el destinatario vacíe su bandeja.
+	PoliticaVolcarADisco
+)
+
+// Interpreta los valores aceptados por la opción -desborde de cmd/servidor.
+func ParsearPoliticaDesborde(valor string) (PoliticaDesborde, error) {
+	switch valor {
+	case "rechazar":
+		return PoliticaRechazar, nil
+	case "descartar-antiguo":
+		return PoliticaDescartarAntiguo, nil
+	case "descartar-nuevo":
+		return PoliticaDescartarNuevo, nil
+	case "volcar":
+		return PoliticaVolcarADisco, nil
+	}
+	return 0, fmt.Errorf("política de desborde desconocida %q, se esperaba rechazar, descartar-antiguo, descartar-nuevo o volcar", valor)
+}
+
+// Devuelve la política de desborde que corresponde al usuario.
+func (s *Servidor) politica(usuario string) PoliticaDesborde {
+	if politica, ok := s.politicasUsuario[usuario]; ok {
+		return politica
+	}
+	return s.politicaDesborde
+}
+
+// Devuelve un identificador aleatorio para un mensaje.
+func nuevoId() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// Crea la bandeja de entrada del usuario y lo registra en el diario. El alta se registra
+// solo si la bandeja se pudo crear, para que el diario no reconstruya bandejas que nunca
+// existieron, y con el candado de la bandeja tomado, para que ningún depósito quede en el
+// diario antes que ella. Si el alta falla, la bandeja queda sin usuario en el directorio
+// y la reemplaza la próxima que se cree.
+func (s *Servidor) crearBandeja(usuario string) error {
+	candado := s.candadosBandeja.candado(usuario)
+	candado.Lock()
+	defer candado.Unlock()
+
+	if _, err := s.BandejasEntrada.Crear(usuario); err != nil {
+		return err
+	}
+	if s.diario != nil {
+		return s.diario.Alta(usuario)
+	}
+	return nil
+}
+
+// Agrega el mensaje a la bandeja aplicando la política de desborde del usuario.
+// Devuelve si el mensaje quedó para ser entregado y los mensajes antiguos que se
+// descartaron para hacerle lugar. Debe llamarse con el candado de la bandeja tomado.
+func (s *Servidor) encolar(ctx context.Context, usuario string, bandejaEntrada BandejaEntrada, msg *MensajeApp) (bool, []*MensajeApp, error) {
+	politica := s.politica(usuario)
+	volcar := politica == PoliticaVolcarADisco && s.volcado != nil
+
+	// mientras haya mensajes volcados los nuevos van detrás de ellos, para conservar el orden
+	if volcar && s.volcado.Largo(usuario) > 0 {
+		return true, nil, s.volcado.Agregar(usuario, msg)
+	}
+
+	var descartados []*MensajeApp
+	for {
+		err := bandejaEntrada.Depositar(ctx, msg)
+		if !errors.Is(err, ErrBandejaLlena) {
+			return err == nil, descartados, err
+		}
+
+		switch {
+		case politica == PoliticaDescartarNuevo:
+			return false, descartados, nil
+		case politica == PoliticaDescartarAntiguo:
+			antiguos, err := bandejaEntrada.Retirar(1)
+			if err != nil {
+				return false, descartados, err
+			}
+			if len(antiguos) == 0 {
+				// una bandeja sin capacidad: no hay nada que descartar
+				return false, descartados, nil
+			}
+			descartados = append(descartados, antiguos...)
+		case volcar:
+			return true, descartados, s.volcado.Agregar(usuario, msg)
+		default:
+			return false, descartados, conReintento(nuevoError(codes.ResourceExhausted, RAZON_BANDEJA_LLENA, map[string]string{"usuario": usuario}, "La bandeja de entrada de %s está llena", usuario), REINTENTO_BANDEJA_LLENA)
+		}
+	}
+}
+
+// Deposita un mensaje en la bandeja de entrada del destinatario según su política de
+// desborde y devuelve si el mensaje quedó para ser entregado. Le asigna al mensaje el
+// siguiente número de secuencia del destinatario. Si hay un diario, el mensaje se
+// registra antes de depositarlo, de modo que un envío confirmado no se pierda si el
+// servidor se reinicia, y también se registran los mensajes que la política de desborde
+// dejó fuera de la bandeja. Si esto último falla el depósito falla con codes.Internal,
+// aunque el mensaje haya quedado en la bandeja: el diario ya no coincide con ella y los
+// mensajes descartados volverían a la bandeja al reiniciar el servidor.
+func (s *Servidor) depositar(ctx context.Context, usuarioDestino string, bandejaEntrada BandejaEntrada, msg *MensajeApp) (bool, error) {
+	candado := s.candadosBandeja.candado(usuarioDestino)
+	candado.Lock()
+	defer candado.Unlock()
+
+	// el número se asigna con el candado tomado para que el orden de las secuencias sea
+	// el de la bandeja; un mensaje que no llega a la bandeja deja un salto
+	candado.ultimaSecuencia++
+	msg.Secuencia = candado.ultimaSecuencia
+
+	if s.diario != nil {
+		if err := s.diario.Deposito(usuarioDestino, msg); err != nil {
+			return false, nuevoError(codes.Internal, RAZON_INTERNA, nil, "no se pudo registrar el mensaje: %s", err)
+		}
+	}
+
+	entregado, descartados, err := s.encolar(ctx, usuarioDestino, bandejaEntrada, msg)
+	s.metricas.deposito(entregado, len(descartados), err)
+	if s.diario != nil {
+		var errDiario error
+		for _, descartado := range descartados {
+			if errRetiro := s.diario.Retiro(usuarioDestino, descartado.Id); errRetiro != nil && errDiario == nil {
+				errDiario = errRetiro
+			}
+		}
+		if err != nil || !entregado {
+			// el candado garantiza que el último depósito registrado para el
+			// destinatario es este mensaje
+			if errAnulacion := s.diario.Anulacion(usuarioDestino); errAnulacion != nil && errDiario == nil {
+				errDiario = errAnulacion
+			}
+		}
+		if errDiario != nil {
+			s.metricas.falloDiario()
+			s.registro.evento(NivelError, "No se pudo registrar en el diario un mensaje que quedó fuera de la bandeja", [2]string{"usuario", usuarioDestino}, [2]string{"error", errDiario.Error()})
+			if err == nil {
+				return false, nuevoError(codes.Internal, RAZON_INTERNA, nil, "no se pudo registrar el descarte en el diario: %s", errDiario)
+			}
+		}
+	}
+	return entregado, err
+}
+
+// Entrega una copia de un mensaje ya sellado a cada destinatario salvo al remitente,
+// con su propio número de secuencia. La política de desborde de cada destinatario se
+// aplica por separado: que la bandeja de uno esté llena no impide que los demás reciban
+// el mensaje. El mensaje queda una sola vez en el historial del remitente.
+func (s *Servidor) repartir(ctx context.Context, usuarioRemitente string, msg *MensajeApp, destinatarios []string) *RespuestaPublicar {
+	s.Archivo.Agregar(usuarioRemitente, &EntradaHistorial{Mensaje: proto.Clone(msg).(*MensajeApp)})
+	respuesta := &RespuestaPublicar{Id: msg.Id, Fecha: msg.Fecha}
+	for _, destinatario := range destinatarios {
+		if destinatario == usuarioRemitente {
+			continue
+		}
+		entregado, err := s.entregar(ctx, destinatario, proto.Clone(msg).(*MensajeApp))
+		if err != nil || !entregado {
+			respuesta.NoEntregados = append(respuesta.NoEntregados, destinatario)
+			continue
+		}
+		respuesta.Entregados++
+	}
+	return respuesta
+}
+
+// Devuelve cuántos de los mensajes, tomados desde el principio, no superan `maximoBytes`
+// en total, salvo que el primero lo supere por sí solo. Si `maximoBytes` no es positivo
+// no hay límite.
+func cortarPorBytes(mensajes []*MensajeApp, maximoBytes int) int {
+	if maximoBytes <= 0 {
+		return len(mensajes)
+	}
+	total := 0
+	for i, msg := range mensajes {
+		total += proto.Size(msg)
+		if total > maximoBytes && i > 0 {
+			return i
+		}
+	}
+	return len(mensajes)
+}
+
+// Devuelve cuántos mensajes esperan al usuario en su bandeja y en disco, sin contar los
+// reservados.
+func (s *Servidor) pendientes(usuario string) int {
+	bandejaEntrada, ok := s.BandejasEntrada.Bandeja(usuario)
+	if !ok {
+		return 0
+	}
+	pendientes := bandejaEntrada.Largo()
+	if s.volcado != nil {
+		pendientes += s.volcado.Largo(usuario)
+	}
+	return pendientes
+}
+
+// Un mensaje entregado que espera la confirmación del destinatario, con la sesión a la que
+// se entregó.
+type reserva struct {
+	msg    *MensajeApp
+	sesion string
+	vence  time.Time
+}
+
+// Retira y devuelve, en orden de llegada, hasta `maximo` mensajes pendientes para la
+// sesión del usuario: primero los de su bandeja y luego los que se hayan volcado a disco. Si
+// `maximoBytes` es positivo, los mensajes devueltos no lo superan en total, salvo que el
+// primero lo supere por sí solo. Devuelve también cuántos mensajes quedan pendientes.
+//
+// Los mensajes quedan reservados hasta que el usuario los confirme o venza el plazo de
+// visibilidad; hasta entonces siguen pendientes en el diario.
+func (s *Servidor) retirar(usuario string, sesion string, maximo int, maximoBytes int) ([]*MensajeApp, int, error) {
+	bandejaEntrada, ok := s.BandejasEntrada.Bandeja(usuario)
+	if !ok {
+		return nil, 0, nil
+	}
+
+	candado := s.candadosBandeja.candado(usuario)
+	candado.Lock()
+	defer candado.Unlock()
+
+	mensajes, err := bandejaEntrada.Retirar(maximo)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(mensajes) < maximo && s.volcado != nil {
+		volcados, err := s.volcado.Retirar(usuario, maximo-len(mensajes))
+		mensajes = append(mensajes, volcados...)
+		if err != nil {
+			// lo ya retirado se devuelve para no perderlo
+			bandejaEntrada.Devolver(mensajes)
+			return nil, 0, err
+		}
+	}
+	if corte := cortarPorBytes(mensajes, maximoBytes); corte < len(mensajes) {
+		if err := bandejaEntrada.Devolver(mensajes[corte:]); err != nil {
+			return nil, 0, err
+		}
+		mensajes = mensajes[:corte]
+	}
+
+	pendientes := s.pendientes(usuario)
+	if len(mensajes) == 0 {
+		return mensajes, pendientes, nil
+	}
+
+	vence := time.Now().Add(s.plazoVisibilidad)
+	for _, msg := range mensajes {
+		candado.reservas = append(candado.reservas, reserva{msg: msg, sesion: sesion, vence: vence})
+	}
+	time.AfterFunc(s.plazoVisibilidad, func() { s.vencerReservas(usuario) })
+	return mensajes, pendientes, nil
+}
+
+// Quita de las reservas del usuario los mensajes con los identificadores indicados y
+// devuelve las reservas que encontró, en orden. Debe llamarse con el candado de la
+// bandeja tomado.
+func quitarReservas(candado *candadoBandeja, ids []string) []reserva {
+	buscados := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		buscados[id] = true
+	}
+	var quitadas []reserva
+	restantes := candado.reservas[:0]
+	for _, r := range candado.reservas {
+		if buscados[r.msg.Id] {
+			quitadas = append(quitadas, r)
+		} else {
+			restantes = append(restantes, r)
+		}
+	}
+	// se limpia el final para que el arreglo no retenga los mensajes quitados
+	for i := len(restantes); i < len(candado.reservas); i++ {
+		candado.reservas[i] = reserva{}
+	}
+	candado.reservas = restantes
+	return quitadas
+}
+
+// Confirma la recepción por una sesión del usuario de los mensajes con los identificadores
+// indicados. Los que estaban reservados salen definitivamente de su bandeja y se copian a
+// las demás sesiones del usuario; las copias reservadas para la sesión se descartan.
+// Devuelve cuántos mensajes y copias estaban reservados.
+func (s *Servidor) confirmar(usuario string, sesion string, ids []string) (int, error) {
+	candado := s.candadosBandeja.candado(usuario)
+	candado.Lock()
+	confirmados := quitarReservas(candado, ids)
+	if s.diario != nil {
+		for _, r := range confirmados {
+			if err := s.diario.Retiro(usuario, r.msg.Id); err != nil {
+				candado.Unlock()
+				return 0, err
+			}
+		}
+	}
+	candado.Unlock()
+
+	s.copiarConfirmados(usuario, confirmados)
+	return len(confirmados) + s.copias.confirmar(sesion, ids), nil
+}
+
+// Vuelve a poner al principio de la bandeja del usuario mensajes reservados que no se
+// llegaron a entregar, para que la próxima lectura los obtenga en el mismo orden. Como
+// no se entregaron, no cuentan como reentregas.
+func (s *Servidor) liberar(usuario string, mensajes []*MensajeApp) error {
+	bandejaEntrada, ok := s.BandejasEntrada.Bandeja(usuario)
+	if !ok || len(mensajes) == 0 {
+		return nil
+	}
+
+	candado := s.candadosBandeja.candado(usuario)
+	candado.Lock()
+	defer candado.Unlock()
+
+	ids := make([]string, len(mensajes))
+	for i, msg := range mensajes {
+		ids[i] = msg.Id
+	}
+	var liberados []*MensajeApp
+	for _, r := range quitarReservas(candado, ids) {
+		liberados = append(liberados, r.msg)
+	}
+	return bandejaEntrada.Devolver(liberados)
+}
+
+// Devuelve a la bandeja del usuario los mensajes reservados cuyo plazo de visibilidad
+// venció, con su contador de reentregas incrementado, y despierta a sus flujos abiertos.
+func (s *Servidor) vencerReservas(usuario string) {
+	bandejaEntrada, ok := s.BandejasEntrada.Bandeja(usuario)
+	if !ok {
+		return
+	}
+
+	candado := s.candadosBandeja.candado(usuario)
+	candado.Lock()
+	ahora := time.Now()
+	var vencidos []*MensajeApp
+	restantes := candado.reservas[:0]
+	for _, r := range candado.reservas {
+		if r.vence.After(ahora) {
+			restantes = append(restantes, r)
+			continue
+		}
+		// se copia el mensaje porque el original puede estar serializándose todavía
+		// en la respuesta que lo entregó
+		msg := proto.Clone(r.msg).(*MensajeApp)
+		msg.Reentregas++
+		vencidos = append(vencidos, msg)
+	}
+	for i := len(restantes); i < len(candado.reservas); i++ {
+		candado.reservas[i] = reserva{}
+	}
+	candado.reservas = restantes
+	err := bandejaEntrada.Devolver(vencidos)
+	candado.Unlock()
+
+	if err != nil {
+		s.registro.evento(NivelError, "No se pudieron devolver los mensajes vencidos a la bandeja", [2]string{"usuario", usuario}, [2]string{"mensajes", fmt.Sprint(len(vencidos))}, [2]string{"error", err.Error()})
+		return
+	}
+	if len(vencidos) > 0 {
+		s.avisos.avisar(usuario)
+	}
+}
+
+// Reconstruye el directorio de usuarios y las bandejas de entrada a partir del estado
+// guardado en el diario. Los mensajes que estaban reservados sin confirmar al detenerse
+// el servidor vuelven a la bandeja.
+func (s *Servidor) restaurar() error {
+	estado, err := s.diario.Estado()
+	if err != nil {
+		return err
+	}
+
+	for usuario, mensajes := range estado {
+		s.Directorio.Registrar(usuario)
+		s.candadosBandeja.candado(usuario).ultimaSecuencia = s.diario.UltimaSecuencia(usuario)
+		bandejaEntrada, err := s.BandejasEntrada.Crear(usuario)
+		if err != nil {
+			return err
+		}
+		for i, msg := range mensajes {
+			entregado, descartados, err := s.encolar(context.Background(), usuario, bandejaEntrada, msg)
+			for _, descartado := range descartados {
+				if err := s.diario.Retiro(usuario, descartado.Id); err != nil {
+					return err
+				}
+			}
+			if err != nil || !entregado {
+				// los mensajes que ya no entran en la bandeja se descartan para que el
+				// diario vuelva a coincidir con la bandeja
+				s.registro.evento(NivelAdvertencia, "Se descartan los mensajes que no entran en la bandeja", [2]string{"usuario", usuario}, [2]string{"mensajes", fmt.Sprint(len(mensajes) - i)})
+				for _, descartado := range mensajes[i:] {
+					if err := s.diario.Retiro(usuario, descartado.Id); err != nil {
+						return err
+					}
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func enviarPrueba(t *testing.T, s *Servidor, destino string, cuerpos ...string) {
+	for _, cuerpo := range cuerpos {
+		if _, err := s.enviar(context.Background(), "beto", &MensajeApp{Usuario: destino, Cuerpo: cuerpo}); err != nil {
+			t.Fatalf("No se pudo enviar: %s", err)
+		}
+	}
+}
+
+// Un mensaje reservado que no se confirma vuelve a la bandeja al vencer su plazo, con su
+// contador de reentregas incrementado; uno confirmado no vuelve.
+func TestReservaVencida(t *testing.T) {
+	plazo := 50 * time.Millisecond
+	s := NuevoServidor(ConPlazoVisibilidad(plazo))
+	defer s.Cerrar()
+	s.Directorio.Registrar("ana")
+	s.crearBandeja("ana")
+	enviarPrueba(t, s, "ana", "confirmado", "olvidado")
+
+	mensajes, _, _ := s.retirar("ana", "sesion", LARGO_LOTE, 0)
+	if obtenido := fmt.Sprint(cuerpos(mensajes)); obtenido != "[confirmado olvidado]" {
+		t.Fatalf("Se esperaban los mensajes [confirmado olvidado], se obtuvo %s", obtenido)
+	}
+	if reservados, _, _ := s.retirar("ana", "sesion", LARGO_LOTE, 0); len(reservados) != 0 {
+		t.Errorf("Se esperaba que los mensajes reservados no se entregaran otra vez, se obtuvo %s", cuerpos(reservados))
+	}
+	if confirmados, _ := s.confirmar("ana", "sesion", []string{mensajes[0].Id, "desconocido"}); confirmados != 1 {
+		t.Errorf("Se esperaba confirmar un mensaje, se confirmaron %d", confirmados)
+	}
+
+	time.Sleep(3 * plazo)
+	reentregados, _, _ := s.retirar("ana", "sesion", LARGO_LOTE, 0)
+	if obtenido := fmt.Sprint(cuerpos(reentregados)); obtenido != "[olvidado]" {
+		t.Fatalf("Se esperaba reentregar [olvidado], se obtuvo %s", obtenido)
+	}
+	if reentregados[0].Reentregas != 1 || reentregados[0].Id != mensajes[1].Id {
+		t.Errorf("Se esperaba el mismo mensaje con una reentrega, se obtuvo %+v", reentregados[0])
+	}
+}
+
+// Los mensajes reservados sin confirmar siguen pendientes en el diario y vuelven a la
+// bandeja al reiniciar el servidor.
+func TestReservasSobrevivenReinicio(t *testing.T) {
+	ruta := filepath.Join(t.TempDir(), "diario")
+	diario := abrirDiarioPrueba(t, ruta, OpcionesDiarioPredeterminadas)
+	s := NuevoServidor(ConDiario(diario))
+	defer s.Cerrar()
+	s.Directorio.Registrar("ana")
+	s.crearBandeja("ana")
+	enviarPrueba(t, s, "ana", "0", "1", "2")
+
+	mensajes, _, _ := s.retirar("ana", "sesion", LARGO_LOTE, 0)
+	s.confirmar("ana", "sesion", []string{mensajes[1].Id})
+	diario.Cerrar()
+
+	diario = abrirDiarioPrueba(t, ruta, OpcionesDiarioPredeterminadas)
+	defer diario.Cerrar()
+	s = NuevoServidor(ConDiario(diario))
+	defer s.Cerrar()
+	restaurados, _, _ := s.retirar("ana", "sesion", LARGO_LOTE, 0)
+	if obtenido := fmt.Sprint(cuerpos(restaurados)); obtenido != "[0 2]" {
+		t.Errorf("Se esperaban los mensajes sin confirmar [0 2], se obtuvo %s", obtenido)
+	}
+}
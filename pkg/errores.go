@@ -0,0 +1,109 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// El dominio de los errores del servidor, que acompaña a cada razón en el ErrorInfo.
+const DOMINIO_ERRORES = "mensajero"
+
+// Las razones con las que el servidor explica sus errores, además del código de gRPC. Van
+// en el detalle ErrorInfo de cada error, de modo que los clientes puedan distinguir, por
+// ejemplo, un token vencido de uno desconocido sin leer el mensaje.
+const (
+	RAZON_SIN_TOKEN                = "SIN_TOKEN"
+	RAZON_TOKEN_INVALIDO           = "TOKEN_INVALIDO"
+	RAZON_SESION_VENCIDA           = "SESION_VENCIDA"
+	RAZON_SESION_INEXISTENTE       = "SESION_INEXISTENTE"
+	RAZON_CREDENCIALES_INCORRECTAS = "CREDENCIALES_INCORRECTAS"
+	RAZON_SIN_CUENTAS              = "SIN_CUENTAS"
+	RAZON_CUENTA_EXISTENTE         = "CUENTA_EXISTENTE"
+	RAZON_USUARIO_CONECTADO        = "USUARIO_CONECTADO"
+	RAZON_LIMITE_ALTAS             = "LIMITE_ALTAS"
+	RAZON_USUARIO_INEXISTENTE      = "USUARIO_INEXISTENTE"
+	RAZON_BANDEJA_LLENA            = "BANDEJA_LLENA"
+	RAZON_CANAL_INEXISTENTE        = "CANAL_INEXISTENTE"
+	RAZON_CANAL_EXISTENTE          = "CANAL_EXISTENTE"
+	RAZON_SIN_PERMISO              = "SIN_PERMISO"
+	RAZON_ARGUMENTO_INVALIDO       = "ARGUMENTO_INVALIDO"
+	RAZON_INTERNA                  = "INTERNA"
+)
+
+// Cuánto se sugiere esperar antes de volver a enviar a una bandeja llena, para dar tiempo
+// a que el destinatario lea sus mensajes.
+const REINTENTO_BANDEJA_LLENA = 5 * time.Second
+
+// Devuelve un error de gRPC con el código y el mensaje indicados y un detalle ErrorInfo
+// con la razón y los metadatos, que pueden ser nil.
+func nuevoError(codigo codes.Code, razon string, metadatos map[string]string, formato string, argumentos ...interface{}) error {
+	estado := status.New(codigo, fmt.Sprintf(formato, argumentos...))
+	conDetalles, err := estado.WithDetails(&errdetails.ErrorInfo{
+		Reason:   razon,
+		Domain:   DOMINIO_ERRORES,
+		Metadata: metadatos,
+	})
+	if err != nil {
+		return estado.Err()
+	}
+	return conDetalles.Err()
+}
+
+// Agrega a un error de gRPC un detalle RetryInfo que sugiere esperar `espera` antes de
+// reintentar la llamada.
+func conReintento(err error, espera time.Duration) error {
+	estado, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	conDetalles, errDetalle := estado.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(espera)})
+	if errDetalle != nil {
+		return err
+	}
+	return conDetalles.Err()
+}
+
+// Convierte un error de un manejador en un error de gRPC. Los errores que ya lo son no
+// cambian, los de un contexto terminado conservan su código y el resto, que gRPC
+// informaría como codes.Unknown, se informan como codes.Internal.
+func aErrorGrpc(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+	return nuevoError(codes.Internal, RAZON_INTERNA, nil, "error interno del servidor: %s", err)
+}
+
+// Devuelve la razón del detalle ErrorInfo de un error del servidor, o una cadena vacía si
+// no tiene una.
+func RazonError(err error) string {
+	for _, detalle := range status.Convert(err).Details() {
+		if info, ok := detalle.(*errdetails.ErrorInfo); ok && info.Domain == DOMINIO_ERRORES {
+			return info.Reason
+		}
+	}
+	return ""
+}
+
+// Devuelve cuánto sugiere esperar el servidor antes de reintentar la llamada que falló, si
+// lo indicó.
+func EsperaReintento(err error) (time.Duration, bool) {
+	for _, detalle := range status.Convert(err).Details() {
+		if reintento, ok := detalle.(*errdetails.RetryInfo); ok {
+			return reintento.RetryDelay.AsDuration(), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,74 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Los errores que no son de gRPC se informan como internos, con su razón, sin alterar los
+// que ya lo son ni los de un contexto terminado.
+func TestAErrorGrpc(t *testing.T) {
+	casos := []struct {
+		err    error
+		codigo codes.Code
+		razon  string
+	}{
+		{nil, codes.OK, ""},
+		{errors.New("disco lleno"), codes.Internal, RAZON_INTERNA},
+		{fmt.Errorf("al leer: %w", context.Canceled), codes.Canceled, ""},
+		{context.DeadlineExceeded, codes.DeadlineExceeded, ""},
+		{nuevoError(codes.NotFound, RAZON_USUARIO_INEXISTENTE, nil, "no existe"), codes.NotFound, RAZON_USUARIO_INEXISTENTE},
+		{status.Error(codes.Unavailable, "sin detalles"), codes.Unavailable, ""},
+	}
+	for _, caso := range casos {
+		err := aErrorGrpc(caso.err)
+		if status.Code(err) != caso.codigo || RazonError(err) != caso.razon {
+			t.Errorf("Para %v se esperaba %s con la razón %q, se obtuvo %+v con la razón %q", caso.err, caso.codigo, caso.razon, err, RazonError(err))
+		}
+	}
+}
+
+// Los errores del cliente se clasifican según el código y la razón del error del servidor,
+// y conservan el error original para errors.As y status.Code.
+func TestErrorCliente(t *testing.T) {
+	casos := []struct {
+		err  error
+		tipo error
+	}{
+		{nuevoError(codes.Unauthenticated, RAZON_SESION_VENCIDA, nil, "vencida"), ErrNoAutenticado},
+		{nuevoError(codes.NotFound, RAZON_USUARIO_INEXISTENTE, nil, "no existe"), ErrDestinatarioInexistente},
+		{nuevoError(codes.NotFound, RAZON_CANAL_INEXISTENTE, nil, "no existe"), ErrCanalInexistente},
+		{nuevoError(codes.ResourceExhausted, RAZON_BANDEJA_LLENA, nil, "llena"), ErrBandejaLlena},
+		{status.Error(codes.Unavailable, "sin conexión"), ErrServidorNoDisponible},
+		{nuevoError(codes.NotFound, RAZON_SESION_INEXISTENTE, nil, "no existe"), nil},
+		{status.Error(codes.ResourceExhausted, "otro límite"), nil},
+		{errors.New("sin código"), nil},
+	}
+	for _, caso := range casos {
+		err := errorCliente("enviar", caso.err)
+		var errCliente *ErrorCliente
+		if !errors.As(err, &errCliente) || errCliente.Tipo != caso.tipo {
+			t.Errorf("Para %v se esperaba la categoría %v, se obtuvo %+v", caso.err, caso.tipo, err)
+			continue
+		}
+		if caso.tipo != nil && !errors.Is(err, caso.tipo) {
+			t.Errorf("Se esperaba que %v fuera %v", err, caso.tipo)
+		}
+		if status.Code(err) != status.Code(caso.err) || !errors.Is(err, caso.err) {
+			t.Errorf("Se esperaba que %v conservara el error original %v", err, caso.err)
+		}
+	}
+
+	if errorCliente("enviar", nil) != nil {
+		t.Errorf("Se esperaba que un error nulo siguiera siendo nulo")
+	}
+	desconexion := &ErrorDesconexion{}
+	if err := errorCliente("salir", desconexion); err != desconexion || !errors.Is(err, ErrDesconectado) {
+		t.Errorf("Se esperaba que la desconexión no cambiara y fuera ErrDesconectado, se obtuvo %+v", err)
+	}
+}
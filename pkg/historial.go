@@ -0,0 +1,188 @@
+package pkg
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+// La cantidad predeterminada de mensajes que el archivo guarda por usuario.
+const LARGO_ARCHIVO = 10000
+
+// Los mensajes archivados de un usuario. Cada entrada tiene una posición que no cambia
+// mientras se conserve: la de entradas[i] es primera+i.
+type archivoUsuario struct {
+	primera  uint64
+	entradas []*EntradaHistorial
+}
+
+type fragmentoArchivo struct {
+	sync.Mutex
+	usuarios map[string]*archivoUsuario
+}
+
+// ArchivoMensajes guarda en memoria los mensajes que envió y recibió cada usuario, para
+// que pueda consultarlos con Historial aunque ya los haya obtenido. Cuando un usuario
+// supera la capacidad del archivo se olvidan sus mensajes más antiguos.
+type ArchivoMensajes struct {
+	capacidad  int
+	fragmentos [NUMERO_FRAGMENTOS]*fragmentoArchivo
+}
+
+// Devuelve un archivo vacío que guarda hasta `capacidad` mensajes por usuario. Es el que
+// usa `NuevoServidor` si no se indica otra capacidad, con LARGO_ARCHIVO.
+func NuevoArchivoMensajes(capacidad int) *ArchivoMensajes {
+	a := &ArchivoMensajes{capacidad: capacidad}
+	for i := range a.fragmentos {
+		a.fragmentos[i] = &fragmentoArchivo{usuarios: make(map[string]*archivoUsuario)}
+	}
+	return a
+}
+
+// Agrega una entrada al final del archivo del usuario. La entrada no debe modificarse
+// después.
+func (a *ArchivoMensajes) Agregar(usuario string, entrada *EntradaHistorial) {
+	if a.capacidad <= 0 {
+		return
+	}
+	f := a.fragmentos[indiceFragmento(usuario)]
+	f.Lock()
+	defer f.Unlock()
+	archivo, ok := f.usuarios[usuario]
+	if !ok {
+		// las posiciones empiezan en 1 para que 0 signifique "desde el final"
+		archivo = &archivoUsuario{primera: 1}
+		f.usuarios[usuario] = archivo
+	}
+	archivo.entradas = append(archivo.entradas, entrada)
+	if len(archivo.entradas) > a.capacidad {
+		// se limpia la posición olvidada para que el arreglo no retenga el mensaje
+		archivo.entradas[0] = nil
+		archivo.entradas = archivo.entradas[1:]
+		archivo.primera++
+	}
+}
+
+// Devuelve, de la más reciente a la más antigua, hasta `largo` entradas del archivo del
+// usuario para las que `coincide` es verdadero, empezando por las anteriores a la
+// posición `antes`, o por la última si es 0. Si quedan más entradas que coinciden
+// devuelve también la posición desde la que seguir, y si no 0.
+func (a *ArchivoMensajes) Consultar(usuario string, coincide func(*EntradaHistorial) bool, antes uint64, largo int) ([]*EntradaHistorial, uint64) {
+	f := a.fragmentos[indiceFragmento(usuario)]
+	f.Lock()
+	defer f.Unlock()
+	archivo, ok := f.usuarios[usuario]
+	if !ok {
+		return nil, 0
+	}
+
+	fin := len(archivo.entradas)
+	if antes != 0 {
+		if antes <= archivo.primera {
+			return nil, 0
+		}
+		if antes-archivo.primera < uint64(fin) {
+			fin = int(antes - archivo.primera)
+		}
+	}
+	var entradas []*EntradaHistorial
+	for i := fin - 1; i >= 0; i-- {
+		if !coincide(archivo.entradas[i]) {
+			continue
+		}
+		if len(entradas) == largo {
+			// hay al menos una entrada más: la siguiente página empieza después de la última
+			// devuelta
+			return entradas, archivo.primera + uint64(i) + 1
+		}
+		entradas = append(entradas, archivo.entradas[i])
+	}
+	return entradas, 0
+}
+
+// Convierte una posición del archivo en el cursor opaco que recibe el cliente.
+func escribirCursor(posicion uint64) string {
+	datos := make([]byte, 8)
+	binary.BigEndian.PutUint64(datos, posicion)
+	return base64.RawURLEncoding.EncodeToString(datos)
+}
+
+// Devuelve la posición del archivo que representa el cursor, o 0 si está vacío.
+func leerCursor(cursor string) (uint64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	datos, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(datos) != 8 {
+		return 0, nuevoError(codes.InvalidArgument, RAZON_ARGUMENTO_INVALIDO, nil, "el cursor %q no es válido", cursor)
+	}
+	return binary.BigEndian.Uint64(datos), nil
+}
+
+// Devuelve si el mensaje de la entrada es un mensaje directo entre el dueño del archivo
+// y `otro`, en cualquier sentido.
+func esConversacion(entrada *EntradaHistorial, otro string) bool {
+	msg := entrada.Mensaje
+	if msg.Canal != "" || msg.Difusion {
+		return false
+	}
+	return msg.Usuario == otro || entrada.Destinatario == otro
+}
+
+// Implementación de Historial definido en el archivo `.proto`.
+func (s *Servidor) Historial(ctx context.Context, consulta *ConsultaHistorial) (*PaginaHistorial, error) {
+	usuario := ctx.Value("nombreUsuario").(string)
+
+	antes, err := leerCursor(consulta.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	if consulta.Largo < 0 {
+		return nil, nuevoError(codes.InvalidArgument, RAZON_ARGUMENTO_INVALIDO, nil, "el largo no puede ser negativo")
+	}
+	largo := int(consulta.Largo)
+	if largo == 0 {
+		largo = LARGO_LOTE
+	}
+	if largo > s.largoLoteMaximo {
+		largo = s.largoLoteMaximo
+	}
+	var desde, hasta time.Time
+	if consulta.Desde != nil {
+		if err := consulta.Desde.CheckValid(); err != nil {
+			return nil, nuevoError(codes.InvalidArgument, RAZON_ARGUMENTO_INVALIDO, nil, "la fecha inicial no es válida: %s", err)
+		}
+		desde = consulta.Desde.AsTime()
+	}
+	if consulta.Hasta != nil {
+		if err := consulta.Hasta.CheckValid(); err != nil {
+			return nil, nuevoError(codes.InvalidArgument, RAZON_ARGUMENTO_INVALIDO, nil, "la fecha final no es válida: %s", err)
+		}
+		hasta = consulta.Hasta.AsTime()
+	}
+
+	coincide := func(entrada *EntradaHistorial) bool {
+		if consulta.Usuario != "" && !esConversacion(entrada, consulta.Usuario) {
+			return false
+		}
+		fecha := entrada.Mensaje.Fecha.AsTime()
+		if consulta.Desde != nil && fecha.Before(desde) {
+			return false
+		}
+		if consulta.Hasta != nil && !fecha.Before(hasta) {
+			return false
+		}
+		return true
+	}
+	entradas, siguiente := s.Archivo.Consultar(usuario, coincide, antes, largo)
+
+	pagina := &PaginaHistorial{Entradas: entradas}
+	if siguiente != 0 {
+		pagina.Cursor = escribirCursor(siguiente)
+	}
+	return pagina, nil
+}
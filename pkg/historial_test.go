@@ -0,0 +1,44 @@
+package pkg
+
+import (
+	"fmt"
+	"testing"
+)
+
+func cuerposEntradas(entradas []*EntradaHistorial) []string {
+	resultado := []string{}
+	for _, entrada := range entradas {
+		resultado = append(resultado, entrada.Mensaje.Cuerpo)
+	}
+	return resultado
+}
+
+// Las páginas recorren el archivo del más reciente al más antiguo sin repetir ni saltear
+// entradas, aunque entre ellas se olviden las más antiguas.
+func TestArchivoPaginas(t *testing.T) {
+	archivo := NuevoArchivoMensajes(6)
+	for i := 0; i < 8; i++ {
+		archivo.Agregar("ana", &EntradaHistorial{Mensaje: &MensajeApp{Cuerpo: fmt.Sprint(i)}})
+	}
+	sinCinco := func(entrada *EntradaHistorial) bool { return entrada.Mensaje.Cuerpo != "5" }
+
+	entradas, siguiente := archivo.Consultar("ana", sinCinco, 0, 2)
+	if obtenido := fmt.Sprint(cuerposEntradas(entradas)); obtenido != "[7 6]" || siguiente == 0 {
+		t.Fatalf("Se esperaba [7 6] y una página siguiente, se obtuvo %s y %d", obtenido, siguiente)
+	}
+	entradas, siguiente = archivo.Consultar("ana", sinCinco, siguiente, 2)
+	if obtenido := fmt.Sprint(cuerposEntradas(entradas)); obtenido != "[4 3]" || siguiente == 0 {
+		t.Fatalf("Se esperaba [4 3] y una página siguiente, se obtuvo %s y %d", obtenido, siguiente)
+	}
+
+	// el archivo olvida el 2 al agregar el 8
+	archivo.Agregar("ana", &EntradaHistorial{Mensaje: &MensajeApp{Cuerpo: "8"}})
+	entradas, siguiente = archivo.Consultar("ana", sinCinco, siguiente, 2)
+	if obtenido := fmt.Sprint(cuerposEntradas(entradas)); obtenido != "[]" || siguiente != 0 {
+		t.Errorf("Se esperaba que no quedaran entradas, se obtuvo %s y %d", obtenido, siguiente)
+	}
+
+	if entradas, _ := archivo.Consultar("beto", sinCinco, 0, 2); len(entradas) != 0 {
+		t.Errorf("Se esperaba un archivo vacío para otro usuario, se obtuvo %s", cuerposEntradas(entradas))
+	}
+}
@@ -9,6 +9,8 @@ package pkg
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
 )
@@ -67,12 +69,22 @@ func (x *Correcto) GetOk() bool {
 	return false
 }
 
+// Cuántos mensajes quiere obtener el usuario en una llamada a ObtenerLimitado.
 type ObtenerConLimite struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
+	// la cantidad máxima de mensajes; 0 para usar el largo de lote del servidor. El servidor
+	// puede devolver menos si supera su propio máximo
 	Largo int32 `protobuf:"varint,1,opt,name=largo,proto3" json:"largo,omitempty"`
+	// el tamaño máximo de la respuesta en bytes, o 0 para no limitarlo. Un mensaje que por sí
+	// solo supera el límite se devuelve igual, solo, para que no bloquee a los siguientes
+	MaximoBytes int32 `protobuf:"varint,2,opt,name=maximoBytes,proto3" json:"maximoBytes,omitempty"`
+	// si la bandeja está vacía, cuánto esperar a que llegue un mensaje antes de responder sin
+	// mensajes. El servidor limita la espera a su propio máximo y deja de esperar si vence el
+	// plazo de la llamada o el cliente la cancela
+	Espera *durationpb.Duration `protobuf:"bytes,3,opt,name=espera,proto3" json:"espera,omitempty"`
 }
 
 func (x *ObtenerConLimite) Reset() {
@@ -114,6 +126,20 @@ func (x *ObtenerConLimite) GetLargo() int32 {
 	return 0
 }
 
+func (x *ObtenerConLimite) GetMaximoBytes() int32 {
+	if x != nil {
+		return x.MaximoBytes
+	}
+	return 0
+}
+
+func (x *ObtenerConLimite) GetEspera() *durationpb.Duration {
+	if x != nil {
+		return x.Espera
+	}
+	return nil
+}
+
 type ListaUsuarios struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -166,7 +192,16 @@ type Registracion struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
+	// el nombre del usuario. Si la conexión usa TLS mutuo puede quedar vacío, ya que el
+	// usuario es el nombre común (CN) del certificado del cliente
 	UsuarioOrigen string `protobuf:"bytes,1,opt,name=usuarioOrigen,proto3" json:"usuarioOrigen,omitempty"`
+	// la contraseña de la cuenta, si el servidor usa cuentas
+	Contrasena string `protobuf:"bytes,2,opt,name=contrasena,proto3" json:"contrasena,omitempty"`
+	// el token de una sesión anterior del usuario que sigue vigente, por ejemplo la de un
+	// cliente que terminó sin desconectarse, para reanudarla en lugar de abrir otra
+	TokenAnterior string `protobuf:"bytes,3,opt,name=tokenAnterior,proto3" json:"tokenAnterior,omitempty"`
+	// si se cierran las demás sesiones del usuario al conectarse
+	CerrarOtras bool `protobuf:"varint,4,opt,name=cerrarOtras,proto3" json:"cerrarOtras,omitempty"`
 }
 
 func (x *Registracion) Reset() {
@@ -208,12 +243,40 @@ func (x *Registracion) GetUsuarioOrigen() string {
 	return ""
 }
 
+func (x *Registracion) GetContrasena() string {
+	if x != nil {
+		return x.Contrasena
+	}
+	return ""
+}
+
+func (x *Registracion) GetTokenAnterior() string {
+	if x != nil {
+		return x.TokenAnterior
+	}
+	return ""
+}
+
+func (x *Registracion) GetCerrarOtras() bool {
+	if x != nil {
+		return x.CerrarOtras
+	}
+	return false
+}
+
 type TokenAutenticacion struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
 	Token string `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	// hasta cuándo vale el token; luego las llamadas que lo usen fallan con UNAUTHENTICATED
+	Vence *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=vence,proto3" json:"vence,omitempty"`
+	// el identificador de la sesión que abrió el token, el que se usa con RevocarSesion
+	Sesion string `protobuf:"bytes,3,opt,name=sesion,proto3" json:"sesion,omitempty"`
+	// cada cuánto debe el cliente dar señales de vida, con Latido o cualquier otra llamada,
+	// para que el servidor no cierre la sesión por inactividad; vacío si no la cierra
+	Latido *durationpb.Duration `protobuf:"bytes,4,opt,name=latido,proto3" json:"latido,omitempty"`
 }
 
 func (x *TokenAutenticacion) Reset() {
@@ -255,6 +318,27 @@ func (x *TokenAutenticacion) GetToken() string {
 	return ""
 }
 
+func (x *TokenAutenticacion) GetVence() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Vence
+	}
+	return nil
+}
+
+func (x *TokenAutenticacion) GetSesion() string {
+	if x != nil {
+		return x.Sesion
+	}
+	return ""
+}
+
+func (x *TokenAutenticacion) GetLatido() *durationpb.Duration {
+	if x != nil {
+		return x.Latido
+	}
+	return nil
+}
+
 type Vacio struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -293,6 +377,128 @@ func (*Vacio) Descriptor() ([]byte, []int) {
 	return file_pkg_mensajero_proto_rawDescGZIP(), []int{5}
 }
 
+// Una sesión abierta de un usuario. Cada Conectar abre una sesión nueva con su propio token.
+type Sesion struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// el identificador de la sesión, que no sirve como token
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// cuándo se abrió y hasta cuándo vale si no se renueva
+	Inicio *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=inicio,proto3" json:"inicio,omitempty"`
+	Vence  *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=vence,proto3" json:"vence,omitempty"`
+	// si es la sesión que hizo la consulta
+	Actual bool `protobuf:"varint,4,opt,name=actual,proto3" json:"actual,omitempty"`
+}
+
+func (x *Sesion) Reset() {
+	*x = Sesion{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_mensajero_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Sesion) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Sesion) ProtoMessage() {}
+
+func (x *Sesion) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_mensajero_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Sesion.ProtoReflect.Descriptor instead.
+func (*Sesion) Descriptor() ([]byte, []int) {
+	return file_pkg_mensajero_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Sesion) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Sesion) GetInicio() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Inicio
+	}
+	return nil
+}
+
+func (x *Sesion) GetVence() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Vence
+	}
+	return nil
+}
+
+func (x *Sesion) GetActual() bool {
+	if x != nil {
+		return x.Actual
+	}
+	return false
+}
+
+type ListaSesiones struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Sesiones []*Sesion `protobuf:"bytes,1,rep,name=sesiones,proto3" json:"sesiones,omitempty"`
+}
+
+func (x *ListaSesiones) Reset() {
+	*x = ListaSesiones{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_mensajero_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListaSesiones) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListaSesiones) ProtoMessage() {}
+
+func (x *ListaSesiones) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_mensajero_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListaSesiones.ProtoReflect.Descriptor instead.
+func (*ListaSesiones) Descriptor() ([]byte, []int) {
+	return file_pkg_mensajero_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ListaSesiones) GetSesiones() []*Sesion {
+	if x != nil {
+		return x.Sesiones
+	}
+	return nil
+}
+
 // TODO: Crear un mensaje denominado MensajeApp que contenga dos cadenas:
 // `usuario` y `cuerpo`.
 type MensajeApp struct {
@@ -302,12 +508,25 @@ type MensajeApp struct {
 
 	Usuario string `protobuf:"bytes,1,opt,name=usuario,proto3" json:"usuario,omitempty"`
 	Cuerpo  string `protobuf:"bytes,2,opt,name=cuerpo,proto3" json:"cuerpo,omitempty"`
+	// identificador único del mensaje
+	Id string `protobuf:"bytes,3,opt,name=id,proto3" json:"id,omitempty"`
+	// cuándo el servidor aceptó el mensaje
+	Fecha *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=fecha,proto3" json:"fecha,omitempty"`
+	// posición del mensaje entre los recibidos por el destinatario: cada mensaje tiene un
+	// número mayor que el anterior, aunque puede haber saltos
+	Secuencia uint64 `protobuf:"varint,5,opt,name=secuencia,proto3" json:"secuencia,omitempty"`
+	// cuántas veces se entregó antes el mensaje sin que el destinatario lo confirmara
+	Reentregas uint32 `protobuf:"varint,6,opt,name=reentregas,proto3" json:"reentregas,omitempty"`
+	// el canal en el que se publicó el mensaje, si no es un mensaje directo
+	Canal string `protobuf:"bytes,7,opt,name=canal,proto3" json:"canal,omitempty"`
+	// si el mensaje es una difusión a todos los usuarios conectados
+	Difusion bool `protobuf:"varint,8,opt,name=difusion,proto3" json:"difusion,omitempty"`
 }
 
 func (x *MensajeApp) Reset() {
 	*x = MensajeApp{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_pkg_mensajero_proto_msgTypes[6]
+		mi := &file_pkg_mensajero_proto_msgTypes[8]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -320,7 +539,7 @@ func (x *MensajeApp) String() string {
 func (*MensajeApp) ProtoMessage() {}
 
 func (x *MensajeApp) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_mensajero_proto_msgTypes[6]
+	mi := &file_pkg_mensajero_proto_msgTypes[8]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -333,7 +552,7 @@ func (x *MensajeApp) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MensajeApp.ProtoReflect.Descriptor instead.
 func (*MensajeApp) Descriptor() ([]byte, []int) {
-	return file_pkg_mensajero_proto_rawDescGZIP(), []int{6}
+	return file_pkg_mensajero_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *MensajeApp) GetUsuario() string {
@@ -350,33 +569,74 @@ func (x *MensajeApp) GetCuerpo() string {
 	return ""
 }
 
-// TODO: Crear un mensaje denominado MensajesApp que contenga una lista repetida
-// de MensajeApp
-type MensajesApp struct {
+func (x *MensajeApp) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *MensajeApp) GetFecha() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Fecha
+	}
+	return nil
+}
+
+func (x *MensajeApp) GetSecuencia() uint64 {
+	if x != nil {
+		return x.Secuencia
+	}
+	return 0
+}
+
+func (x *MensajeApp) GetReentregas() uint32 {
+	if x != nil {
+		return x.Reentregas
+	}
+	return 0
+}
+
+func (x *MensajeApp) GetCanal() string {
+	if x != nil {
+		return x.Canal
+	}
+	return ""
+}
+
+func (x *MensajeApp) GetDifusion() bool {
+	if x != nil {
+		return x.Difusion
+	}
+	return false
+}
+
+// Un canal, identificado por su nombre.
+type Canal struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Mensajes []*MensajeApp `protobuf:"bytes,1,rep,name=mensajes,proto3" json:"mensajes,omitempty"`
+	Nombre string `protobuf:"bytes,1,opt,name=nombre,proto3" json:"nombre,omitempty"`
 }
 
-func (x *MensajesApp) Reset() {
-	*x = MensajesApp{}
+func (x *Canal) Reset() {
+	*x = Canal{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_pkg_mensajero_proto_msgTypes[7]
+		mi := &file_pkg_mensajero_proto_msgTypes[9]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *MensajesApp) String() string {
+func (x *Canal) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*MensajesApp) ProtoMessage() {}
+func (*Canal) ProtoMessage() {}
 
-func (x *MensajesApp) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_mensajero_proto_msgTypes[7]
+func (x *Canal) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_mensajero_proto_msgTypes[9]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -387,118 +647,1050 @@ func (x *MensajesApp) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use MensajesApp.ProtoReflect.Descriptor instead.
-func (*MensajesApp) Descriptor() ([]byte, []int) {
-	return file_pkg_mensajero_proto_rawDescGZIP(), []int{7}
+// Deprecated: Use Canal.ProtoReflect.Descriptor instead.
+func (*Canal) Descriptor() ([]byte, []int) {
+	return file_pkg_mensajero_proto_rawDescGZIP(), []int{9}
 }
 
-func (x *MensajesApp) GetMensajes() []*MensajeApp {
+func (x *Canal) GetNombre() string {
 	if x != nil {
-		return x.Mensajes
+		return x.Nombre
+	}
+	return ""
+}
+
+type ListaCanales struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Canales []string `protobuf:"bytes,1,rep,name=canales,proto3" json:"canales,omitempty"`
+}
+
+func (x *ListaCanales) Reset() {
+	*x = ListaCanales{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_mensajero_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListaCanales) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListaCanales) ProtoMessage() {}
+
+func (x *ListaCanales) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_mensajero_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListaCanales.ProtoReflect.Descriptor instead.
+func (*ListaCanales) Descriptor() ([]byte, []int) {
+	return file_pkg_mensajero_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ListaCanales) GetCanales() []string {
+	if x != nil {
+		return x.Canales
 	}
 	return nil
 }
 
-var File_pkg_mensajero_proto protoreflect.FileDescriptor
+// La respuesta de Publicar y Difundir.
+type RespuestaPublicar struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
 
-var file_pkg_mensajero_proto_rawDesc = []byte{
-	0x0a, 0x13, 0x70, 0x6b, 0x67, 0x2f, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e,
-	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x09, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f,
-	0x22, 0x1a, 0x0a, 0x08, 0x43, 0x6f, 0x72, 0x72, 0x65, 0x63, 0x74, 0x6f, 0x12, 0x0e, 0x0a, 0x02,
-	0x6f, 0x6b, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x02, 0x6f, 0x6b, 0x22, 0x28, 0x0a, 0x10,
-	0x4f, 0x62, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x43, 0x6f, 0x6e, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x65,
-	0x12, 0x14, 0x0a, 0x05, 0x6c, 0x61, 0x72, 0x67, 0x6f, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52,
-	0x05, 0x6c, 0x61, 0x72, 0x67, 0x6f, 0x22, 0x2b, 0x0a, 0x0d, 0x4c, 0x69, 0x73, 0x74, 0x61, 0x55,
-	0x73, 0x75, 0x61, 0x72, 0x69, 0x6f, 0x73, 0x12, 0x1a, 0x0a, 0x08, 0x75, 0x73, 0x75, 0x61, 0x72,
-	0x69, 0x6f, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x08, 0x75, 0x73, 0x75, 0x61, 0x72,
-	0x69, 0x6f, 0x73, 0x22, 0x34, 0x0a, 0x0c, 0x52, 0x65, 0x67, 0x69, 0x73, 0x74, 0x72, 0x61, 0x63,
-	0x69, 0x6f, 0x6e, 0x12, 0x24, 0x0a, 0x0d, 0x75, 0x73, 0x75, 0x61, 0x72, 0x69, 0x6f, 0x4f, 0x72,
-	0x69, 0x67, 0x65, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x75, 0x73, 0x75, 0x61,
-	0x72, 0x69, 0x6f, 0x4f, 0x72, 0x69, 0x67, 0x65, 0x6e, 0x22, 0x2a, 0x0a, 0x12, 0x54, 0x6f, 0x6b,
-	0x65, 0x6e, 0x41, 0x75, 0x74, 0x65, 0x6e, 0x74, 0x69, 0x63, 0x61, 0x63, 0x69, 0x6f, 0x6e, 0x12,
-	0x14, 0x0a, 0x05, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05,
-	0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x22, 0x07, 0x0a, 0x05, 0x56, 0x61, 0x63, 0x69, 0x6f, 0x22, 0x3e,
-	0x0a, 0x0a, 0x4d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x41, 0x70, 0x70, 0x12, 0x18, 0x0a, 0x07,
-	0x75, 0x73, 0x75, 0x61, 0x72, 0x69, 0x6f, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x75,
-	0x73, 0x75, 0x61, 0x72, 0x69, 0x6f, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x75, 0x65, 0x72, 0x70, 0x6f,
-	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x63, 0x75, 0x65, 0x72, 0x70, 0x6f, 0x22, 0x40,
-	0x0a, 0x0b, 0x4d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x73, 0x41, 0x70, 0x70, 0x12, 0x31, 0x0a,
-	0x08, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32,
-	0x15, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x4d, 0x65, 0x6e, 0x73,
-	0x61, 0x6a, 0x65, 0x41, 0x70, 0x70, 0x52, 0x08, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x73,
-	0x32, 0xa6, 0x02, 0x0a, 0x09, 0x4d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x12, 0x42,
-	0x0a, 0x08, 0x43, 0x6f, 0x6e, 0x65, 0x63, 0x74, 0x61, 0x72, 0x12, 0x17, 0x2e, 0x6d, 0x65, 0x6e,
-	0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x52, 0x65, 0x67, 0x69, 0x73, 0x74, 0x72, 0x61, 0x63,
-	0x69, 0x6f, 0x6e, 0x1a, 0x1d, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e,
-	0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x41, 0x75, 0x74, 0x65, 0x6e, 0x74, 0x69, 0x63, 0x61, 0x63, 0x69,
-	0x6f, 0x6e, 0x12, 0x34, 0x0a, 0x06, 0x45, 0x6e, 0x76, 0x69, 0x61, 0x72, 0x12, 0x15, 0x2e, 0x6d,
-	0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x4d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65,
-	0x41, 0x70, 0x70, 0x1a, 0x13, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e,
-	0x43, 0x6f, 0x72, 0x72, 0x65, 0x63, 0x74, 0x6f, 0x12, 0x33, 0x0a, 0x07, 0x4f, 0x62, 0x74, 0x65,
-	0x6e, 0x65, 0x72, 0x12, 0x10, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e,
-	0x56, 0x61, 0x63, 0x69, 0x6f, 0x1a, 0x16, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72,
-	0x6f, 0x2e, 0x4d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x73, 0x41, 0x70, 0x70, 0x12, 0x34, 0x0a,
-	0x06, 0x4c, 0x69, 0x73, 0x74, 0x61, 0x72, 0x12, 0x10, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a,
-	0x65, 0x72, 0x6f, 0x2e, 0x56, 0x61, 0x63, 0x69, 0x6f, 0x1a, 0x18, 0x2e, 0x6d, 0x65, 0x6e, 0x73,
-	0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x61, 0x55, 0x73, 0x75, 0x61, 0x72,
-	0x69, 0x6f, 0x73, 0x12, 0x34, 0x0a, 0x0b, 0x44, 0x65, 0x73, 0x63, 0x6f, 0x6e, 0x65, 0x63, 0x74,
-	0x61, 0x72, 0x12, 0x10, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x56,
-	0x61, 0x63, 0x69, 0x6f, 0x1a, 0x13, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f,
-	0x2e, 0x43, 0x6f, 0x72, 0x72, 0x65, 0x63, 0x74, 0x6f, 0x42, 0x0f, 0x5a, 0x0d, 0x6d, 0x65, 0x6e,
-	0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2f, 0x70, 0x6b, 0x67, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x33,
+	// el identificador y la fecha que el servidor asignó al mensaje, iguales para todos los
+	// destinatarios
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Fecha *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=fecha,proto3" json:"fecha,omitempty"`
+	// cuántos destinatarios recibieron el mensaje en su bandeja
+	Entregados int32 `protobuf:"varint,3,opt,name=entregados,proto3" json:"entregados,omitempty"`
+	// los destinatarios a los que no se les pudo entregar, por ejemplo porque su bandeja
+	// estaba llena
+	NoEntregados []string `protobuf:"bytes,4,rep,name=noEntregados,proto3" json:"noEntregados,omitempty"`
 }
 
-var (
-	file_pkg_mensajero_proto_rawDescOnce sync.Once
-	file_pkg_mensajero_proto_rawDescData = file_pkg_mensajero_proto_rawDesc
-)
+func (x *RespuestaPublicar) Reset() {
+	*x = RespuestaPublicar{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_mensajero_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
 
-func file_pkg_mensajero_proto_rawDescGZIP() []byte {
-	file_pkg_mensajero_proto_rawDescOnce.Do(func() {
-		file_pkg_mensajero_proto_rawDescData = protoimpl.X.CompressGZIP(file_pkg_mensajero_proto_rawDescData)
-	})
-	return file_pkg_mensajero_proto_rawDescData
+func (x *RespuestaPublicar) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-var file_pkg_mensajero_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
-var file_pkg_mensajero_proto_goTypes = []interface{}{
-	(*Correcto)(nil),           // 0: mensajero.Correcto
-	(*ObtenerConLimite)(nil),   // 1: mensajero.ObtenerConLimite
-	(*ListaUsuarios)(nil),      // 2: mensajero.ListaUsuarios
-	(*Registracion)(nil),       // 3: mensajero.Registracion
-	(*TokenAutenticacion)(nil), // 4: mensajero.TokenAutenticacion
-	(*Vacio)(nil),              // 5: mensajero.Vacio
-	(*MensajeApp)(nil),         // 6: mensajero.MensajeApp
-	(*MensajesApp)(nil),        // 7: mensajero.MensajesApp
+func (*RespuestaPublicar) ProtoMessage() {}
+
+func (x *RespuestaPublicar) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_mensajero_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
 }
-var file_pkg_mensajero_proto_depIdxs = []int32{
-	6, // 0: mensajero.MensajesApp.mensajes:type_name -> mensajero.MensajeApp
-	3, // 1: mensajero.Mensajero.Conectar:input_type -> mensajero.Registracion
-	6, // 2: mensajero.Mensajero.Enviar:input_type -> mensajero.MensajeApp
-	5, // 3: mensajero.Mensajero.Obtener:input_type -> mensajero.Vacio
-	5, // 4: mensajero.Mensajero.Listar:input_type -> mensajero.Vacio
-	5, // 5: mensajero.Mensajero.Desconectar:input_type -> mensajero.Vacio
-	4, // 6: mensajero.Mensajero.Conectar:output_type -> mensajero.TokenAutenticacion
-	0, // 7: mensajero.Mensajero.Enviar:output_type -> mensajero.Correcto
-	7, // 8: mensajero.Mensajero.Obtener:output_type -> mensajero.MensajesApp
-	2, // 9: mensajero.Mensajero.Listar:output_type -> mensajero.ListaUsuarios
-	0, // 10: mensajero.Mensajero.Desconectar:output_type -> mensajero.Correcto
-	6, // [6:11] is the sub-list for method output_type
-	1, // [1:6] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+
+// Deprecated: Use RespuestaPublicar.ProtoReflect.Descriptor instead.
+func (*RespuestaPublicar) Descriptor() ([]byte, []int) {
+	return file_pkg_mensajero_proto_rawDescGZIP(), []int{11}
 }
 
-func init() { file_pkg_mensajero_proto_init() }
-func file_pkg_mensajero_proto_init() {
-	if File_pkg_mensajero_proto != nil {
-		return
+func (x *RespuestaPublicar) GetId() string {
+	if x != nil {
+		return x.Id
 	}
-	if !protoimpl.UnsafeEnabled {
-		file_pkg_mensajero_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Correcto); i {
+	return ""
+}
+
+func (x *RespuestaPublicar) GetFecha() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Fecha
+	}
+	return nil
+}
+
+func (x *RespuestaPublicar) GetEntregados() int32 {
+	if x != nil {
+		return x.Entregados
+	}
+	return 0
+}
+
+func (x *RespuestaPublicar) GetNoEntregados() []string {
+	if x != nil {
+		return x.NoEntregados
+	}
+	return nil
+}
+
+// Los mensajes que el usuario confirma haber recibido, por su identificador.
+type Confirmacion struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Ids []string `protobuf:"bytes,1,rep,name=ids,proto3" json:"ids,omitempty"`
+}
+
+func (x *Confirmacion) Reset() {
+	*x = Confirmacion{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_mensajero_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Confirmacion) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Confirmacion) ProtoMessage() {}
+
+func (x *Confirmacion) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_mensajero_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Confirmacion.ProtoReflect.Descriptor instead.
+func (*Confirmacion) Descriptor() ([]byte, []int) {
+	return file_pkg_mensajero_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Confirmacion) GetIds() []string {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+// TODO: Crear un mensaje denominado MensajesApp que contenga una lista repetida
+// de MensajeApp
+type MensajesApp struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Mensajes []*MensajeApp `protobuf:"bytes,1,rep,name=mensajes,proto3" json:"mensajes,omitempty"`
+	// cuántos mensajes quedaron en la bandeja luego de esta entrega
+	Pendientes int32 `protobuf:"varint,2,opt,name=pendientes,proto3" json:"pendientes,omitempty"`
+}
+
+func (x *MensajesApp) Reset() {
+	*x = MensajesApp{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_mensajero_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MensajesApp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MensajesApp) ProtoMessage() {}
+
+func (x *MensajesApp) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_mensajero_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MensajesApp.ProtoReflect.Descriptor instead.
+func (*MensajesApp) Descriptor() ([]byte, []int) {
+	return file_pkg_mensajero_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *MensajesApp) GetMensajes() []*MensajeApp {
+	if x != nil {
+		return x.Mensajes
+	}
+	return nil
+}
+
+func (x *MensajesApp) GetPendientes() int32 {
+	if x != nil {
+		return x.Pendientes
+	}
+	return 0
+}
+
+// La respuesta de Enviar.
+type RespuestaEnviar struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// si el mensaje quedó para ser entregado
+	Ok bool `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	// el identificador que el servidor asignó al mensaje
+	Id string `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	// la fecha y el número de secuencia asignados al mensaje, si quedó para ser entregado
+	Fecha     *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=fecha,proto3" json:"fecha,omitempty"`
+	Secuencia uint64                 `protobuf:"varint,4,opt,name=secuencia,proto3" json:"secuencia,omitempty"`
+}
+
+func (x *RespuestaEnviar) Reset() {
+	*x = RespuestaEnviar{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_mensajero_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RespuestaEnviar) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RespuestaEnviar) ProtoMessage() {}
+
+func (x *RespuestaEnviar) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_mensajero_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RespuestaEnviar.ProtoReflect.Descriptor instead.
+func (*RespuestaEnviar) Descriptor() ([]byte, []int) {
+	return file_pkg_mensajero_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *RespuestaEnviar) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+func (x *RespuestaEnviar) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *RespuestaEnviar) GetFecha() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Fecha
+	}
+	return nil
+}
+
+func (x *RespuestaEnviar) GetSecuencia() uint64 {
+	if x != nil {
+		return x.Secuencia
+	}
+	return 0
+}
+
+// El resultado de un mensaje enviado por el cliente en una conversación. Los resultados
+// llegan en el mismo orden en que el cliente envió los mensajes.
+type ResultadoEnvio struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// el destinatario del mensaje
+	Usuario string `protobuf:"bytes,1,opt,name=usuario,proto3" json:"usuario,omitempty"`
+	// si el mensaje quedó para ser entregado
+	Ok bool `protobuf:"varint,2,opt,name=ok,proto3" json:"ok,omitempty"`
+	// el código de gRPC y la descripción del error, si lo hubo
+	Codigo int32  `protobuf:"varint,3,opt,name=codigo,proto3" json:"codigo,omitempty"`
+	Error  string `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	// el identificador que el servidor asignó al mensaje
+	Id string `protobuf:"bytes,5,opt,name=id,proto3" json:"id,omitempty"`
+	// la razón del error, como en el ErrorInfo de los errores de las demás llamadas
+	Razon string `protobuf:"bytes,6,opt,name=razon,proto3" json:"razon,omitempty"`
+}
+
+func (x *ResultadoEnvio) Reset() {
+	*x = ResultadoEnvio{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_mensajero_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ResultadoEnvio) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResultadoEnvio) ProtoMessage() {}
+
+func (x *ResultadoEnvio) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_mensajero_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResultadoEnvio.ProtoReflect.Descriptor instead.
+func (*ResultadoEnvio) Descriptor() ([]byte, []int) {
+	return file_pkg_mensajero_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ResultadoEnvio) GetUsuario() string {
+	if x != nil {
+		return x.Usuario
+	}
+	return ""
+}
+
+func (x *ResultadoEnvio) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+func (x *ResultadoEnvio) GetCodigo() int32 {
+	if x != nil {
+		return x.Codigo
+	}
+	return 0
+}
+
+func (x *ResultadoEnvio) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *ResultadoEnvio) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ResultadoEnvio) GetRazon() string {
+	if x != nil {
+		return x.Razon
+	}
+	return ""
+}
+
+// Qué parte de su historial quiere ver el usuario con Historial.
+type ConsultaHistorial struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// el otro usuario de una conversación, para ver solo los mensajes directos entre ambos;
+	// vacío para ver todos los mensajes, incluidos los de canales y las difusiones
+	Usuario string `protobuf:"bytes,1,opt,name=usuario,proto3" json:"usuario,omitempty"`
+	// solo los mensajes aceptados desde `desde`, inclusive, y antes de `hasta`; sin valor, el
+	// intervalo no se limita de ese lado
+	Desde *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=desde,proto3" json:"desde,omitempty"`
+	Hasta *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=hasta,proto3" json:"hasta,omitempty"`
+	// la cantidad máxima de mensajes de la página; 0 para usar el largo de lote del servidor.
+	// El servidor puede devolver menos si supera su propio máximo
+	Largo int32 `protobuf:"varint,4,opt,name=largo,proto3" json:"largo,omitempty"`
+	// el cursor devuelto con la página anterior, para seguir desde donde terminó; vacío para
+	// empezar por los mensajes más recientes
+	Cursor string `protobuf:"bytes,5,opt,name=cursor,proto3" json:"cursor,omitempty"`
+}
+
+func (x *ConsultaHistorial) Reset() {
+	*x = ConsultaHistorial{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_mensajero_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ConsultaHistorial) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConsultaHistorial) ProtoMessage() {}
+
+func (x *ConsultaHistorial) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_mensajero_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConsultaHistorial.ProtoReflect.Descriptor instead.
+func (*ConsultaHistorial) Descriptor() ([]byte, []int) {
+	return file_pkg_mensajero_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ConsultaHistorial) GetUsuario() string {
+	if x != nil {
+		return x.Usuario
+	}
+	return ""
+}
+
+func (x *ConsultaHistorial) GetDesde() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Desde
+	}
+	return nil
+}
+
+func (x *ConsultaHistorial) GetHasta() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Hasta
+	}
+	return nil
+}
+
+func (x *ConsultaHistorial) GetLargo() int32 {
+	if x != nil {
+		return x.Largo
+	}
+	return 0
+}
+
+func (x *ConsultaHistorial) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+// Un mensaje del historial de un usuario, enviado o recibido por él.
+type EntradaHistorial struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// el mensaje como lo recibió el destinatario; `usuario` es el remitente
+	Mensaje *MensajeApp `protobuf:"bytes,1,opt,name=mensaje,proto3" json:"mensaje,omitempty"`
+	// el destinatario del mensaje, o vacío si el usuario lo publicó en un canal o lo difundió
+	Destinatario string `protobuf:"bytes,2,opt,name=destinatario,proto3" json:"destinatario,omitempty"`
+}
+
+func (x *EntradaHistorial) Reset() {
+	*x = EntradaHistorial{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_mensajero_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *EntradaHistorial) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EntradaHistorial) ProtoMessage() {}
+
+func (x *EntradaHistorial) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_mensajero_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EntradaHistorial.ProtoReflect.Descriptor instead.
+func (*EntradaHistorial) Descriptor() ([]byte, []int) {
+	return file_pkg_mensajero_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *EntradaHistorial) GetMensaje() *MensajeApp {
+	if x != nil {
+		return x.Mensaje
+	}
+	return nil
+}
+
+func (x *EntradaHistorial) GetDestinatario() string {
+	if x != nil {
+		return x.Destinatario
+	}
+	return ""
+}
+
+// Una página del historial.
+type PaginaHistorial struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// los mensajes, del más reciente al más antiguo
+	Entradas []*EntradaHistorial `protobuf:"bytes,1,rep,name=entradas,proto3" json:"entradas,omitempty"`
+	// el cursor para pedir la página siguiente, con mensajes más antiguos; vacío si no hay más
+	Cursor string `protobuf:"bytes,2,opt,name=cursor,proto3" json:"cursor,omitempty"`
+}
+
+func (x *PaginaHistorial) Reset() {
+	*x = PaginaHistorial{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_mensajero_proto_msgTypes[18]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PaginaHistorial) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PaginaHistorial) ProtoMessage() {}
+
+func (x *PaginaHistorial) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_mensajero_proto_msgTypes[18]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PaginaHistorial.ProtoReflect.Descriptor instead.
+func (*PaginaHistorial) Descriptor() ([]byte, []int) {
+	return file_pkg_mensajero_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *PaginaHistorial) GetEntradas() []*EntradaHistorial {
+	if x != nil {
+		return x.Entradas
+	}
+	return nil
+}
+
+func (x *PaginaHistorial) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+// Lo que el servidor envía al cliente en una conversación.
+type EventoConversacion struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Evento:
+	//	*EventoConversacion_Mensaje
+	//	*EventoConversacion_Resultado
+	Evento isEventoConversacion_Evento `protobuf_oneof:"evento"`
+}
+
+func (x *EventoConversacion) Reset() {
+	*x = EventoConversacion{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_mensajero_proto_msgTypes[19]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *EventoConversacion) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EventoConversacion) ProtoMessage() {}
+
+func (x *EventoConversacion) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_mensajero_proto_msgTypes[19]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EventoConversacion.ProtoReflect.Descriptor instead.
+func (*EventoConversacion) Descriptor() ([]byte, []int) {
+	return file_pkg_mensajero_proto_rawDescGZIP(), []int{19}
+}
+
+func (m *EventoConversacion) GetEvento() isEventoConversacion_Evento {
+	if m != nil {
+		return m.Evento
+	}
+	return nil
+}
+
+func (x *EventoConversacion) GetMensaje() *MensajeApp {
+	if x, ok := x.GetEvento().(*EventoConversacion_Mensaje); ok {
+		return x.Mensaje
+	}
+	return nil
+}
+
+func (x *EventoConversacion) GetResultado() *ResultadoEnvio {
+	if x, ok := x.GetEvento().(*EventoConversacion_Resultado); ok {
+		return x.Resultado
+	}
+	return nil
+}
+
+type isEventoConversacion_Evento interface {
+	isEventoConversacion_Evento()
+}
+
+type EventoConversacion_Mensaje struct {
+	// un mensaje recibido; `usuario` es el remitente
+	Mensaje *MensajeApp `protobuf:"bytes,1,opt,name=mensaje,proto3,oneof"`
+}
+
+type EventoConversacion_Resultado struct {
+	// el resultado de un mensaje enviado por el cliente
+	Resultado *ResultadoEnvio `protobuf:"bytes,2,opt,name=resultado,proto3,oneof"`
+}
+
+func (*EventoConversacion_Mensaje) isEventoConversacion_Evento() {}
+
+func (*EventoConversacion_Resultado) isEventoConversacion_Evento() {}
+
+var File_pkg_mensajero_proto protoreflect.FileDescriptor
+
+var file_pkg_mensajero_proto_rawDesc = []byte{
+	0x0a, 0x13, 0x70, 0x6b, 0x67, 0x2f, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x09, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f,
+	0x1a, 0x1e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75,
+	0x66, 0x2f, 0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x1a, 0x1f, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75,
+	0x66, 0x2f, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x22, 0x1a, 0x0a, 0x08, 0x43, 0x6f, 0x72, 0x72, 0x65, 0x63, 0x74, 0x6f, 0x12, 0x0e, 0x0a,
+	0x02, 0x6f, 0x6b, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x02, 0x6f, 0x6b, 0x22, 0x7d, 0x0a,
+	0x10, 0x4f, 0x62, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x43, 0x6f, 0x6e, 0x4c, 0x69, 0x6d, 0x69, 0x74,
+	0x65, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x61, 0x72, 0x67, 0x6f, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x05, 0x6c, 0x61, 0x72, 0x67, 0x6f, 0x12, 0x20, 0x0a, 0x0b, 0x6d, 0x61, 0x78, 0x69, 0x6d,
+	0x6f, 0x42, 0x79, 0x74, 0x65, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0b, 0x6d, 0x61,
+	0x78, 0x69, 0x6d, 0x6f, 0x42, 0x79, 0x74, 0x65, 0x73, 0x12, 0x31, 0x0a, 0x06, 0x65, 0x73, 0x70,
+	0x65, 0x72, 0x61, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x67, 0x6f, 0x6f, 0x67,
+	0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x44, 0x75, 0x72, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x52, 0x06, 0x65, 0x73, 0x70, 0x65, 0x72, 0x61, 0x22, 0x2b, 0x0a, 0x0d,
+	0x4c, 0x69, 0x73, 0x74, 0x61, 0x55, 0x73, 0x75, 0x61, 0x72, 0x69, 0x6f, 0x73, 0x12, 0x1a, 0x0a,
+	0x08, 0x75, 0x73, 0x75, 0x61, 0x72, 0x69, 0x6f, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52,
+	0x08, 0x75, 0x73, 0x75, 0x61, 0x72, 0x69, 0x6f, 0x73, 0x22, 0x9c, 0x01, 0x0a, 0x0c, 0x52, 0x65,
+	0x67, 0x69, 0x73, 0x74, 0x72, 0x61, 0x63, 0x69, 0x6f, 0x6e, 0x12, 0x24, 0x0a, 0x0d, 0x75, 0x73,
+	0x75, 0x61, 0x72, 0x69, 0x6f, 0x4f, 0x72, 0x69, 0x67, 0x65, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0d, 0x75, 0x73, 0x75, 0x61, 0x72, 0x69, 0x6f, 0x4f, 0x72, 0x69, 0x67, 0x65, 0x6e,
+	0x12, 0x1e, 0x0a, 0x0a, 0x63, 0x6f, 0x6e, 0x74, 0x72, 0x61, 0x73, 0x65, 0x6e, 0x61, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x63, 0x6f, 0x6e, 0x74, 0x72, 0x61, 0x73, 0x65, 0x6e, 0x61,
+	0x12, 0x24, 0x0a, 0x0d, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x41, 0x6e, 0x74, 0x65, 0x72, 0x69, 0x6f,
+	0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x41, 0x6e,
+	0x74, 0x65, 0x72, 0x69, 0x6f, 0x72, 0x12, 0x20, 0x0a, 0x0b, 0x63, 0x65, 0x72, 0x72, 0x61, 0x72,
+	0x4f, 0x74, 0x72, 0x61, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0b, 0x63, 0x65, 0x72,
+	0x72, 0x61, 0x72, 0x4f, 0x74, 0x72, 0x61, 0x73, 0x22, 0xa7, 0x01, 0x0a, 0x12, 0x54, 0x6f, 0x6b,
+	0x65, 0x6e, 0x41, 0x75, 0x74, 0x65, 0x6e, 0x74, 0x69, 0x63, 0x61, 0x63, 0x69, 0x6f, 0x6e, 0x12,
+	0x14, 0x0a, 0x05, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05,
+	0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x12, 0x30, 0x0a, 0x05, 0x76, 0x65, 0x6e, 0x63, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70,
+	0x52, 0x05, 0x76, 0x65, 0x6e, 0x63, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x65, 0x73, 0x69, 0x6f,
+	0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x65, 0x73, 0x69, 0x6f, 0x6e, 0x12,
+	0x31, 0x0a, 0x06, 0x6c, 0x61, 0x74, 0x69, 0x64, 0x6f, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x19, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75,
+	0x66, 0x2e, 0x44, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x06, 0x6c, 0x61, 0x74, 0x69,
+	0x64, 0x6f, 0x22, 0x07, 0x0a, 0x05, 0x56, 0x61, 0x63, 0x69, 0x6f, 0x22, 0x96, 0x01, 0x0a, 0x06,
+	0x53, 0x65, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x32, 0x0a, 0x06, 0x69, 0x6e, 0x69, 0x63, 0x69, 0x6f,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61,
+	0x6d, 0x70, 0x52, 0x06, 0x69, 0x6e, 0x69, 0x63, 0x69, 0x6f, 0x12, 0x30, 0x0a, 0x05, 0x76, 0x65,
+	0x6e, 0x63, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67,
+	0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65,
+	0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x05, 0x76, 0x65, 0x6e, 0x63, 0x65, 0x12, 0x16, 0x0a, 0x06,
+	0x61, 0x63, 0x74, 0x75, 0x61, 0x6c, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x61, 0x63,
+	0x74, 0x75, 0x61, 0x6c, 0x22, 0x3e, 0x0a, 0x0d, 0x4c, 0x69, 0x73, 0x74, 0x61, 0x53, 0x65, 0x73,
+	0x69, 0x6f, 0x6e, 0x65, 0x73, 0x12, 0x2d, 0x0a, 0x08, 0x73, 0x65, 0x73, 0x69, 0x6f, 0x6e, 0x65,
+	0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a,
+	0x65, 0x72, 0x6f, 0x2e, 0x53, 0x65, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x08, 0x73, 0x65, 0x73, 0x69,
+	0x6f, 0x6e, 0x65, 0x73, 0x22, 0xf0, 0x01, 0x0a, 0x0a, 0x4d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65,
+	0x41, 0x70, 0x70, 0x12, 0x18, 0x0a, 0x07, 0x75, 0x73, 0x75, 0x61, 0x72, 0x69, 0x6f, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x75, 0x73, 0x75, 0x61, 0x72, 0x69, 0x6f, 0x12, 0x16, 0x0a,
+	0x06, 0x63, 0x75, 0x65, 0x72, 0x70, 0x6f, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x63,
+	0x75, 0x65, 0x72, 0x70, 0x6f, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x30, 0x0a, 0x05, 0x66, 0x65, 0x63, 0x68, 0x61, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70,
+	0x52, 0x05, 0x66, 0x65, 0x63, 0x68, 0x61, 0x12, 0x1c, 0x0a, 0x09, 0x73, 0x65, 0x63, 0x75, 0x65,
+	0x6e, 0x63, 0x69, 0x61, 0x18, 0x05, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x73, 0x65, 0x63, 0x75,
+	0x65, 0x6e, 0x63, 0x69, 0x61, 0x12, 0x1e, 0x0a, 0x0a, 0x72, 0x65, 0x65, 0x6e, 0x74, 0x72, 0x65,
+	0x67, 0x61, 0x73, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0a, 0x72, 0x65, 0x65, 0x6e, 0x74,
+	0x72, 0x65, 0x67, 0x61, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x61, 0x6e, 0x61, 0x6c, 0x18, 0x07,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x63, 0x61, 0x6e, 0x61, 0x6c, 0x12, 0x1a, 0x0a, 0x08, 0x64,
+	0x69, 0x66, 0x75, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x08, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x64,
+	0x69, 0x66, 0x75, 0x73, 0x69, 0x6f, 0x6e, 0x22, 0x1f, 0x0a, 0x05, 0x43, 0x61, 0x6e, 0x61, 0x6c,
+	0x12, 0x16, 0x0a, 0x06, 0x6e, 0x6f, 0x6d, 0x62, 0x72, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x06, 0x6e, 0x6f, 0x6d, 0x62, 0x72, 0x65, 0x22, 0x28, 0x0a, 0x0c, 0x4c, 0x69, 0x73, 0x74,
+	0x61, 0x43, 0x61, 0x6e, 0x61, 0x6c, 0x65, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x61, 0x6e, 0x61,
+	0x6c, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x07, 0x63, 0x61, 0x6e, 0x61, 0x6c,
+	0x65, 0x73, 0x22, 0x99, 0x01, 0x0a, 0x11, 0x52, 0x65, 0x73, 0x70, 0x75, 0x65, 0x73, 0x74, 0x61,
+	0x50, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x61, 0x72, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x30, 0x0a, 0x05, 0x66, 0x65, 0x63, 0x68,
+	0x61, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74,
+	0x61, 0x6d, 0x70, 0x52, 0x05, 0x66, 0x65, 0x63, 0x68, 0x61, 0x12, 0x1e, 0x0a, 0x0a, 0x65, 0x6e,
+	0x74, 0x72, 0x65, 0x67, 0x61, 0x64, 0x6f, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a,
+	0x65, 0x6e, 0x74, 0x72, 0x65, 0x67, 0x61, 0x64, 0x6f, 0x73, 0x12, 0x22, 0x0a, 0x0c, 0x6e, 0x6f,
+	0x45, 0x6e, 0x74, 0x72, 0x65, 0x67, 0x61, 0x64, 0x6f, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x09,
+	0x52, 0x0c, 0x6e, 0x6f, 0x45, 0x6e, 0x74, 0x72, 0x65, 0x67, 0x61, 0x64, 0x6f, 0x73, 0x22, 0x20,
+	0x0a, 0x0c, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x72, 0x6d, 0x61, 0x63, 0x69, 0x6f, 0x6e, 0x12, 0x10,
+	0x0a, 0x03, 0x69, 0x64, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x03, 0x69, 0x64, 0x73,
+	0x22, 0x60, 0x0a, 0x0b, 0x4d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x73, 0x41, 0x70, 0x70, 0x12,
+	0x31, 0x0a, 0x08, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x15, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x4d, 0x65,
+	0x6e, 0x73, 0x61, 0x6a, 0x65, 0x41, 0x70, 0x70, 0x52, 0x08, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a,
+	0x65, 0x73, 0x12, 0x1e, 0x0a, 0x0a, 0x70, 0x65, 0x6e, 0x64, 0x69, 0x65, 0x6e, 0x74, 0x65, 0x73,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a, 0x70, 0x65, 0x6e, 0x64, 0x69, 0x65, 0x6e, 0x74,
+	0x65, 0x73, 0x22, 0x81, 0x01, 0x0a, 0x0f, 0x52, 0x65, 0x73, 0x70, 0x75, 0x65, 0x73, 0x74, 0x61,
+	0x45, 0x6e, 0x76, 0x69, 0x61, 0x72, 0x12, 0x0e, 0x0a, 0x02, 0x6f, 0x6b, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x02, 0x6f, 0x6b, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x30, 0x0a, 0x05, 0x66, 0x65, 0x63, 0x68, 0x61, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d,
+	0x70, 0x52, 0x05, 0x66, 0x65, 0x63, 0x68, 0x61, 0x12, 0x1c, 0x0a, 0x09, 0x73, 0x65, 0x63, 0x75,
+	0x65, 0x6e, 0x63, 0x69, 0x61, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x73, 0x65, 0x63,
+	0x75, 0x65, 0x6e, 0x63, 0x69, 0x61, 0x22, 0x8e, 0x01, 0x0a, 0x0e, 0x52, 0x65, 0x73, 0x75, 0x6c,
+	0x74, 0x61, 0x64, 0x6f, 0x45, 0x6e, 0x76, 0x69, 0x6f, 0x12, 0x18, 0x0a, 0x07, 0x75, 0x73, 0x75,
+	0x61, 0x72, 0x69, 0x6f, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x75, 0x73, 0x75, 0x61,
+	0x72, 0x69, 0x6f, 0x12, 0x0e, 0x0a, 0x02, 0x6f, 0x6b, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x02, 0x6f, 0x6b, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x6f, 0x64, 0x69, 0x67, 0x6f, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x06, 0x63, 0x6f, 0x64, 0x69, 0x67, 0x6f, 0x12, 0x14, 0x0a, 0x05, 0x65,
+	0x72, 0x72, 0x6f, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f,
+	0x72, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69,
+	0x64, 0x12, 0x14, 0x0a, 0x05, 0x72, 0x61, 0x7a, 0x6f, 0x6e, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x05, 0x72, 0x61, 0x7a, 0x6f, 0x6e, 0x22, 0xbf, 0x01, 0x0a, 0x11, 0x43, 0x6f, 0x6e, 0x73,
+	0x75, 0x6c, 0x74, 0x61, 0x48, 0x69, 0x73, 0x74, 0x6f, 0x72, 0x69, 0x61, 0x6c, 0x12, 0x18, 0x0a,
+	0x07, 0x75, 0x73, 0x75, 0x61, 0x72, 0x69, 0x6f, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07,
+	0x75, 0x73, 0x75, 0x61, 0x72, 0x69, 0x6f, 0x12, 0x30, 0x0a, 0x05, 0x64, 0x65, 0x73, 0x64, 0x65,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61,
+	0x6d, 0x70, 0x52, 0x05, 0x64, 0x65, 0x73, 0x64, 0x65, 0x12, 0x30, 0x0a, 0x05, 0x68, 0x61, 0x73,
+	0x74, 0x61, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c,
+	0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73,
+	0x74, 0x61, 0x6d, 0x70, 0x52, 0x05, 0x68, 0x61, 0x73, 0x74, 0x61, 0x12, 0x14, 0x0a, 0x05, 0x6c,
+	0x61, 0x72, 0x67, 0x6f, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x6c, 0x61, 0x72, 0x67,
+	0x6f, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x06, 0x63, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x22, 0x67, 0x0a, 0x10, 0x45, 0x6e, 0x74,
+	0x72, 0x61, 0x64, 0x61, 0x48, 0x69, 0x73, 0x74, 0x6f, 0x72, 0x69, 0x61, 0x6c, 0x12, 0x2f, 0x0a,
+	0x07, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x15,
+	0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x4d, 0x65, 0x6e, 0x73, 0x61,
+	0x6a, 0x65, 0x41, 0x70, 0x70, 0x52, 0x07, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x12, 0x22,
+	0x0a, 0x0c, 0x64, 0x65, 0x73, 0x74, 0x69, 0x6e, 0x61, 0x74, 0x61, 0x72, 0x69, 0x6f, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x64, 0x65, 0x73, 0x74, 0x69, 0x6e, 0x61, 0x74, 0x61, 0x72,
+	0x69, 0x6f, 0x22, 0x62, 0x0a, 0x0f, 0x50, 0x61, 0x67, 0x69, 0x6e, 0x61, 0x48, 0x69, 0x73, 0x74,
+	0x6f, 0x72, 0x69, 0x61, 0x6c, 0x12, 0x37, 0x0a, 0x08, 0x65, 0x6e, 0x74, 0x72, 0x61, 0x64, 0x61,
+	0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a,
+	0x65, 0x72, 0x6f, 0x2e, 0x45, 0x6e, 0x74, 0x72, 0x61, 0x64, 0x61, 0x48, 0x69, 0x73, 0x74, 0x6f,
+	0x72, 0x69, 0x61, 0x6c, 0x52, 0x08, 0x65, 0x6e, 0x74, 0x72, 0x61, 0x64, 0x61, 0x73, 0x12, 0x16,
+	0x0a, 0x06, 0x63, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06,
+	0x63, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x22, 0x8c, 0x01, 0x0a, 0x12, 0x45, 0x76, 0x65, 0x6e, 0x74,
+	0x6f, 0x43, 0x6f, 0x6e, 0x76, 0x65, 0x72, 0x73, 0x61, 0x63, 0x69, 0x6f, 0x6e, 0x12, 0x31, 0x0a,
+	0x07, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x15,
+	0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x4d, 0x65, 0x6e, 0x73, 0x61,
+	0x6a, 0x65, 0x41, 0x70, 0x70, 0x48, 0x00, 0x52, 0x07, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65,
+	0x12, 0x39, 0x0a, 0x09, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x61, 0x64, 0x6f, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e,
+	0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x61, 0x64, 0x6f, 0x45, 0x6e, 0x76, 0x69, 0x6f, 0x48, 0x00,
+	0x52, 0x09, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x61, 0x64, 0x6f, 0x42, 0x08, 0x0a, 0x06, 0x65,
+	0x76, 0x65, 0x6e, 0x74, 0x6f, 0x32, 0xb7, 0x0a, 0x0a, 0x09, 0x4d, 0x65, 0x6e, 0x73, 0x61, 0x6a,
+	0x65, 0x72, 0x6f, 0x12, 0x42, 0x0a, 0x08, 0x43, 0x6f, 0x6e, 0x65, 0x63, 0x74, 0x61, 0x72, 0x12,
+	0x17, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x52, 0x65, 0x67, 0x69,
+	0x73, 0x74, 0x72, 0x61, 0x63, 0x69, 0x6f, 0x6e, 0x1a, 0x1d, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61,
+	0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x41, 0x75, 0x74, 0x65, 0x6e, 0x74,
+	0x69, 0x63, 0x61, 0x63, 0x69, 0x6f, 0x6e, 0x12, 0x3b, 0x0a, 0x0b, 0x43, 0x72, 0x65, 0x61, 0x72,
+	0x43, 0x75, 0x65, 0x6e, 0x74, 0x61, 0x12, 0x17, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65,
+	0x72, 0x6f, 0x2e, 0x52, 0x65, 0x67, 0x69, 0x73, 0x74, 0x72, 0x61, 0x63, 0x69, 0x6f, 0x6e, 0x1a,
+	0x13, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x43, 0x6f, 0x72, 0x72,
+	0x65, 0x63, 0x74, 0x6f, 0x12, 0x3a, 0x0a, 0x07, 0x52, 0x65, 0x6e, 0x6f, 0x76, 0x61, 0x72, 0x12,
+	0x10, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x56, 0x61, 0x63, 0x69,
+	0x6f, 0x1a, 0x1d, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x54, 0x6f,
+	0x6b, 0x65, 0x6e, 0x41, 0x75, 0x74, 0x65, 0x6e, 0x74, 0x69, 0x63, 0x61, 0x63, 0x69, 0x6f, 0x6e,
+	0x12, 0x2f, 0x0a, 0x06, 0x4c, 0x61, 0x74, 0x69, 0x64, 0x6f, 0x12, 0x10, 0x2e, 0x6d, 0x65, 0x6e,
+	0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x56, 0x61, 0x63, 0x69, 0x6f, 0x1a, 0x13, 0x2e, 0x6d,
+	0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x43, 0x6f, 0x72, 0x72, 0x65, 0x63, 0x74,
+	0x6f, 0x12, 0x3b, 0x0a, 0x06, 0x45, 0x6e, 0x76, 0x69, 0x61, 0x72, 0x12, 0x15, 0x2e, 0x6d, 0x65,
+	0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x4d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x41,
+	0x70, 0x70, 0x1a, 0x1a, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x52,
+	0x65, 0x73, 0x70, 0x75, 0x65, 0x73, 0x74, 0x61, 0x45, 0x6e, 0x76, 0x69, 0x61, 0x72, 0x12, 0x33,
+	0x0a, 0x07, 0x4f, 0x62, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x12, 0x10, 0x2e, 0x6d, 0x65, 0x6e, 0x73,
+	0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x56, 0x61, 0x63, 0x69, 0x6f, 0x1a, 0x16, 0x2e, 0x6d, 0x65,
+	0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x4d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x73,
+	0x41, 0x70, 0x70, 0x12, 0x46, 0x0a, 0x0f, 0x4f, 0x62, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x4c, 0x69,
+	0x6d, 0x69, 0x74, 0x61, 0x64, 0x6f, 0x12, 0x1b, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65,
+	0x72, 0x6f, 0x2e, 0x4f, 0x62, 0x74, 0x65, 0x6e, 0x65, 0x72, 0x43, 0x6f, 0x6e, 0x4c, 0x69, 0x6d,
+	0x69, 0x74, 0x65, 0x1a, 0x16, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e,
+	0x4d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x73, 0x41, 0x70, 0x70, 0x12, 0x39, 0x0a, 0x09, 0x43,
+	0x6f, 0x6e, 0x66, 0x69, 0x72, 0x6d, 0x61, 0x72, 0x12, 0x17, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61,
+	0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x72, 0x6d, 0x61, 0x63, 0x69, 0x6f,
+	0x6e, 0x1a, 0x13, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x43, 0x6f,
+	0x72, 0x72, 0x65, 0x63, 0x74, 0x6f, 0x12, 0x45, 0x0a, 0x09, 0x43, 0x6f, 0x6e, 0x76, 0x65, 0x72,
+	0x73, 0x61, 0x72, 0x12, 0x15, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e,
+	0x4d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x41, 0x70, 0x70, 0x1a, 0x1d, 0x2e, 0x6d, 0x65, 0x6e,
+	0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x6f, 0x43, 0x6f, 0x6e,
+	0x76, 0x65, 0x72, 0x73, 0x61, 0x63, 0x69, 0x6f, 0x6e, 0x28, 0x01, 0x30, 0x01, 0x12, 0x36, 0x0a,
+	0x09, 0x53, 0x75, 0x73, 0x63, 0x72, 0x69, 0x62, 0x69, 0x72, 0x12, 0x10, 0x2e, 0x6d, 0x65, 0x6e,
+	0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x56, 0x61, 0x63, 0x69, 0x6f, 0x1a, 0x15, 0x2e, 0x6d,
+	0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x4d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65,
+	0x41, 0x70, 0x70, 0x30, 0x01, 0x12, 0x33, 0x0a, 0x0a, 0x43, 0x72, 0x65, 0x61, 0x72, 0x43, 0x61,
+	0x6e, 0x61, 0x6c, 0x12, 0x10, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e,
+	0x43, 0x61, 0x6e, 0x61, 0x6c, 0x1a, 0x13, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72,
+	0x6f, 0x2e, 0x43, 0x6f, 0x72, 0x72, 0x65, 0x63, 0x74, 0x6f, 0x12, 0x34, 0x0a, 0x0b, 0x55, 0x6e,
+	0x69, 0x72, 0x73, 0x65, 0x43, 0x61, 0x6e, 0x61, 0x6c, 0x12, 0x10, 0x2e, 0x6d, 0x65, 0x6e, 0x73,
+	0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x43, 0x61, 0x6e, 0x61, 0x6c, 0x1a, 0x13, 0x2e, 0x6d, 0x65,
+	0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x43, 0x6f, 0x72, 0x72, 0x65, 0x63, 0x74, 0x6f,
+	0x12, 0x37, 0x0a, 0x0e, 0x41, 0x62, 0x61, 0x6e, 0x64, 0x6f, 0x6e, 0x61, 0x72, 0x43, 0x61, 0x6e,
+	0x61, 0x6c, 0x12, 0x10, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x43,
+	0x61, 0x6e, 0x61, 0x6c, 0x1a, 0x13, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f,
+	0x2e, 0x43, 0x6f, 0x72, 0x72, 0x65, 0x63, 0x74, 0x6f, 0x12, 0x3a, 0x0a, 0x0d, 0x4c, 0x69, 0x73,
+	0x74, 0x61, 0x72, 0x43, 0x61, 0x6e, 0x61, 0x6c, 0x65, 0x73, 0x12, 0x10, 0x2e, 0x6d, 0x65, 0x6e,
+	0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x56, 0x61, 0x63, 0x69, 0x6f, 0x1a, 0x17, 0x2e, 0x6d,
+	0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x61, 0x43, 0x61,
+	0x6e, 0x61, 0x6c, 0x65, 0x73, 0x12, 0x3c, 0x0a, 0x0e, 0x4c, 0x69, 0x73, 0x74, 0x61, 0x72, 0x4d,
+	0x69, 0x65, 0x6d, 0x62, 0x72, 0x6f, 0x73, 0x12, 0x10, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a,
+	0x65, 0x72, 0x6f, 0x2e, 0x43, 0x61, 0x6e, 0x61, 0x6c, 0x1a, 0x18, 0x2e, 0x6d, 0x65, 0x6e, 0x73,
+	0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x61, 0x55, 0x73, 0x75, 0x61, 0x72,
+	0x69, 0x6f, 0x73, 0x12, 0x3f, 0x0a, 0x08, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x63, 0x61, 0x72, 0x12,
+	0x15, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x4d, 0x65, 0x6e, 0x73,
+	0x61, 0x6a, 0x65, 0x41, 0x70, 0x70, 0x1a, 0x1c, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65,
+	0x72, 0x6f, 0x2e, 0x52, 0x65, 0x73, 0x70, 0x75, 0x65, 0x73, 0x74, 0x61, 0x50, 0x75, 0x62, 0x6c,
+	0x69, 0x63, 0x61, 0x72, 0x12, 0x3f, 0x0a, 0x08, 0x44, 0x69, 0x66, 0x75, 0x6e, 0x64, 0x69, 0x72,
+	0x12, 0x15, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x4d, 0x65, 0x6e,
+	0x73, 0x61, 0x6a, 0x65, 0x41, 0x70, 0x70, 0x1a, 0x1c, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a,
+	0x65, 0x72, 0x6f, 0x2e, 0x52, 0x65, 0x73, 0x70, 0x75, 0x65, 0x73, 0x74, 0x61, 0x50, 0x75, 0x62,
+	0x6c, 0x69, 0x63, 0x61, 0x72, 0x12, 0x45, 0x0a, 0x09, 0x48, 0x69, 0x73, 0x74, 0x6f, 0x72, 0x69,
+	0x61, 0x6c, 0x12, 0x1c, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x43,
+	0x6f, 0x6e, 0x73, 0x75, 0x6c, 0x74, 0x61, 0x48, 0x69, 0x73, 0x74, 0x6f, 0x72, 0x69, 0x61, 0x6c,
+	0x1a, 0x1a, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x50, 0x61, 0x67,
+	0x69, 0x6e, 0x61, 0x48, 0x69, 0x73, 0x74, 0x6f, 0x72, 0x69, 0x61, 0x6c, 0x12, 0x3c, 0x0a, 0x0e,
+	0x4c, 0x69, 0x73, 0x74, 0x61, 0x72, 0x53, 0x65, 0x73, 0x69, 0x6f, 0x6e, 0x65, 0x73, 0x12, 0x10,
+	0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x56, 0x61, 0x63, 0x69, 0x6f,
+	0x1a, 0x18, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x4c, 0x69, 0x73,
+	0x74, 0x61, 0x53, 0x65, 0x73, 0x69, 0x6f, 0x6e, 0x65, 0x73, 0x12, 0x37, 0x0a, 0x0d, 0x52, 0x65,
+	0x76, 0x6f, 0x63, 0x61, 0x72, 0x53, 0x65, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x11, 0x2e, 0x6d, 0x65,
+	0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x53, 0x65, 0x73, 0x69, 0x6f, 0x6e, 0x1a, 0x13,
+	0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x43, 0x6f, 0x72, 0x72, 0x65,
+	0x63, 0x74, 0x6f, 0x12, 0x34, 0x0a, 0x06, 0x4c, 0x69, 0x73, 0x74, 0x61, 0x72, 0x12, 0x10, 0x2e,
+	0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x56, 0x61, 0x63, 0x69, 0x6f, 0x1a,
+	0x18, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x4c, 0x69, 0x73, 0x74,
+	0x61, 0x55, 0x73, 0x75, 0x61, 0x72, 0x69, 0x6f, 0x73, 0x12, 0x34, 0x0a, 0x0b, 0x44, 0x65, 0x73,
+	0x63, 0x6f, 0x6e, 0x65, 0x63, 0x74, 0x61, 0x72, 0x12, 0x10, 0x2e, 0x6d, 0x65, 0x6e, 0x73, 0x61,
+	0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x56, 0x61, 0x63, 0x69, 0x6f, 0x1a, 0x13, 0x2e, 0x6d, 0x65, 0x6e,
+	0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2e, 0x43, 0x6f, 0x72, 0x72, 0x65, 0x63, 0x74, 0x6f, 0x42,
+	0x0f, 0x5a, 0x0d, 0x6d, 0x65, 0x6e, 0x73, 0x61, 0x6a, 0x65, 0x72, 0x6f, 0x2f, 0x70, 0x6b, 0x67,
+	0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_pkg_mensajero_proto_rawDescOnce sync.Once
+	file_pkg_mensajero_proto_rawDescData = file_pkg_mensajero_proto_rawDesc
+)
+
+func file_pkg_mensajero_proto_rawDescGZIP() []byte {
+	file_pkg_mensajero_proto_rawDescOnce.Do(func() {
+		file_pkg_mensajero_proto_rawDescData = protoimpl.X.CompressGZIP(file_pkg_mensajero_proto_rawDescData)
+	})
+	return file_pkg_mensajero_proto_rawDescData
+}
+
+var file_pkg_mensajero_proto_msgTypes = make([]protoimpl.MessageInfo, 20)
+var file_pkg_mensajero_proto_goTypes = []interface{}{
+	(*Correcto)(nil),              // 0: mensajero.Correcto
+	(*ObtenerConLimite)(nil),      // 1: mensajero.ObtenerConLimite
+	(*ListaUsuarios)(nil),         // 2: mensajero.ListaUsuarios
+	(*Registracion)(nil),          // 3: mensajero.Registracion
+	(*TokenAutenticacion)(nil),    // 4: mensajero.TokenAutenticacion
+	(*Vacio)(nil),                 // 5: mensajero.Vacio
+	(*Sesion)(nil),                // 6: mensajero.Sesion
+	(*ListaSesiones)(nil),         // 7: mensajero.ListaSesiones
+	(*MensajeApp)(nil),            // 8: mensajero.MensajeApp
+	(*Canal)(nil),                 // 9: mensajero.Canal
+	(*ListaCanales)(nil),          // 10: mensajero.ListaCanales
+	(*RespuestaPublicar)(nil),     // 11: mensajero.RespuestaPublicar
+	(*Confirmacion)(nil),          // 12: mensajero.Confirmacion
+	(*MensajesApp)(nil),           // 13: mensajero.MensajesApp
+	(*RespuestaEnviar)(nil),       // 14: mensajero.RespuestaEnviar
+	(*ResultadoEnvio)(nil),        // 15: mensajero.ResultadoEnvio
+	(*ConsultaHistorial)(nil),     // 16: mensajero.ConsultaHistorial
+	(*EntradaHistorial)(nil),      // 17: mensajero.EntradaHistorial
+	(*PaginaHistorial)(nil),       // 18: mensajero.PaginaHistorial
+	(*EventoConversacion)(nil),    // 19: mensajero.EventoConversacion
+	(*durationpb.Duration)(nil),   // 20: google.protobuf.Duration
+	(*timestamppb.Timestamp)(nil), // 21: google.protobuf.Timestamp
+}
+var file_pkg_mensajero_proto_depIdxs = []int32{
+	20, // 0: mensajero.ObtenerConLimite.espera:type_name -> google.protobuf.Duration
+	21, // 1: mensajero.TokenAutenticacion.vence:type_name -> google.protobuf.Timestamp
+	20, // 2: mensajero.TokenAutenticacion.latido:type_name -> google.protobuf.Duration
+	21, // 3: mensajero.Sesion.inicio:type_name -> google.protobuf.Timestamp
+	21, // 4: mensajero.Sesion.vence:type_name -> google.protobuf.Timestamp
+	6,  // 5: mensajero.ListaSesiones.sesiones:type_name -> mensajero.Sesion
+	21, // 6: mensajero.MensajeApp.fecha:type_name -> google.protobuf.Timestamp
+	21, // 7: mensajero.RespuestaPublicar.fecha:type_name -> google.protobuf.Timestamp
+	8,  // 8: mensajero.MensajesApp.mensajes:type_name -> mensajero.MensajeApp
+	21, // 9: mensajero.RespuestaEnviar.fecha:type_name -> google.protobuf.Timestamp
+	21, // 10: mensajero.ConsultaHistorial.desde:type_name -> google.protobuf.Timestamp
+	21, // 11: mensajero.ConsultaHistorial.hasta:type_name -> google.protobuf.Timestamp
+	8,  // 12: mensajero.EntradaHistorial.mensaje:type_name -> mensajero.MensajeApp
+	17, // 13: mensajero.PaginaHistorial.entradas:type_name -> mensajero.EntradaHistorial
+	8,  // 14: mensajero.EventoConversacion.mensaje:type_name -> mensajero.MensajeApp
+	15, // 15: mensajero.EventoConversacion.resultado:type_name -> mensajero.ResultadoEnvio
+	3,  // 16: mensajero.Mensajero.Conectar:input_type -> mensajero.Registracion
+	3,  // 17: mensajero.Mensajero.CrearCuenta:input_type -> mensajero.Registracion
+	5,  // 18: mensajero.Mensajero.Renovar:input_type -> mensajero.Vacio
+	5,  // 19: mensajero.Mensajero.Latido:input_type -> mensajero.Vacio
+	8,  // 20: mensajero.Mensajero.Enviar:input_type -> mensajero.MensajeApp
+	5,  // 21: mensajero.Mensajero.Obtener:input_type -> mensajero.Vacio
+	1,  // 22: mensajero.Mensajero.ObtenerLimitado:input_type -> mensajero.ObtenerConLimite
+	12, // 23: mensajero.Mensajero.Confirmar:input_type -> mensajero.Confirmacion
+	8,  // 24: mensajero.Mensajero.Conversar:input_type -> mensajero.MensajeApp
+	5,  // 25: mensajero.Mensajero.Suscribir:input_type -> mensajero.Vacio
+	9,  // 26: mensajero.Mensajero.CrearCanal:input_type -> mensajero.Canal
+	9,  // 27: mensajero.Mensajero.UnirseCanal:input_type -> mensajero.Canal
+	9,  // 28: mensajero.Mensajero.AbandonarCanal:input_type -> mensajero.Canal
+	5,  // 29: mensajero.Mensajero.ListarCanales:input_type -> mensajero.Vacio
+	9,  // 30: mensajero.Mensajero.ListarMiembros:input_type -> mensajero.Canal
+	8,  // 31: mensajero.Mensajero.Publicar:input_type -> mensajero.MensajeApp
+	8,  // 32: mensajero.Mensajero.Difundir:input_type -> mensajero.MensajeApp
+	16, // 33: mensajero.Mensajero.Historial:input_type -> mensajero.ConsultaHistorial
+	5,  // 34: mensajero.Mensajero.ListarSesiones:input_type -> mensajero.Vacio
+	6,  // 35: mensajero.Mensajero.RevocarSesion:input_type -> mensajero.Sesion
+	5,  // 36: mensajero.Mensajero.Listar:input_type -> mensajero.Vacio
+	5,  // 37: mensajero.Mensajero.Desconectar:input_type -> mensajero.Vacio
+	4,  // 38: mensajero.Mensajero.Conectar:output_type -> mensajero.TokenAutenticacion
+	0,  // 39: mensajero.Mensajero.CrearCuenta:output_type -> mensajero.Correcto
+	4,  // 40: mensajero.Mensajero.Renovar:output_type -> mensajero.TokenAutenticacion
+	0,  // 41: mensajero.Mensajero.Latido:output_type -> mensajero.Correcto
+	14, // 42: mensajero.Mensajero.Enviar:output_type -> mensajero.RespuestaEnviar
+	13, // 43: mensajero.Mensajero.Obtener:output_type -> mensajero.MensajesApp
+	13, // 44: mensajero.Mensajero.ObtenerLimitado:output_type -> mensajero.MensajesApp
+	0,  // 45: mensajero.Mensajero.Confirmar:output_type -> mensajero.Correcto
+	19, // 46: mensajero.Mensajero.Conversar:output_type -> mensajero.EventoConversacion
+	8,  // 47: mensajero.Mensajero.Suscribir:output_type -> mensajero.MensajeApp
+	0,  // 48: mensajero.Mensajero.CrearCanal:output_type -> mensajero.Correcto
+	0,  // 49: mensajero.Mensajero.UnirseCanal:output_type -> mensajero.Correcto
+	0,  // 50: mensajero.Mensajero.AbandonarCanal:output_type -> mensajero.Correcto
+	10, // 51: mensajero.Mensajero.ListarCanales:output_type -> mensajero.ListaCanales
+	2,  // 52: mensajero.Mensajero.ListarMiembros:output_type -> mensajero.ListaUsuarios
+	11, // 53: mensajero.Mensajero.Publicar:output_type -> mensajero.RespuestaPublicar
+	11, // 54: mensajero.Mensajero.Difundir:output_type -> mensajero.RespuestaPublicar
+	18, // 55: mensajero.Mensajero.Historial:output_type -> mensajero.PaginaHistorial
+	7,  // 56: mensajero.Mensajero.ListarSesiones:output_type -> mensajero.ListaSesiones
+	0,  // 57: mensajero.Mensajero.RevocarSesion:output_type -> mensajero.Correcto
+	2,  // 58: mensajero.Mensajero.Listar:output_type -> mensajero.ListaUsuarios
+	0,  // 59: mensajero.Mensajero.Desconectar:output_type -> mensajero.Correcto
+	38, // [38:60] is the sub-list for method output_type
+	16, // [16:38] is the sub-list for method input_type
+	16, // [16:16] is the sub-list for extension type_name
+	16, // [16:16] is the sub-list for extension extendee
+	0,  // [0:16] is the sub-list for field type_name
+}
+
+func init() { file_pkg_mensajero_proto_init() }
+func file_pkg_mensajero_proto_init() {
+	if File_pkg_mensajero_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_pkg_mensajero_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Correcto); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -570,7 +1762,7 @@ func file_pkg_mensajero_proto_init() {
 			}
 		}
 		file_pkg_mensajero_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*MensajeApp); i {
+			switch v := v.(*Sesion); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -582,6 +1774,78 @@ func file_pkg_mensajero_proto_init() {
 			}
 		}
 		file_pkg_mensajero_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListaSesiones); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_mensajero_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*MensajeApp); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_mensajero_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Canal); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_mensajero_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListaCanales); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_mensajero_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RespuestaPublicar); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_mensajero_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Confirmacion); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_mensajero_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*MensajesApp); i {
 			case 0:
 				return &v.state
@@ -593,6 +1857,82 @@ func file_pkg_mensajero_proto_init() {
 				return nil
 			}
 		}
+		file_pkg_mensajero_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RespuestaEnviar); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_mensajero_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ResultadoEnvio); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_mensajero_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ConsultaHistorial); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_mensajero_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*EntradaHistorial); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_mensajero_proto_msgTypes[18].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PaginaHistorial); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_mensajero_proto_msgTypes[19].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*EventoConversacion); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_pkg_mensajero_proto_msgTypes[19].OneofWrappers = []interface{}{
+		(*EventoConversacion_Mensaje)(nil),
+		(*EventoConversacion_Resultado)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -600,7 +1940,7 @@ func file_pkg_mensajero_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_pkg_mensajero_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   8,
+			NumMessages:   20,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
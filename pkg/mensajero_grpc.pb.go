@@ -24,18 +24,103 @@ const _ = grpc.SupportPackageIsVersion7
 type MensajeroClient interface {
 	// El usuario recibe un token de conexión. Este token de conexión se pasa implícitamente como
 	// metadatos en todas las demás llamadas y es validado por un interceptor del lado del servidor.
+	// Cada llamada abre una sesión nueva, de modo que el usuario puede estar conectado desde
+	// varios dispositivos a la vez.
+	// El token es aleatorio y vale por un plazo que define el servidor. Si el servidor usa
+	// cuentas, falla con UNAUTHENTICATED si el usuario no tiene una o la contraseña no es la suya.
+	// Con `tokenAnterior` reanuda esa sesión, que conserva su identificador y sus mensajes, con
+	// un token nuevo que invalida al anterior; falla con NOT_FOUND si la sesión no existe, es de
+	// otro usuario o ya venció. Con `cerrarOtras` cierra además las demás sesiones del usuario.
+	// Con TLS mutuo el certificado del cliente identifica al usuario en lugar de la contraseña;
+	// falla con PERMISSION_DENIED si `usuarioOrigen` no es el del certificado.
 	Conectar(ctx context.Context, in *Registracion, opts ...grpc.CallOption) (*TokenAutenticacion, error)
-	// El usuario envía un mensaje a otro usuario.
-	Enviar(ctx context.Context, in *MensajeApp, opts ...grpc.CallOption) (*Correcto, error)
-	// El usuario obtiene todos los mensajes dirigidos a El en lotes. El tamaño del lote es
+	// El usuario crea una cuenta con su contraseña para luego conectarse con Conectar. No
+	// requiere token. Falla con ALREADY_EXISTS si el usuario ya tiene una cuenta y con
+	// FAILED_PRECONDITION si el servidor no usa cuentas.
+	CrearCuenta(ctx context.Context, in *Registracion, opts ...grpc.CallOption) (*Correcto, error)
+	// El usuario extiende el plazo de su token, que no cambia, por el plazo completo que
+	// define el servidor. Falla con UNAUTHENTICATED si el token ya venció: el usuario debe
+	// volver a conectarse.
+	Renovar(ctx context.Context, in *Vacio, opts ...grpc.CallOption) (*TokenAutenticacion, error)
+	// El usuario avisa que su sesión sigue en uso. Cualquier llamada cuenta como actividad,
+	// pero un cliente sin nada que hacer debe llamar a Latido con la frecuencia indicada en su
+	// TokenAutenticacion: el servidor cierra las sesiones inactivas como si se desconectaran.
+	Latido(ctx context.Context, in *Vacio, opts ...grpc.CallOption) (*Correcto, error)
+	// El usuario envía un mensaje a otro usuario, esté conectado o no. Falla con NOT_FOUND
+	// si el destinatario nunca se conectó al servidor. Si la bandeja del destinatario está
+	// llena, según la política del servidor falla con RESOURCE_EXHAUSTED, descarta el mensaje
+	// más antiguo de la bandeja, lo guarda en disco o lo descarta respondiendo `ok` en false.
+	// La respuesta lleva el identificador que el servidor asignó al mensaje.
+	Enviar(ctx context.Context, in *MensajeApp, opts ...grpc.CallOption) (*RespuestaEnviar, error)
+	// El usuario obtiene todos los mensajes dirigidos a El en lotes.
+	// Si el usuario tiene varias sesiones, todas ven los mensajes que le llegan: los que obtiene
+	// y confirma una sesión se copian a las demás, que los reciben en su próxima llamada, antes
+	// que los mensajes pendientes. Las copias se confirman y se vuelven a entregar como los
+	// mensajes, pero solo a la sesión que las recibió. El tamaño del lote es
 	// definido por el servidor que implementa esta RPC, los clientes no pueden controlarlo.
+	// Los mensajes obtenidos quedan reservados por un plazo de visibilidad: si el usuario no
+	// los confirma con Confirmar antes de que venza, vuelven a su bandeja y se entregan otra
+	// vez, con `reentregas` incrementado. Lo mismo vale para los mensajes que llegan por
+	// Conversar y Suscribir.
 	Obtener(ctx context.Context, in *Vacio, opts ...grpc.CallOption) (*MensajesApp, error)
+	// Como Obtener, pero el usuario elige cuántos mensajes obtener y puede limitar el tamaño de
+	// la respuesta en bytes. Con `espera`, si no hay mensajes, la llamada espera hasta que
+	// llegue alguno en lugar de responder de inmediato.
+	ObtenerLimitado(ctx context.Context, in *ObtenerConLimite, opts ...grpc.CallOption) (*MensajesApp, error)
+	// El usuario confirma que recibió los mensajes indicados, que salen definitivamente de su
+	// bandeja. Responde `ok` en false si alguno no estaba reservado, por ejemplo porque su
+	// plazo venció y el mensaje volvió a la bandeja; ese mensaje se entregará de nuevo.
+	Confirmar(ctx context.Context, in *Confirmacion, opts ...grpc.CallOption) (*Correcto, error)
+	// El usuario abre una conversación: envía mensajes a otros usuarios por el flujo de entrada
+	// (con el destinatario en `usuario`, como en Enviar) y recibe por el flujo de salida el
+	// resultado de cada envío y los mensajes que le llegan, en cuanto llegan. El token se
+	// valida igual que en las demás llamadas. La conversación termina cuando el cliente
+	// cierra su flujo de entrada, o con UNAUTHENTICATED si su sesión se cierra o vence.
+	Conversar(ctx context.Context, opts ...grpc.CallOption) (Mensajero_ConversarClient, error)
+	// El usuario se suscribe a su bandeja de entrada: el servidor le envía primero los mensajes
+	// que ya tenía pendientes y luego cada mensaje que le llega, en cuanto llega. Los mensajes
+	// que el servidor no logra enviar porque el cliente se desconectó o el flujo falló vuelven a
+	// la bandeja y se entregan en la próxima llamada; los enviados deben confirmarse como los
+	// de Obtener. La suscripción termina cuando el cliente la cancela, o con UNAUTHENTICATED
+	// si su sesión se cierra o vence; mientras está abierta la sesión no se cierra por
+	// inactividad.
+	Suscribir(ctx context.Context, in *Vacio, opts ...grpc.CallOption) (Mensajero_SuscribirClient, error)
+	// El usuario crea un canal y se une a él. Falla con ALREADY_EXISTS si el canal ya existe.
+	CrearCanal(ctx context.Context, in *Canal, opts ...grpc.CallOption) (*Correcto, error)
+	// El usuario se une a un canal existente. Falla con NOT_FOUND si el canal no existe.
+	UnirseCanal(ctx context.Context, in *Canal, opts ...grpc.CallOption) (*Correcto, error)
+	// El usuario abandona un canal. Responde `ok` en false si no era miembro.
+	AbandonarCanal(ctx context.Context, in *Canal, opts ...grpc.CallOption) (*Correcto, error)
+	// El usuario obtiene los nombres de todos los canales.
+	ListarCanales(ctx context.Context, in *Vacio, opts ...grpc.CallOption) (*ListaCanales, error)
+	// El usuario obtiene los miembros de un canal. Falla con NOT_FOUND si el canal no existe.
+	ListarMiembros(ctx context.Context, in *Canal, opts ...grpc.CallOption) (*ListaUsuarios, error)
+	// El usuario publica un mensaje en el canal indicado en `canal`: el mensaje se deposita en
+	// la bandeja de cada uno de los demás miembros, con el nombre del canal. Solo los miembros
+	// pueden publicar; los demás reciben PERMISSION_DENIED.
+	Publicar(ctx context.Context, in *MensajeApp, opts ...grpc.CallOption) (*RespuestaPublicar, error)
+	// El usuario envía un mensaje a todos los demás usuarios conectados, marcado con `difusion`.
+	// Solo pueden hacerlo los administradores del servidor; los demás reciben
+	// PERMISSION_DENIED. El campo `usuario` se ignora.
+	Difundir(ctx context.Context, in *MensajeApp, opts ...grpc.CallOption) (*RespuestaPublicar, error)
+	// El usuario recorre su historial: los mensajes que envió y recibió, estén leídos o no, del
+	// más reciente al más antiguo y de a páginas. El servidor guarda en memoria una cantidad
+	// limitada de mensajes por usuario y olvida los más antiguos; el historial no sobrevive a
+	// un reinicio. Falla con INVALID_ARGUMENT si el cursor no es uno devuelto por el servidor.
+	Historial(ctx context.Context, in *ConsultaHistorial, opts ...grpc.CallOption) (*PaginaHistorial, error)
+	// El usuario obtiene sus sesiones vigentes, con la que hace la consulta marcada como `actual`.
+	ListarSesiones(ctx context.Context, in *Vacio, opts ...grpc.CallOption) (*ListaSesiones, error)
+	// El usuario cierra una de sus sesiones por su identificador, por ejemplo la de un
+	// dispositivo perdido, e invalida su token. Responde `ok` en false si no tenía esa sesión.
+	RevocarSesion(ctx context.Context, in *Sesion, opts ...grpc.CallOption) (*Correcto, error)
 	// El usuario obtiene una lista de los usuarios actualmente activos.
 	Listar(ctx context.Context, in *Vacio, opts ...grpc.CallOption) (*ListaUsuarios, error)
-	// Enviado por el usuario para informar al servidor que se va. Luego, el servidor puede
+	// Enviado por el usuario para informar al servidor que se va. Solo cierra la sesión de la
+	// llamada: las demás sesiones del usuario siguen abiertas. Luego, el servidor puede
 	// optar por hacer algo con la acumulación de mensajes que quedan en la cola de la bandeja de
-	// entrada del usuario que aún no se han leído; para esta práctica, simplemente los eliminaremos.
-	// También invalida el token de autenticación utilizado por el usuario.
+	// entrada del usuario que aún no se han leído; el servidor los conserva, junto con los que
+	// lleguen mientras tanto, y los entrega cuando el usuario vuelve a conectarse.
+	// También invalida el token de autenticación utilizado por el usuario en esta sesión.
 	Desconectar(ctx context.Context, in *Vacio, opts ...grpc.CallOption) (*Correcto, error)
 }
 
@@ -56,8 +141,35 @@ func (c *mensajeroClient) Conectar(ctx context.Context, in *Registracion, opts .
 	return out, nil
 }
 
-func (c *mensajeroClient) Enviar(ctx context.Context, in *MensajeApp, opts ...grpc.CallOption) (*Correcto, error) {
+func (c *mensajeroClient) CrearCuenta(ctx context.Context, in *Registracion, opts ...grpc.CallOption) (*Correcto, error) {
 	out := new(Correcto)
+	err := c.cc.Invoke(ctx, "/mensajero.Mensajero/CrearCuenta", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mensajeroClient) Renovar(ctx context.Context, in *Vacio, opts ...grpc.CallOption) (*TokenAutenticacion, error) {
+	out := new(TokenAutenticacion)
+	err := c.cc.Invoke(ctx, "/mensajero.Mensajero/Renovar", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mensajeroClient) Latido(ctx context.Context, in *Vacio, opts ...grpc.CallOption) (*Correcto, error) {
+	out := new(Correcto)
+	err := c.cc.Invoke(ctx, "/mensajero.Mensajero/Latido", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mensajeroClient) Enviar(ctx context.Context, in *MensajeApp, opts ...grpc.CallOption) (*RespuestaEnviar, error) {
+	out := new(RespuestaEnviar)
 	err := c.cc.Invoke(ctx, "/mensajero.Mensajero/Enviar", in, out, opts...)
 	if err != nil {
 		return nil, err
@@ -74,6 +186,177 @@ func (c *mensajeroClient) Obtener(ctx context.Context, in *Vacio, opts ...grpc.C
 	return out, nil
 }
 
+func (c *mensajeroClient) ObtenerLimitado(ctx context.Context, in *ObtenerConLimite, opts ...grpc.CallOption) (*MensajesApp, error) {
+	out := new(MensajesApp)
+	err := c.cc.Invoke(ctx, "/mensajero.Mensajero/ObtenerLimitado", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mensajeroClient) Confirmar(ctx context.Context, in *Confirmacion, opts ...grpc.CallOption) (*Correcto, error) {
+	out := new(Correcto)
+	err := c.cc.Invoke(ctx, "/mensajero.Mensajero/Confirmar", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mensajeroClient) Conversar(ctx context.Context, opts ...grpc.CallOption) (Mensajero_ConversarClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Mensajero_ServiceDesc.Streams[0], "/mensajero.Mensajero/Conversar", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &mensajeroConversarClient{stream}
+	return x, nil
+}
+
+type Mensajero_ConversarClient interface {
+	Send(*MensajeApp) error
+	Recv() (*EventoConversacion, error)
+	grpc.ClientStream
+}
+
+type mensajeroConversarClient struct {
+	grpc.ClientStream
+}
+
+func (x *mensajeroConversarClient) Send(m *MensajeApp) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *mensajeroConversarClient) Recv() (*EventoConversacion, error) {
+	m := new(EventoConversacion)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *mensajeroClient) Suscribir(ctx context.Context, in *Vacio, opts ...grpc.CallOption) (Mensajero_SuscribirClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Mensajero_ServiceDesc.Streams[1], "/mensajero.Mensajero/Suscribir", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &mensajeroSuscribirClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Mensajero_SuscribirClient interface {
+	Recv() (*MensajeApp, error)
+	grpc.ClientStream
+}
+
+type mensajeroSuscribirClient struct {
+	grpc.ClientStream
+}
+
+func (x *mensajeroSuscribirClient) Recv() (*MensajeApp, error) {
+	m := new(MensajeApp)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *mensajeroClient) CrearCanal(ctx context.Context, in *Canal, opts ...grpc.CallOption) (*Correcto, error) {
+	out := new(Correcto)
+	err := c.cc.Invoke(ctx, "/mensajero.Mensajero/CrearCanal", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mensajeroClient) UnirseCanal(ctx context.Context, in *Canal, opts ...grpc.CallOption) (*Correcto, error) {
+	out := new(Correcto)
+	err := c.cc.Invoke(ctx, "/mensajero.Mensajero/UnirseCanal", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mensajeroClient) AbandonarCanal(ctx context.Context, in *Canal, opts ...grpc.CallOption) (*Correcto, error) {
+	out := new(Correcto)
+	err := c.cc.Invoke(ctx, "/mensajero.Mensajero/AbandonarCanal", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mensajeroClient) ListarCanales(ctx context.Context, in *Vacio, opts ...grpc.CallOption) (*ListaCanales, error) {
+	out := new(ListaCanales)
+	err := c.cc.Invoke(ctx, "/mensajero.Mensajero/ListarCanales", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mensajeroClient) ListarMiembros(ctx context.Context, in *Canal, opts ...grpc.CallOption) (*ListaUsuarios, error) {
+	out := new(ListaUsuarios)
+	err := c.cc.Invoke(ctx, "/mensajero.Mensajero/ListarMiembros", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mensajeroClient) Publicar(ctx context.Context, in *MensajeApp, opts ...grpc.CallOption) (*RespuestaPublicar, error) {
+	out := new(RespuestaPublicar)
+	err := c.cc.Invoke(ctx, "/mensajero.Mensajero/Publicar", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mensajeroClient) Difundir(ctx context.Context, in *MensajeApp, opts ...grpc.CallOption) (*RespuestaPublicar, error) {
+	out := new(RespuestaPublicar)
+	err := c.cc.Invoke(ctx, "/mensajero.Mensajero/Difundir", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mensajeroClient) Historial(ctx context.Context, in *ConsultaHistorial, opts ...grpc.CallOption) (*PaginaHistorial, error) {
+	out := new(PaginaHistorial)
+	err := c.cc.Invoke(ctx, "/mensajero.Mensajero/Historial", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mensajeroClient) ListarSesiones(ctx context.Context, in *Vacio, opts ...grpc.CallOption) (*ListaSesiones, error) {
+	out := new(ListaSesiones)
+	err := c.cc.Invoke(ctx, "/mensajero.Mensajero/ListarSesiones", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mensajeroClient) RevocarSesion(ctx context.Context, in *Sesion, opts ...grpc.CallOption) (*Correcto, error) {
+	out := new(Correcto)
+	err := c.cc.Invoke(ctx, "/mensajero.Mensajero/RevocarSesion", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *mensajeroClient) Listar(ctx context.Context, in *Vacio, opts ...grpc.CallOption) (*ListaUsuarios, error) {
 	out := new(ListaUsuarios)
 	err := c.cc.Invoke(ctx, "/mensajero.Mensajero/Listar", in, out, opts...)
@@ -98,18 +381,103 @@ func (c *mensajeroClient) Desconectar(ctx context.Context, in *Vacio, opts ...gr
 type MensajeroServer interface {
 	// El usuario recibe un token de conexión. Este token de conexión se pasa implícitamente como
 	// metadatos en todas las demás llamadas y es validado por un interceptor del lado del servidor.
+	// Cada llamada abre una sesión nueva, de modo que el usuario puede estar conectado desde
+	// varios dispositivos a la vez.
+	// El token es aleatorio y vale por un plazo que define el servidor. Si el servidor usa
+	// cuentas, falla con UNAUTHENTICATED si el usuario no tiene una o la contraseña no es la suya.
+	// Con `tokenAnterior` reanuda esa sesión, que conserva su identificador y sus mensajes, con
+	// un token nuevo que invalida al anterior; falla con NOT_FOUND si la sesión no existe, es de
+	// otro usuario o ya venció. Con `cerrarOtras` cierra además las demás sesiones del usuario.
+	// Con TLS mutuo el certificado del cliente identifica al usuario en lugar de la contraseña;
+	// falla con PERMISSION_DENIED si `usuarioOrigen` no es el del certificado.
 	Conectar(context.Context, *Registracion) (*TokenAutenticacion, error)
-	// El usuario envía un mensaje a otro usuario.
-	Enviar(context.Context, *MensajeApp) (*Correcto, error)
-	// El usuario obtiene todos los mensajes dirigidos a El en lotes. El tamaño del lote es
+	// El usuario crea una cuenta con su contraseña para luego conectarse con Conectar. No
+	// requiere token. Falla con ALREADY_EXISTS si el usuario ya tiene una cuenta y con
+	// FAILED_PRECONDITION si el servidor no usa cuentas.
+	CrearCuenta(context.Context, *Registracion) (*Correcto, error)
+	// El usuario extiende el plazo de su token, que no cambia, por el plazo completo que
+	// define el servidor. Falla con UNAUTHENTICATED si el token ya venció: el usuario debe
+	// volver a conectarse.
+	Renovar(context.Context, *Vacio) (*TokenAutenticacion, error)
+	// El usuario avisa que su sesión sigue en uso. Cualquier llamada cuenta como actividad,
+	// pero un cliente sin nada que hacer debe llamar a Latido con la frecuencia indicada en su
+	// TokenAutenticacion: el servidor cierra las sesiones inactivas como si se desconectaran.
+	Latido(context.Context, *Vacio) (*Correcto, error)
+	// El usuario envía un mensaje a otro usuario, esté conectado o no. Falla con NOT_FOUND
+	// si el destinatario nunca se conectó al servidor. Si la bandeja del destinatario está
+	// llena, según la política del servidor falla con RESOURCE_EXHAUSTED, descarta el mensaje
+	// más antiguo de la bandeja, lo guarda en disco o lo descarta respondiendo `ok` en false.
+	// La respuesta lleva el identificador que el servidor asignó al mensaje.
+	Enviar(context.Context, *MensajeApp) (*RespuestaEnviar, error)
+	// El usuario obtiene todos los mensajes dirigidos a El en lotes.
+	// Si el usuario tiene varias sesiones, todas ven los mensajes que le llegan: los que obtiene
+	// y confirma una sesión se copian a las demás, que los reciben en su próxima llamada, antes
+	// que los mensajes pendientes. Las copias se confirman y se vuelven a entregar como los
+	// mensajes, pero solo a la sesión que las recibió. El tamaño del lote es
 	// definido por el servidor que implementa esta RPC, los clientes no pueden controlarlo.
+	// Los mensajes obtenidos quedan reservados por un plazo de visibilidad: si el usuario no
+	// los confirma con Confirmar antes de que venza, vuelven a su bandeja y se entregan otra
+	// vez, con `reentregas` incrementado. Lo mismo vale para los mensajes que llegan por
+	// Conversar y Suscribir.
 	Obtener(context.Context, *Vacio) (*MensajesApp, error)
+	// Como Obtener, pero el usuario elige cuántos mensajes obtener y puede limitar el tamaño de
+	// la respuesta en bytes. Con `espera`, si no hay mensajes, la llamada espera hasta que
+	// llegue alguno en lugar de responder de inmediato.
+	ObtenerLimitado(context.Context, *ObtenerConLimite) (*MensajesApp, error)
+	// El usuario confirma que recibió los mensajes indicados, que salen definitivamente de su
+	// bandeja. Responde `ok` en false si alguno no estaba reservado, por ejemplo porque su
+	// plazo venció y el mensaje volvió a la bandeja; ese mensaje se entregará de nuevo.
+	Confirmar(context.Context, *Confirmacion) (*Correcto, error)
+	// El usuario abre una conversación: envía mensajes a otros usuarios por el flujo de entrada
+	// (con el destinatario en `usuario`, como en Enviar) y recibe por el flujo de salida el
+	// resultado de cada envío y los mensajes que le llegan, en cuanto llegan. El token se
+	// valida igual que en las demás llamadas. La conversación termina cuando el cliente
+	// cierra su flujo de entrada, o con UNAUTHENTICATED si su sesión se cierra o vence.
+	Conversar(Mensajero_ConversarServer) error
+	// El usuario se suscribe a su bandeja de entrada: el servidor le envía primero los mensajes
+	// que ya tenía pendientes y luego cada mensaje que le llega, en cuanto llega. Los mensajes
+	// que el servidor no logra enviar porque el cliente se desconectó o el flujo falló vuelven a
+	// la bandeja y se entregan en la próxima llamada; los enviados deben confirmarse como los
+	// de Obtener. La suscripción termina cuando el cliente la cancela, o con UNAUTHENTICATED
+	// si su sesión se cierra o vence; mientras está abierta la sesión no se cierra por
+	// inactividad.
+	Suscribir(*Vacio, Mensajero_SuscribirServer) error
+	// El usuario crea un canal y se une a él. Falla con ALREADY_EXISTS si el canal ya existe.
+	CrearCanal(context.Context, *Canal) (*Correcto, error)
+	// El usuario se une a un canal existente. Falla con NOT_FOUND si el canal no existe.
+	UnirseCanal(context.Context, *Canal) (*Correcto, error)
+	// El usuario abandona un canal. Responde `ok` en false si no era miembro.
+	AbandonarCanal(context.Context, *Canal) (*Correcto, error)
+	// El usuario obtiene los nombres de todos los canales.
+	ListarCanales(context.Context, *Vacio) (*ListaCanales, error)
+	// El usuario obtiene los miembros de un canal. Falla con NOT_FOUND si el canal no existe.
+	ListarMiembros(context.Context, *Canal) (*ListaUsuarios, error)
+	// El usuario publica un mensaje en el canal indicado en `canal`: el mensaje se deposita en
+	// la bandeja de cada uno de los demás miembros, con el nombre del canal. Solo los miembros
+	// pueden publicar; los demás reciben PERMISSION_DENIED.
+	Publicar(context.Context, *MensajeApp) (*RespuestaPublicar, error)
+	// El usuario envía un mensaje a todos los demás usuarios conectados, marcado con `difusion`.
+	// Solo pueden hacerlo los administradores del servidor; los demás reciben
+	// PERMISSION_DENIED. El campo `usuario` se ignora.
+	Difundir(context.Context, *MensajeApp) (*RespuestaPublicar, error)
+	// El usuario recorre su historial: los mensajes que envió y recibió, estén leídos o no, del
+	// más reciente al más antiguo y de a páginas. El servidor guarda en memoria una cantidad
+	// limitada de mensajes por usuario y olvida los más antiguos; el historial no sobrevive a
+	// un reinicio. Falla con INVALID_ARGUMENT si el cursor no es uno devuelto por el servidor.
+	Historial(context.Context, *ConsultaHistorial) (*PaginaHistorial, error)
+	// El usuario obtiene sus sesiones vigentes, con la que hace la consulta marcada como `actual`.
+	ListarSesiones(context.Context, *Vacio) (*ListaSesiones, error)
+	// El usuario cierra una de sus sesiones por su identificador, por ejemplo la de un
+	// dispositivo perdido, e invalida su token. Responde `ok` en false si no tenía esa sesión.
+	RevocarSesion(context.Context, *Sesion) (*Correcto, error)
 	// El usuario obtiene una lista de los usuarios actualmente activos.
 	Listar(context.Context, *Vacio) (*ListaUsuarios, error)
-	// Enviado por el usuario para informar al servidor que se va. Luego, el servidor puede
+	// Enviado por el usuario para informar al servidor que se va. Solo cierra la sesión de la
+	// llamada: las demás sesiones del usuario siguen abiertas. Luego, el servidor puede
 	// optar por hacer algo con la acumulación de mensajes que quedan en la cola de la bandeja de
-	// entrada del usuario que aún no se han leído; para esta práctica, simplemente los eliminaremos.
-	// También invalida el token de autenticación utilizado por el usuario.
+	// entrada del usuario que aún no se han leído; el servidor los conserva, junto con los que
+	// lleguen mientras tanto, y los entrega cuando el usuario vuelve a conectarse.
+	// También invalida el token de autenticación utilizado por el usuario en esta sesión.
 	Desconectar(context.Context, *Vacio) (*Correcto, error)
 	mustEmbedUnimplementedMensajeroServer()
 }
@@ -121,12 +489,63 @@ type UnimplementedMensajeroServer struct {
 func (UnimplementedMensajeroServer) Conectar(context.Context, *Registracion) (*TokenAutenticacion, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Conectar not implemented")
 }
-func (UnimplementedMensajeroServer) Enviar(context.Context, *MensajeApp) (*Correcto, error) {
+func (UnimplementedMensajeroServer) CrearCuenta(context.Context, *Registracion) (*Correcto, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CrearCuenta not implemented")
+}
+func (UnimplementedMensajeroServer) Renovar(context.Context, *Vacio) (*TokenAutenticacion, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Renovar not implemented")
+}
+func (UnimplementedMensajeroServer) Latido(context.Context, *Vacio) (*Correcto, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Latido not implemented")
+}
+func (UnimplementedMensajeroServer) Enviar(context.Context, *MensajeApp) (*RespuestaEnviar, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Enviar not implemented")
 }
 func (UnimplementedMensajeroServer) Obtener(context.Context, *Vacio) (*MensajesApp, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Obtener not implemented")
 }
+func (UnimplementedMensajeroServer) ObtenerLimitado(context.Context, *ObtenerConLimite) (*MensajesApp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ObtenerLimitado not implemented")
+}
+func (UnimplementedMensajeroServer) Confirmar(context.Context, *Confirmacion) (*Correcto, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Confirmar not implemented")
+}
+func (UnimplementedMensajeroServer) Conversar(Mensajero_ConversarServer) error {
+	return status.Errorf(codes.Unimplemented, "method Conversar not implemented")
+}
+func (UnimplementedMensajeroServer) Suscribir(*Vacio, Mensajero_SuscribirServer) error {
+	return status.Errorf(codes.Unimplemented, "method Suscribir not implemented")
+}
+func (UnimplementedMensajeroServer) CrearCanal(context.Context, *Canal) (*Correcto, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CrearCanal not implemented")
+}
+func (UnimplementedMensajeroServer) UnirseCanal(context.Context, *Canal) (*Correcto, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnirseCanal not implemented")
+}
+func (UnimplementedMensajeroServer) AbandonarCanal(context.Context, *Canal) (*Correcto, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AbandonarCanal not implemented")
+}
+func (UnimplementedMensajeroServer) ListarCanales(context.Context, *Vacio) (*ListaCanales, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListarCanales not implemented")
+}
+func (UnimplementedMensajeroServer) ListarMiembros(context.Context, *Canal) (*ListaUsuarios, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListarMiembros not implemented")
+}
+func (UnimplementedMensajeroServer) Publicar(context.Context, *MensajeApp) (*RespuestaPublicar, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Publicar not implemented")
+}
+func (UnimplementedMensajeroServer) Difundir(context.Context, *MensajeApp) (*RespuestaPublicar, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Difundir not implemented")
+}
+func (UnimplementedMensajeroServer) Historial(context.Context, *ConsultaHistorial) (*PaginaHistorial, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Historial not implemented")
+}
+func (UnimplementedMensajeroServer) ListarSesiones(context.Context, *Vacio) (*ListaSesiones, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListarSesiones not implemented")
+}
+func (UnimplementedMensajeroServer) RevocarSesion(context.Context, *Sesion) (*Correcto, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevocarSesion not implemented")
+}
 func (UnimplementedMensajeroServer) Listar(context.Context, *Vacio) (*ListaUsuarios, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Listar not implemented")
 }
@@ -164,6 +583,60 @@ func _Mensajero_Conectar_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Mensajero_CrearCuenta_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Registracion)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MensajeroServer).CrearCuenta(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mensajero.Mensajero/CrearCuenta",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MensajeroServer).CrearCuenta(ctx, req.(*Registracion))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mensajero_Renovar_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Vacio)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MensajeroServer).Renovar(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mensajero.Mensajero/Renovar",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MensajeroServer).Renovar(ctx, req.(*Vacio))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mensajero_Latido_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Vacio)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MensajeroServer).Latido(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mensajero.Mensajero/Latido",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MensajeroServer).Latido(ctx, req.(*Vacio))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Mensajero_Enviar_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(MensajeApp)
 	if err := dec(in); err != nil {
@@ -200,6 +673,269 @@ func _Mensajero_Obtener_Handler(srv interface{}, ctx context.Context, dec func(i
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Mensajero_ObtenerLimitado_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ObtenerConLimite)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MensajeroServer).ObtenerLimitado(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mensajero.Mensajero/ObtenerLimitado",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MensajeroServer).ObtenerLimitado(ctx, req.(*ObtenerConLimite))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mensajero_Confirmar_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Confirmacion)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MensajeroServer).Confirmar(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mensajero.Mensajero/Confirmar",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MensajeroServer).Confirmar(ctx, req.(*Confirmacion))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mensajero_Conversar_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(MensajeroServer).Conversar(&mensajeroConversarServer{stream})
+}
+
+type Mensajero_ConversarServer interface {
+	Send(*EventoConversacion) error
+	Recv() (*MensajeApp, error)
+	grpc.ServerStream
+}
+
+type mensajeroConversarServer struct {
+	grpc.ServerStream
+}
+
+func (x *mensajeroConversarServer) Send(m *EventoConversacion) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *mensajeroConversarServer) Recv() (*MensajeApp, error) {
+	m := new(MensajeApp)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Mensajero_Suscribir_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Vacio)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MensajeroServer).Suscribir(m, &mensajeroSuscribirServer{stream})
+}
+
+type Mensajero_SuscribirServer interface {
+	Send(*MensajeApp) error
+	grpc.ServerStream
+}
+
+type mensajeroSuscribirServer struct {
+	grpc.ServerStream
+}
+
+func (x *mensajeroSuscribirServer) Send(m *MensajeApp) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Mensajero_CrearCanal_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Canal)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MensajeroServer).CrearCanal(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mensajero.Mensajero/CrearCanal",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MensajeroServer).CrearCanal(ctx, req.(*Canal))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mensajero_UnirseCanal_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Canal)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MensajeroServer).UnirseCanal(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mensajero.Mensajero/UnirseCanal",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MensajeroServer).UnirseCanal(ctx, req.(*Canal))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mensajero_AbandonarCanal_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Canal)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MensajeroServer).AbandonarCanal(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mensajero.Mensajero/AbandonarCanal",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MensajeroServer).AbandonarCanal(ctx, req.(*Canal))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mensajero_ListarCanales_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Vacio)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MensajeroServer).ListarCanales(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mensajero.Mensajero/ListarCanales",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MensajeroServer).ListarCanales(ctx, req.(*Vacio))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mensajero_ListarMiembros_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Canal)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MensajeroServer).ListarMiembros(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mensajero.Mensajero/ListarMiembros",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MensajeroServer).ListarMiembros(ctx, req.(*Canal))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mensajero_Publicar_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MensajeApp)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MensajeroServer).Publicar(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mensajero.Mensajero/Publicar",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MensajeroServer).Publicar(ctx, req.(*MensajeApp))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mensajero_Difundir_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MensajeApp)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MensajeroServer).Difundir(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mensajero.Mensajero/Difundir",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MensajeroServer).Difundir(ctx, req.(*MensajeApp))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mensajero_Historial_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConsultaHistorial)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MensajeroServer).Historial(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mensajero.Mensajero/Historial",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MensajeroServer).Historial(ctx, req.(*ConsultaHistorial))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mensajero_ListarSesiones_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Vacio)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MensajeroServer).ListarSesiones(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mensajero.Mensajero/ListarSesiones",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MensajeroServer).ListarSesiones(ctx, req.(*Vacio))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Mensajero_RevocarSesion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Sesion)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MensajeroServer).RevocarSesion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/mensajero.Mensajero/RevocarSesion",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MensajeroServer).RevocarSesion(ctx, req.(*Sesion))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Mensajero_Listar_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(Vacio)
 	if err := dec(in); err != nil {
@@ -247,6 +983,18 @@ var Mensajero_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Conectar",
 			Handler:    _Mensajero_Conectar_Handler,
 		},
+		{
+			MethodName: "CrearCuenta",
+			Handler:    _Mensajero_CrearCuenta_Handler,
+		},
+		{
+			MethodName: "Renovar",
+			Handler:    _Mensajero_Renovar_Handler,
+		},
+		{
+			MethodName: "Latido",
+			Handler:    _Mensajero_Latido_Handler,
+		},
 		{
 			MethodName: "Enviar",
 			Handler:    _Mensajero_Enviar_Handler,
@@ -255,6 +1003,54 @@ var Mensajero_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Obtener",
 			Handler:    _Mensajero_Obtener_Handler,
 		},
+		{
+			MethodName: "ObtenerLimitado",
+			Handler:    _Mensajero_ObtenerLimitado_Handler,
+		},
+		{
+			MethodName: "Confirmar",
+			Handler:    _Mensajero_Confirmar_Handler,
+		},
+		{
+			MethodName: "CrearCanal",
+			Handler:    _Mensajero_CrearCanal_Handler,
+		},
+		{
+			MethodName: "UnirseCanal",
+			Handler:    _Mensajero_UnirseCanal_Handler,
+		},
+		{
+			MethodName: "AbandonarCanal",
+			Handler:    _Mensajero_AbandonarCanal_Handler,
+		},
+		{
+			MethodName: "ListarCanales",
+			Handler:    _Mensajero_ListarCanales_Handler,
+		},
+		{
+			MethodName: "ListarMiembros",
+			Handler:    _Mensajero_ListarMiembros_Handler,
+		},
+		{
+			MethodName: "Publicar",
+			Handler:    _Mensajero_Publicar_Handler,
+		},
+		{
+			MethodName: "Difundir",
+			Handler:    _Mensajero_Difundir_Handler,
+		},
+		{
+			MethodName: "Historial",
+			Handler:    _Mensajero_Historial_Handler,
+		},
+		{
+			MethodName: "ListarSesiones",
+			Handler:    _Mensajero_ListarSesiones_Handler,
+		},
+		{
+			MethodName: "RevocarSesion",
+			Handler:    _Mensajero_RevocarSesion_Handler,
+		},
 		{
 			MethodName: "Listar",
 			Handler:    _Mensajero_Listar_Handler,
@@ -264,6 +1060,18 @@ var Mensajero_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _Mensajero_Desconectar_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Conversar",
+			Handler:       _Mensajero_Conversar_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Suscribir",
+			Handler:       _Mensajero_Suscribir_Handler,
+			ServerStreams: true,
+		},
+	},
 	Metadata: "pkg/mensajero.proto",
 }
@@ -0,0 +1,275 @@
+package pkg
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Los límites, en segundos, de los intervalos del histograma de duración de las llamadas.
+// La duración de un flujo, como Conversar, es la de toda la conversación.
+var limitesDuracion = []float64{0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 30}
+
+// Los límites de los intervalos del histograma de la profundidad de las bandejas, como
+// fracciones de la capacidad del almacén: muestran cuántas bandejas están vacías, casi
+// vacías o a punto de llenarse.
+var fraccionesBuzon = []float64{0, 0.1, 0.25, 0.5, 0.75, 0.9, 1}
+
+// Los límites del mismo histograma, en mensajes, para los almacenes que no informan su
+// capacidad.
+var limitesBuzonSinCapacidad = []int{0, 10, 100, 1000, 10000}
+
+// Devuelve los límites del histograma de la profundidad de las bandejas del almacén, sin
+// repetir los que coinciden en una capacidad chica, y la capacidad, o 0 si no la informa.
+func limitesBuzon(almacen AlmacenBandejas) ([]int, int) {
+	conCapacidad, ok := almacen.(AlmacenConCapacidad)
+	if !ok || conCapacidad.Capacidad() <= 0 {
+		return limitesBuzonSinCapacidad, 0
+	}
+	capacidad := conCapacidad.Capacidad()
+	limites := []int{}
+	for _, fraccion := range fraccionesBuzon {
+		limite := int(fraccion * float64(capacidad))
+		if len(limites) == 0 || limite > limites[len(limites)-1] {
+			limites = append(limites, limite)
+		}
+	}
+	return limites, capacidad
+}
+
+// La cantidad de códigos de gRPC, de codes.OK a codes.Unauthenticated.
+const CANTIDAD_CODIGOS = int(codes.Unauthenticated) + 1
+
+// Las llamadas a un método: cuántas terminaron con cada código y cuánto duraron. Los
+// contadores se actualizan con sync/atomic para no serializar las llamadas.
+type metricasMetodo struct {
+	sumaNanosegundos uint64
+	codigos          [CANTIDAD_CODIGOS]uint64
+	// la cantidad de llamadas de cada intervalo de limitesDuracion, sin acumular; el último
+	// es el de las que superan el mayor límite
+	intervalos []uint64
+}
+
+// Los contadores que el servidor acumula mientras atiende: las llamadas de cada método,
+// los fallos de autenticación, los mensajes y las copias perdidos por el desborde de las
+// bandejas y los fallos al anotar los descartes en el diario.
+// Lo que se puede calcular del estado del servidor, como los usuarios conectados, se
+// calcula al exportar las métricas.
+type metricas struct {
+	rechazados          uint64
+	descartadosAntiguos uint64
+	descartadosNuevos   uint64
+	copiasDescartadas   uint64
+	fallosDiario        uint64
+	// de método a *metricasMetodo
+	metodos sync.Map
+	// de razón a *uint64
+	fallosAutenticacion sync.Map
+}
+
+func nuevasMetricas() *metricas {
+	return &metricas{}
+}
+
+// Registra una llamada al método indicado que empezó en `inicio` y terminó con `err`. Las
+// que fallan con codes.Unauthenticated cuentan además como fallos de autenticación.
+func (m *metricas) llamada(metodo string, inicio time.Time, err error) {
+	valor, ok := m.metodos.Load(metodo)
+	if !ok {
+		valor, _ = m.metodos.LoadOrStore(metodo, &metricasMetodo{intervalos: make([]uint64, len(limitesDuracion)+1)})
+	}
+	metodoActual := valor.(*metricasMetodo)
+
+	duracion := time.Since(inicio)
+	intervalo := sort.SearchFloat64s(limitesDuracion, duracion.Seconds())
+	atomic.AddUint64(&metodoActual.intervalos[intervalo], 1)
+	atomic.AddUint64(&metodoActual.sumaNanosegundos, uint64(duracion))
+
+	codigo := status.Code(err)
+	if int(codigo) < CANTIDAD_CODIGOS {
+		atomic.AddUint64(&metodoActual.codigos[codigo], 1)
+	}
+	if codigo == codes.Unauthenticated {
+		razon := RazonError(err)
+		contador, ok := m.fallosAutenticacion.Load(razon)
+		if !ok {
+			contador, _ = m.fallosAutenticacion.LoadOrStore(razon, new(uint64))
+		}
+		atomic.AddUint64(contador.(*uint64), 1)
+	}
+}
+
+// Registra el resultado de depositar un mensaje en una bandeja: los mensajes antiguos que
+// se descartaron para hacerle lugar, si el mensaje mismo se descartó y si se rechazó
+// porque la bandeja estaba llena.
+func (m *metricas) deposito(entregado bool, descartados int, err error) {
+	atomic.AddUint64(&m.descartadosAntiguos, uint64(descartados))
+	if err == nil && !entregado {
+		atomic.AddUint64(&m.descartadosNuevos, 1)
+	}
+	if RazonError(err) == RAZON_BANDEJA_LLENA {
+		atomic.AddUint64(&m.rechazados, 1)
+	}
+}
+
+// Registra que se descartaron copias para una sesión que acumuló LARGO_BUZON sin
+// retirarlas.
+func (m *metricas) descarteCopias(cantidad int) {
+	atomic.AddUint64(&m.copiasDescartadas, uint64(cantidad))
+}
+
+// Registra que no se pudo anotar en el diario que un mensaje salió de una bandeja, por lo
+// que volverá a ella al reiniciar el servidor.
+func (m *metricas) falloDiario() {
+	atomic.AddUint64(&m.fallosDiario, 1)
+}
+
+// Escribe las métricas en el formato de texto de Prometheus.
+type escritorMetricas struct {
+	salida io.Writer
+}
+
+func (e escritorMetricas) encabezado(nombre string, tipo string, ayuda string) {
+	fmt.Fprintf(e.salida, "# HELP %s %s\n# TYPE %s %s\n", nombre, ayuda, nombre, tipo)
+}
+
+// Escapa los valores de las etiquetas como pide el formato de exposición de Prometheus:
+// solo la barra invertida, las comillas dobles y el salto de línea.
+var escaparEtiqueta = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// Escribe una muestra con las etiquetas indicadas, dadas como pares nombre y valor.
+func (e escritorMetricas) muestra(nombre string, valor interface{}, etiquetas ...string) {
+	if len(etiquetas) == 0 {
+		fmt.Fprintf(e.salida, "%s %v\n", nombre, valor)
+		return
+	}
+	pares := []string{}
+	for i := 0; i+1 < len(etiquetas); i += 2 {
+		pares = append(pares, fmt.Sprintf("%s=\"%s\"", etiquetas[i], escaparEtiqueta.Replace(etiquetas[i+1])))
+	}
+	fmt.Fprintf(e.salida, "%s{%s} %v\n", nombre, strings.Join(pares, ","), valor)
+}
+
+// Escribe un histograma a partir de la cantidad de observaciones de cada intervalo, sin
+// acumular, con un intervalo más que límites para las que superan el mayor.
+func (e escritorMetricas) histograma(nombre string, limites []string, intervalos []uint64, suma interface{}, etiquetas ...string) {
+	var acumulado uint64
+	for i, limite := range limites {
+		acumulado += intervalos[i]
+		e.muestra(nombre+"_bucket", acumulado, append(etiquetas, "le", limite)...)
+	}
+	acumulado += intervalos[len(limites)]
+	e.muestra(nombre+"_bucket", acumulado, append(etiquetas, "le", "+Inf")...)
+	e.muestra(nombre+"_sum", suma, etiquetas...)
+	e.muestra(nombre+"_count", acumulado, etiquetas...)
+}
+
+// Escribe las métricas del servidor en el formato de texto de Prometheus.
+func (s *Servidor) EscribirMetricas(salida io.Writer) {
+	e := escritorMetricas{salida: salida}
+	m := s.metricas
+
+	metodos := []string{}
+	m.metodos.Range(func(clave, _ interface{}) bool {
+		metodos = append(metodos, clave.(string))
+		return true
+	})
+	sort.Strings(metodos)
+
+	e.encabezado("mensajero_rpc_llamadas_total", "counter", "Llamadas atendidas por método y código de respuesta.")
+	for _, metodo := range metodos {
+		valor, _ := m.metodos.Load(metodo)
+		for codigo := 0; codigo < CANTIDAD_CODIGOS; codigo++ {
+			if cantidad := atomic.LoadUint64(&valor.(*metricasMetodo).codigos[codigo]); cantidad > 0 {
+				e.muestra("mensajero_rpc_llamadas_total", cantidad, "metodo", metodo, "codigo", codes.Code(codigo).String())
+			}
+		}
+	}
+
+	limites := []string{}
+	for _, limite := range limitesDuracion {
+		limites = append(limites, fmt.Sprint(limite))
+	}
+	e.encabezado("mensajero_rpc_duracion_segundos", "histogram", "Duración de las llamadas por método.")
+	for _, metodo := range metodos {
+		valor, _ := m.metodos.Load(metodo)
+		metodoActual := valor.(*metricasMetodo)
+		intervalos := make([]uint64, len(metodoActual.intervalos))
+		for i := range intervalos {
+			intervalos[i] = atomic.LoadUint64(&metodoActual.intervalos[i])
+		}
+		suma := time.Duration(atomic.LoadUint64(&metodoActual.sumaNanosegundos)).Seconds()
+		e.histograma("mensajero_rpc_duracion_segundos", limites, intervalos, suma, "metodo", metodo)
+	}
+
+	e.encabezado("mensajero_autenticacion_fallos_total", "counter", "Llamadas rechazadas con UNAUTHENTICATED, por razón.")
+	razones := []string{}
+	m.fallosAutenticacion.Range(func(clave, _ interface{}) bool {
+		razones = append(razones, clave.(string))
+		return true
+	})
+	sort.Strings(razones)
+	for _, razon := range razones {
+		contador, _ := m.fallosAutenticacion.Load(razon)
+		e.muestra("mensajero_autenticacion_fallos_total", atomic.LoadUint64(contador.(*uint64)), "razon", razon)
+	}
+
+	e.encabezado("mensajero_mensajes_rechazados_total", "counter", "Mensajes rechazados porque la bandeja del destinatario estaba llena.")
+	e.muestra("mensajero_mensajes_rechazados_total", atomic.LoadUint64(&m.rechazados))
+	e.encabezado("mensajero_mensajes_descartados_total", "counter", "Mensajes descartados por la política de desborde del destinatario.")
+	e.muestra("mensajero_mensajes_descartados_total", atomic.LoadUint64(&m.descartadosAntiguos), "politica", "descartar-antiguo")
+	e.muestra("mensajero_mensajes_descartados_total", atomic.LoadUint64(&m.descartadosNuevos), "politica", "descartar-nuevo")
+	e.encabezado("mensajero_copias_descartadas_total", "counter", "Copias para las otras sesiones de un usuario descartadas porque la sesión no las retiraba.")
+	e.muestra("mensajero_copias_descartadas_total", atomic.LoadUint64(&m.copiasDescartadas))
+	e.encabezado("mensajero_diario_fallos_total", "counter", "Descartes que no se pudieron anotar en el diario; sus mensajes vuelven a la bandeja al reiniciar.")
+	e.muestra("mensajero_diario_fallos_total", atomic.LoadUint64(&m.fallosDiario))
+
+	e.encabezado("mensajero_usuarios_conectados", "gauge", "Usuarios con al menos una sesión vigente.")
+	e.muestra("mensajero_usuarios_conectados", len(s.TablaAutenticacionUsuario.Usuarios()))
+	e.encabezado("mensajero_sesiones", "gauge", "Sesiones vigentes.")
+	e.muestra("mensajero_sesiones", s.TablaAutenticacionUsuario.Largo())
+
+	// la profundidad de cada bandeja, sin los mensajes volcados a disco, que no ocupan lugar
+	limitesProfundidad, capacidad := limitesBuzon(s.BandejasEntrada)
+	etiquetasBuzon := make([]string, len(limitesProfundidad))
+	for i, limite := range limitesProfundidad {
+		etiquetasBuzon[i] = fmt.Sprint(limite)
+	}
+	intervalosBuzon := make([]uint64, len(limitesProfundidad)+1)
+	profundidadTotal, pendientes := 0, 0
+	for _, usuario := range s.Directorio.Usuarios() {
+		bandejaEntrada, ok := s.BandejasEntrada.Bandeja(usuario)
+		if !ok {
+			continue
+		}
+		profundidad := bandejaEntrada.Largo()
+		intervalosBuzon[sort.SearchInts(limitesProfundidad, profundidad)]++
+		profundidadTotal += profundidad
+		pendientes += s.pendientes(usuario)
+	}
+	e.encabezado("mensajero_mensajes_pendientes", "gauge", "Mensajes que esperan en las bandejas y en disco a que los retiren sus destinatarios.")
+	e.muestra("mensajero_mensajes_pendientes", pendientes)
+	if capacidad > 0 {
+		e.encabezado("mensajero_bandeja_capacidad", "gauge", "Capacidad de cada bandeja de entrada del almacén.")
+		e.muestra("mensajero_bandeja_capacidad", capacidad)
+	}
+	e.encabezado("mensajero_bandeja_profundidad", "histogram", "Mensajes en cada bandeja de entrada.")
+	e.histograma("mensajero_bandeja_profundidad", etiquetasBuzon, intervalosBuzon, profundidadTotal)
+}
+
+// Devuelve un manejador de HTTP que sirve las métricas del servidor en el formato de
+// texto de Prometheus.
+func (s *Servidor) ManejadorMetricas() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		s.EscribirMetricas(w)
+	})
+}
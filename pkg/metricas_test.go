@@ -0,0 +1,82 @@
+package pkg
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Los intervalos de los histogramas se exportan acumulados, y cada llamada cae en el
+// primero cuyo límite no supera.
+func TestHistogramaMetricas(t *testing.T) {
+	m := nuevasMetricas()
+	m.llamada("/m/A", time.Now(), nil)
+	m.llamada("/m/A", time.Now().Add(-2*time.Second), nuevoError(codes.Unauthenticated, RAZON_SESION_VENCIDA, nil, "vencida"))
+	m.llamada("/m/A", time.Now().Add(-time.Minute), nil)
+	m.deposito(true, 2, nil)
+	m.descarteCopias(3)
+
+	s := NuevoServidor(ConInactividadMaxima(0))
+	defer s.Cerrar()
+	s.metricas = m
+	var salida bytes.Buffer
+	s.EscribirMetricas(&salida)
+
+	for _, linea := range []string{
+		`mensajero_rpc_llamadas_total{metodo="/m/A",codigo="OK"} 2`,
+		`mensajero_rpc_llamadas_total{metodo="/m/A",codigo="Unauthenticated"} 1`,
+		`mensajero_rpc_duracion_segundos_bucket{metodo="/m/A",le="0.0005"} 1`,
+		`mensajero_rpc_duracion_segundos_bucket{metodo="/m/A",le="1"} 1`,
+		`mensajero_rpc_duracion_segundos_bucket{metodo="/m/A",le="5"} 2`,
+		`mensajero_rpc_duracion_segundos_bucket{metodo="/m/A",le="30"} 2`,
+		`mensajero_rpc_duracion_segundos_bucket{metodo="/m/A",le="+Inf"} 3`,
+		`mensajero_rpc_duracion_segundos_count{metodo="/m/A"} 3`,
+		`mensajero_autenticacion_fallos_total{razon="SESION_VENCIDA"} 1`,
+		`mensajero_mensajes_descartados_total{politica="descartar-antiguo"} 2`,
+		`mensajero_bandeja_profundidad_count 0`,
+		`mensajero_copias_descartadas_total 3`,
+	} {
+		if !strings.Contains(salida.String(), linea+"\n") {
+			t.Errorf("Se esperaba la línea %s en las métricas:\n%s", linea, salida.String())
+		}
+	}
+}
+
+// Los valores de las etiquetas escapan solo la barra invertida, las comillas y el salto
+// de línea, no los demás caracteres como haría %q.
+func TestMetricasEscapanEtiquetas(t *testing.T) {
+	var salida bytes.Buffer
+	escritorMetricas{salida: &salida}.muestra("m", 1, "e", "a\\b\"c\nd\tñ")
+	if esperada := "m{e=\"a\\\\b\\\"c\\nd\tñ\"} 1\n"; salida.String() != esperada {
+		t.Errorf("Se esperaba %q, se obtuvo %q", esperada, salida.String())
+	}
+}
+
+// La profundidad de las bandejas se mide en fracciones de la capacidad del almacén, o en
+// mensajes si el almacén no la informa.
+func TestMetricasCapacidadBandejas(t *testing.T) {
+	casos := []struct {
+		almacen   AlmacenBandejas
+		presentes []string
+		ausente   string
+	}{
+		{NuevoAlmacenBandejasMemoria(20), []string{"mensajero_bandeja_capacidad 20\n", `le="2"}`, `le="20"}`}, `le="102"}`},
+		{almacenSinLugar{NuevoAlmacenBandejasMemoria(20)}, []string{`le="10"}`, `le="10000"}`}, "mensajero_bandeja_capacidad"},
+	}
+	for _, caso := range casos {
+		s := NuevoServidor(ConAlmacenBandejas(caso.almacen), ConInactividadMaxima(0))
+		var salida bytes.Buffer
+		s.EscribirMetricas(&salida)
+		for _, presente := range caso.presentes {
+			if !strings.Contains(salida.String(), presente) {
+				t.Errorf("Se esperaba %s en las métricas:\n%s", presente, salida.String())
+			}
+		}
+		if strings.Contains(salida.String(), caso.ausente) {
+			t.Errorf("No se esperaba %s en las métricas:\n%s", caso.ausente, salida.String())
+		}
+	}
+}
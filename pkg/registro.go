@@ -0,0 +1,298 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// La clave de los metadatos con el identificador de la solicitud. El servidor usa el que
+// envía el cliente o, si no envía uno, genera uno nuevo, y lo devuelve en los encabezados
+// de la respuesta para relacionar la llamada con su línea en el registro.
+const CLAVE_ID_SOLICITUD = "id-solicitud"
+
+// El largo máximo de un identificador de solicitud enviado por el cliente; los más largos
+// se reemplazan por uno nuevo.
+const LARGO_ID_SOLICITUD = 64
+
+// El formato de las líneas del registro.
+type FormatoRegistro int
+
+const (
+	// Pares clave=valor separados por espacios, como `metodo=/mensajero.Mensajero/Enviar`
+	FormatoLogfmt FormatoRegistro = iota
+	// Un objeto JSON por línea
+	FormatoJSON
+)
+
+// Interpreta los valores aceptados por la opción -registro-formato de cmd/servidor.
+func ParsearFormatoRegistro(valor string) (FormatoRegistro, error) {
+	switch valor {
+	case "logfmt":
+		return FormatoLogfmt, nil
+	case "json":
+		return FormatoJSON, nil
+	}
+	return 0, fmt.Errorf("formato de registro desconocido %q, se esperaba logfmt o json", valor)
+}
+
+// La importancia de una línea del registro. El registro solo escribe las líneas de su
+// nivel o de uno mayor.
+type NivelRegistro int
+
+const (
+	// Además de cada llamada, la solicitud que la originó
+	NivelDepuracion NivelRegistro = iota
+	// Cada llamada
+	NivelInfo
+	// Las llamadas que fallaron por un error del cliente, como un token vencido o un
+	// destinatario inexistente
+	NivelAdvertencia
+	// Las llamadas que fallaron por un error del servidor
+	NivelError
+	// Ninguna llamada
+	NivelNinguno
+)
+
+var nombresNivel = map[NivelRegistro]string{
+	NivelDepuracion:  "depuracion",
+	NivelInfo:        "info",
+	NivelAdvertencia: "advertencia",
+	NivelError:       "error",
+	NivelNinguno:     "ninguno",
+}
+
+func (n NivelRegistro) String() string {
+	return nombresNivel[n]
+}
+
+// Interpreta los valores aceptados por la opción -registro-nivel de cmd/servidor.
+func ParsearNivelRegistro(valor string) (NivelRegistro, error) {
+	for nivel, nombre := range nombresNivel {
+		if nombre == valor {
+			return nivel, nil
+		}
+	}
+	return 0, fmt.Errorf("nivel de registro desconocido %q, se esperaba depuracion, info, advertencia, error o ninguno", valor)
+}
+
+type OpcionesRegistro struct {
+	Formato FormatoRegistro
+	Nivel   NivelRegistro
+	// Si las solicitudes registradas con NivelDepuracion muestran el cuerpo de los
+	// mensajes. Las contraseñas y los tokens no se muestran nunca.
+	MostrarCuerpos bool
+}
+
+var OpcionesRegistroPredeterminadas = OpcionesRegistro{
+	Formato: FormatoLogfmt,
+	Nivel:   NivelInfo,
+}
+
+// Registro escribe una línea por cada llamada que atiende el servidor, con el método, el
+// usuario autenticado, la dirección del cliente, la duración, el código de la respuesta y
+// el identificador de la solicitud. Es seguro para uso concurrente.
+type Registro struct {
+	opciones OpcionesRegistro
+	candado  sync.Mutex
+	salida   io.Writer
+}
+
+// Crea un registro que escribe en `salida`.
+func NuevoRegistro(salida io.Writer, opciones OpcionesRegistro) *Registro {
+	return &Registro{opciones: opciones, salida: salida}
+}
+
+// Los campos que se ocultan siempre en las solicitudes registradas.
+var camposSecretos = map[protoreflect.Name]bool{
+	"contrasena":    true,
+	"token":         true,
+	"tokenAnterior": true,
+}
+
+// El texto con el que se reemplazan los campos ocultos.
+const OCULTO = "[oculto]"
+
+// Devuelve una copia de la solicitud sin contraseñas ni tokens y, salvo que se indique
+// lo contrario, sin el cuerpo de los mensajes.
+func ocultarCampos(solicitud proto.Message, mostrarCuerpos bool) proto.Message {
+	copia := proto.Clone(solicitud)
+	ocultarMensaje(copia.ProtoReflect(), mostrarCuerpos)
+	return copia
+}
+
+func ocultarMensaje(mensaje protoreflect.Message, mostrarCuerpos bool) {
+	mensaje.Range(func(campo protoreflect.FieldDescriptor, valor protoreflect.Value) bool {
+		switch {
+		case campo.Kind() == protoreflect.StringKind && !campo.IsList() && !campo.IsMap():
+			if camposSecretos[campo.Name()] || (campo.Name() == "cuerpo" && !mostrarCuerpos) {
+				mensaje.Set(campo, protoreflect.ValueOfString(OCULTO))
+			}
+		case campo.Kind() == protoreflect.MessageKind && campo.IsList():
+			lista := valor.List()
+			for i := 0; i < lista.Len(); i++ {
+				ocultarMensaje(lista.Get(i).Message(), mostrarCuerpos)
+			}
+		case campo.Kind() == protoreflect.MessageKind && !campo.IsMap():
+			ocultarMensaje(valor.Message(), mostrarCuerpos)
+		}
+		return true
+	})
+}
+
+// Una llamada en curso, que se registra al terminar.
+type llamadaRegistrada struct {
+	registro  *Registro
+	metodo    string
+	id        string
+	direccion string
+	inicio    time.Time
+	// el usuario autenticado, o el que intenta conectarse en los métodos públicos
+	usuario string
+}
+
+// Empieza a registrar una llamada al método indicado. El identificador de la solicitud es
+// el de los metadatos de la llamada o uno nuevo.
+func (r *Registro) iniciar(ctx context.Context, metodo string) *llamadaRegistrada {
+	llamada := &llamadaRegistrada{registro: r, metodo: metodo, inicio: time.Now()}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if valores := md.Get(CLAVE_ID_SOLICITUD); len(valores) == 1 && valores[0] != "" && len(valores[0]) <= LARGO_ID_SOLICITUD {
+			llamada.id = valores[0]
+		}
+	}
+	if llamada.id == "" {
+		// sin un identificador aleatorio se usa la hora, que basta para el registro
+		id, err := nuevoId()
+		if err != nil {
+			id = fmt.Sprintf("%x", llamada.inicio.UnixNano())
+		}
+		llamada.id = id
+	}
+	if par, ok := peer.FromContext(ctx); ok && par.Addr != nil {
+		llamada.direccion = par.Addr.String()
+	}
+	return llamada
+}
+
+// Devuelve el nivel con el que se registra una llamada que terminó con el código indicado.
+func nivelCodigo(codigo codes.Code) NivelRegistro {
+	switch codigo {
+	case codes.OK:
+		return NivelInfo
+	case codes.Unknown, codes.Internal, codes.Unavailable, codes.DataLoss, codes.Unimplemented, codes.DeadlineExceeded:
+		return NivelError
+	}
+	return NivelAdvertencia
+}
+
+// Registra la llamada terminada con el error indicado, que puede ser nil. La solicitud,
+// si la hay, solo se registra con NivelDepuracion.
+func (l *llamadaRegistrada) terminar(solicitud interface{}, err error) {
+	r := l.registro
+	codigo := status.Code(err)
+	nivel := nivelCodigo(codigo)
+	if nivel < r.opciones.Nivel {
+		return
+	}
+
+	campos := [][2]string{
+		{"hora", l.inicio.UTC().Format(time.RFC3339Nano)},
+		{"nivel", nivel.String()},
+		{"metodo", l.metodo},
+		{"usuario", l.usuario},
+		{"direccion", l.direccion},
+		{"duracion", time.Since(l.inicio).String()},
+		{"codigo", codigo.String()},
+		{"id_solicitud", l.id},
+	}
+	if err != nil {
+		campos = append(campos, [2]string{"error", status.Convert(err).Message()})
+		if razon := RazonError(err); razon != "" {
+			campos = append(campos, [2]string{"razon", razon})
+		}
+	}
+	if mensaje, ok := solicitud.(proto.Message); ok && r.opciones.Nivel == NivelDepuracion {
+		datos, errJSON := protojson.Marshal(ocultarCampos(mensaje, r.opciones.MostrarCuerpos))
+		if errJSON == nil {
+			campos = append(campos, [2]string{"solicitud", string(datos)})
+		}
+	}
+	r.escribir(campos)
+}
+
+// Registra con el nivel indicado algo que ocurrió fuera de una llamada, o que no es su
+// resultado, como el cierre de una sesión inactiva, con los campos adicionales que lo
+// describen.
+func (r *Registro) evento(nivel NivelRegistro, evento string, campos ...[2]string) {
+	if nivel < r.opciones.Nivel {
+		return
+	}
+	r.escribir(append([][2]string{
+		{"hora", time.Now().UTC().Format(time.RFC3339Nano)},
+		{"nivel", nivel.String()},
+		{"evento", evento},
+	}, campos...))
+}
+
+// Escribe una línea con los campos en el formato del registro, omitiendo los vacíos.
+func (r *Registro) escribir(campos [][2]string) {
+	var linea bytes.Buffer
+	if r.opciones.Formato == FormatoJSON {
+		linea.WriteByte('{')
+	}
+	primero := true
+	for _, campo := range campos {
+		if campo[1] == "" {
+			continue
+		}
+		if !primero {
+			if r.opciones.Formato == FormatoJSON {
+				linea.WriteByte(',')
+			} else {
+				linea.WriteByte(' ')
+			}
+		}
+		primero = false
+		if r.opciones.Formato == FormatoJSON {
+			clave, _ := json.Marshal(campo[0])
+			valor, _ := json.Marshal(campo[1])
+			linea.Write(clave)
+			linea.WriteByte(':')
+			linea.Write(valor)
+		} else {
+			linea.WriteString(campo[0])
+			linea.WriteByte('=')
+			linea.WriteString(valorLogfmt(campo[1]))
+		}
+	}
+	if r.opciones.Formato == FormatoJSON {
+		linea.WriteByte('}')
+	}
+	linea.WriteByte('\n')
+
+	r.candado.Lock()
+	defer r.candado.Unlock()
+	r.salida.Write(linea.Bytes())
+}
+
+// Devuelve el valor como se escribe en logfmt: entre comillas si tiene espacios, comillas
+// o signos igual.
+func valorLogfmt(valor string) string {
+	if strings.ContainsAny(valor, " \t\n\r\"=\\") {
+		return fmt.Sprintf("%q", valor)
+	}
+	return valor
+}
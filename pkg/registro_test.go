@@ -0,0 +1,91 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// Un contexto de llamada con la dirección del cliente y el identificador de solicitud.
+func contextoRegistro(id string) context.Context {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 4321}})
+	return metadata.NewIncomingContext(ctx, metadata.Pairs(CLAVE_ID_SOLICITUD, id))
+}
+
+// Cada llamada se registra en una línea con sus campos, en logfmt o en JSON, y solo si su
+// nivel alcanza el del registro.
+func TestRegistroLlamadas(t *testing.T) {
+	var salida bytes.Buffer
+	registro := NuevoRegistro(&salida, OpcionesRegistroPredeterminadas)
+	llamada := registro.iniciar(contextoRegistro("abc"), "/mensajero.Mensajero/Enviar")
+	llamada.usuario = "ana"
+	llamada.terminar(nil, nuevoError(codes.NotFound, RAZON_USUARIO_INEXISTENTE, nil, "El usuario destino beto no existe"))
+
+	linea := salida.String()
+	for _, campo := range []string{
+		"nivel=advertencia", "metodo=/mensajero.Mensajero/Enviar", "usuario=ana", "direccion=127.0.0.1:4321",
+		"codigo=NotFound", "id_solicitud=abc", `error="El usuario destino beto no existe"`, "razon=USUARIO_INEXISTENTE", "duracion=",
+	} {
+		if !strings.Contains(linea, campo) {
+			t.Errorf("Se esperaba %s en la línea %q", campo, linea)
+		}
+	}
+
+	salida.Reset()
+	registro = NuevoRegistro(&salida, OpcionesRegistro{Formato: FormatoJSON, Nivel: NivelInfo})
+	registro.iniciar(contextoRegistro("def"), "/mensajero.Mensajero/Listar").terminar(nil, nil)
+	var campos map[string]string
+	if err := json.Unmarshal(salida.Bytes(), &campos); err != nil {
+		t.Fatalf("Se esperaba una línea JSON, se obtuvo %q: %s", salida.String(), err)
+	}
+	if campos["codigo"] != "OK" || campos["nivel"] != "info" || campos["id_solicitud"] != "def" || campos["usuario"] != "" {
+		t.Errorf("Campos inesperados en la línea JSON: %v", campos)
+	}
+
+	// sin identificador en los metadatos se genera uno
+	if llamada := registro.iniciar(context.Background(), "/mensajero.Mensajero/Listar"); llamada.id == "" {
+		t.Errorf("Se esperaba un identificador de solicitud nuevo")
+	}
+
+	salida.Reset()
+	registro = NuevoRegistro(&salida, OpcionesRegistro{Formato: FormatoLogfmt, Nivel: NivelAdvertencia})
+	registro.iniciar(contextoRegistro("ghi"), "/mensajero.Mensajero/Listar").terminar(nil, nil)
+	if salida.Len() != 0 {
+		t.Errorf("Se esperaba que una llamada correcta no se registrara con NivelAdvertencia, se obtuvo %q", salida.String())
+	}
+}
+
+// Con NivelDepuracion se registra la solicitud, sin contraseñas ni tokens y, salvo que se
+// pida, sin el cuerpo de los mensajes.
+func TestRegistroOcultaCampos(t *testing.T) {
+	var salida bytes.Buffer
+	registro := NuevoRegistro(&salida, OpcionesRegistro{Nivel: NivelDepuracion})
+	registro.iniciar(contextoRegistro("1"), "/mensajero.Mensajero/Conectar").terminar(&Registracion{UsuarioOrigen: "ana", Contrasena: "secreta", TokenAnterior: "viejo"}, nil)
+	registro.iniciar(contextoRegistro("2"), "/mensajero.Mensajero/Enviar").terminar(&MensajeApp{Usuario: "beto", Cuerpo: "privado"}, nil)
+	for _, secreto := range []string{"secreta", "viejo", "privado"} {
+		if strings.Contains(salida.String(), secreto) {
+			t.Errorf("Se esperaba que %q no apareciera en el registro: %s", secreto, salida.String())
+		}
+	}
+	if !strings.Contains(salida.String(), "beto") || !strings.Contains(salida.String(), OCULTO) {
+		t.Errorf("Se esperaba la solicitud con los campos ocultos en el registro: %s", salida.String())
+	}
+
+	salida.Reset()
+	registro = NuevoRegistro(&salida, OpcionesRegistro{Nivel: NivelDepuracion, MostrarCuerpos: true})
+	mensajes := &MensajesApp{Mensajes: []*MensajeApp{{Usuario: "beto", Cuerpo: "visible"}}}
+	registro.iniciar(contextoRegistro("3"), "/mensajero.Mensajero/Obtener").terminar(mensajes, nil)
+	if !strings.Contains(salida.String(), "visible") {
+		t.Errorf("Se esperaba el cuerpo del mensaje en el registro: %s", salida.String())
+	}
+	if mensajes.Mensajes[0].Cuerpo != "visible" {
+		t.Errorf("Se esperaba que registrar la solicitud no la modificara")
+	}
+}
@@ -0,0 +1,105 @@
+package pkg
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// Lee un conjunto de certificados de autoridades en formato PEM.
+func leerAutoridades(archivo string) (*x509.CertPool, error) {
+	datos, err := os.ReadFile(archivo)
+	if err != nil {
+		return nil, err
+	}
+	autoridades := x509.NewCertPool()
+	if !autoridades.AppendCertsFromPEM(datos) {
+		return nil, fmt.Errorf("%s no contiene certificados PEM", archivo)
+	}
+	return autoridades, nil
+}
+
+// Devuelve las credenciales TLS del servidor, con su certificado y su clave en formato
+// PEM. Si se indica `archivoAutoridadesClientes` se usa TLS mutuo: cada cliente debe
+// presentar un certificado firmado por una de esas autoridades, y el nombre común (CN) del
+// certificado es el nombre con el que el usuario se conecta.
+func CredencialesServidor(archivoCertificado string, archivoClave string, archivoAutoridadesClientes string) (credentials.TransportCredentials, error) {
+	certificado, err := tls.LoadX509KeyPair(archivoCertificado, archivoClave)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo leer el certificado del servidor: %s", err)
+	}
+	configuracion := &tls.Config{
+		Certificates: []tls.Certificate{certificado},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if archivoAutoridadesClientes != "" {
+		autoridades, err := leerAutoridades(archivoAutoridadesClientes)
+		if err != nil {
+			return nil, fmt.Errorf("no se pudieron leer las autoridades de los clientes: %s", err)
+		}
+		configuracion.ClientCAs = autoridades
+		configuracion.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return credentials.NewTLS(configuracion), nil
+}
+
+// Devuelve las credenciales TLS del cliente. El certificado del servidor se verifica con
+// las autoridades de `archivoAutoridades`, o con las del sistema si está vacío. Si se
+// indican `archivoCertificado` y `archivoClave`, el cliente los presenta para el TLS mutuo.
+func CredencialesCliente(archivoAutoridades string, archivoCertificado string, archivoClave string) (credentials.TransportCredentials, error) {
+	configuracion := &tls.Config{MinVersion: tls.VersionTLS12}
+	if archivoAutoridades != "" {
+		autoridades, err := leerAutoridades(archivoAutoridades)
+		if err != nil {
+			return nil, fmt.Errorf("no se pudieron leer las autoridades: %s", err)
+		}
+		configuracion.RootCAs = autoridades
+	}
+	if archivoCertificado != "" || archivoClave != "" {
+		certificado, err := tls.LoadX509KeyPair(archivoCertificado, archivoClave)
+		if err != nil {
+			return nil, fmt.Errorf("no se pudo leer el certificado del cliente: %s", err)
+		}
+		configuracion.Certificates = []tls.Certificate{certificado}
+	}
+	return credentials.NewTLS(configuracion), nil
+}
+
+// Devuelve el nombre común (CN) del certificado en formato PEM, que es el usuario con el
+// que se conecta un cliente que lo presenta.
+func UsuarioCertificado(archivoCertificado string) (string, error) {
+	datos, err := os.ReadFile(archivoCertificado)
+	if err != nil {
+		return "", err
+	}
+	bloque, _ := pem.Decode(datos)
+	if bloque == nil || bloque.Type != "CERTIFICATE" {
+		return "", fmt.Errorf("%s no contiene un certificado PEM", archivoCertificado)
+	}
+	certificado, err := x509.ParseCertificate(bloque.Bytes)
+	if err != nil {
+		return "", err
+	}
+	return certificado.Subject.CommonName, nil
+}
+
+// Devuelve el usuario del certificado que presentó el cliente de la llamada, si la
+// conexión usa TLS mutuo y el servidor verificó el certificado.
+func usuarioDelCertificado(ctx context.Context) (string, bool) {
+	par, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	info, ok := par.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.PeerCertificates) == 0 {
+		return "", false
+	}
+	usuario := info.State.PeerCertificates[0].Subject.CommonName
+	return usuario, usuario != ""
+}
@@ -0,0 +1,129 @@
+package pkg
+
+import (
+	"fmt"
+	"io"
+
+	"google.golang.org/grpc/status"
+)
+
+// Envía con `enviar` los mensajes pendientes para la sesión del usuario, de a lotes de
+// LARGO_LOTE, hasta vaciar su bandeja. Los mensajes enviados quedan reservados como los
+// de Obtener. Si un envío falla, ese mensaje y el resto del lote vuelven al principio de
+// la bandeja, o de las copias de la sesión, y se devuelve el error.
+//
+// Como solo se retira un lote por vez, un cliente lento no acumula mensajes en el
+// servidor: mientras el flujo espera que el cliente lea, los mensajes nuevos quedan en
+// su bandeja sujetos a la política de desborde.
+func (s *Servidor) despachar(usuario string, sesion string, enviar func(*MensajeApp) error) error {
+	for {
+		mensajes, copias, _, err := s.retirarSesion(usuario, sesion, LARGO_LOTE, 0)
+		if err != nil {
+			return err
+		}
+		for i, msg := range mensajes {
+			if err := enviar(msg); err != nil {
+				if copias {
+					s.copias.devolver(sesion, mensajes[i:])
+				} else if errDevolucion := s.liberar(usuario, mensajes[i:]); errDevolucion != nil {
+					s.registro.evento(NivelError, "No se pudieron devolver los mensajes a la bandeja", [2]string{"usuario", usuario}, [2]string{"mensajes", fmt.Sprint(len(mensajes) - i)}, [2]string{"error", errDevolucion.Error()})
+				}
+				return err
+			}
+		}
+		// después de las copias puede haber mensajes en la bandeja
+		if !copias && len(mensajes) < LARGO_LOTE {
+			return nil
+		}
+	}
+}
+
+// Implementación de Suscribir definido en el archivo `.proto`.
+func (s *Servidor) Suscribir(_ *Vacio, flujo Mensajero_SuscribirServer) error {
+	ctx := flujo.Context()
+	usuario := ctx.Value("nombreUsuario").(string)
+	sesion := ctx.Value("idSesion").(string)
+
+	for {
+		// el aviso se pide antes de revisar la bandeja para no perder un mensaje que
+		// llegue entre la revisión y la espera
+		aviso := s.avisos.esperar(usuario)
+		if err := s.despachar(usuario, sesion, flujo.Send); err != nil {
+			return err
+		}
+
+		select {
+		case <-aviso:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Implementación de Conversar definido en el archivo `.proto`.
+// Una gorutina lee los mensajes que envía el cliente y los entrega como lo haría
+// `Enviar`; esta función es la única que escribe en el flujo, ya que gRPC no admite
+// envíos concurrentes, y alterna entre los resultados de esos envíos y los mensajes
+// que llegan a la bandeja del usuario.
+func (s *Servidor) Conversar(flujo Mensajero_ConversarServer) error {
+	ctx := flujo.Context()
+	usuario := ctx.Value("nombreUsuario").(string)
+	sesion := ctx.Value("idSesion").(string)
+
+	// el lector cierra el canal cuando el cliente termina la conversación; errLectura
+	// se asigna antes de cerrarlo
+	resultados := make(chan *ResultadoEnvio, LARGO_LOTE)
+	var errLectura error
+	go func() {
+		defer close(resultados)
+		for {
+			msg, err := flujo.Recv()
+			if err != nil {
+				if err != io.EOF {
+					errLectura = err
+				}
+				return
+			}
+
+			destino := msg.Usuario
+			resultado := &ResultadoEnvio{Usuario: destino}
+			resultado.Ok, err = s.enviar(ctx, usuario, msg)
+			resultado.Id = msg.Id
+			if err != nil {
+				err = aErrorGrpc(err)
+				estado := status.Convert(err)
+				resultado.Codigo, resultado.Error = int32(estado.Code()), estado.Message()
+				resultado.Razon = RazonError(err)
+			}
+
+			select {
+			case resultados <- resultado:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	enviarMensaje := func(msg *MensajeApp) error {
+		return flujo.Send(&EventoConversacion{Evento: &EventoConversacion_Mensaje{Mensaje: msg}})
+	}
+	for {
+		aviso := s.avisos.esperar(usuario)
+		if err := s.despachar(usuario, sesion, enviarMensaje); err != nil {
+			return err
+		}
+
+		select {
+		case <-aviso:
+		case resultado, abierto := <-resultados:
+			if !abierto {
+				return errLectura
+			}
+			if err := flujo.Send(&EventoConversacion{Evento: &EventoConversacion_Resultado{Resultado: resultado}}); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Si el flujo falla a mitad de un lote, el mensaje que no se pudo enviar y los que le
+// siguen vuelven a la bandeja en el mismo orden.
+func TestDespacharDevuelveLoQueNoSeEnvio(t *testing.T) {
+	ruta := filepath.Join(t.TempDir(), "diario")
+	diario := abrirDiarioPrueba(t, ruta, OpcionesDiarioPredeterminadas)
+	defer diario.Cerrar()
+	s := NuevoServidor(ConDiario(diario))
+	defer s.Cerrar()
+
+	s.Directorio.Registrar("ana")
+	if err := s.crearBandeja("ana"); err != nil {
+		t.Fatalf("No se pudo crear la bandeja: %s", err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := s.enviar(context.Background(), "beto", &MensajeApp{Usuario: "ana", Cuerpo: fmt.Sprintf("%d", i)}); err != nil {
+			t.Fatalf("No se pudo enviar: %s", err)
+		}
+	}
+
+	enviados := []*MensajeApp{}
+	errFlujo := errors.New("el cliente se desconectó")
+	err := s.despachar("ana", "sesion", func(msg *MensajeApp) error {
+		if len(enviados) == 2 {
+			return errFlujo
+		}
+		enviados = append(enviados, msg)
+		return nil
+	})
+	if err != errFlujo {
+		t.Errorf("Se esperaba el error del flujo, se obtuvo %+v", err)
+	}
+	if obtenido := fmt.Sprint(cuerpos(enviados)); obtenido != "[0 1]" {
+		t.Errorf("Se esperaba enviar [0 1], se envió %s", obtenido)
+	}
+
+	// un mensaje que llega después queda detrás de los devueltos
+	s.enviar(context.Background(), "beto", &MensajeApp{Usuario: "ana", Cuerpo: "5"})
+
+	// los enviados siguen pendientes en el diario hasta que se confirman
+	estado, err := diario.Estado()
+	if err != nil {
+		t.Fatalf("No se pudo leer el estado: %s", err)
+	}
+	if obtenido := fmt.Sprint(cuerpos(estado["ana"])); obtenido != "[0 1 2 3 4 5]" {
+		t.Errorf("Se esperaban los mensajes [0 1 2 3 4 5] en el diario, se obtuvo %s", obtenido)
+	}
+	if confirmados, err := s.confirmar("ana", "sesion", []string{enviados[0].Id, enviados[1].Id}); confirmados != 2 || err != nil {
+		t.Errorf("Se esperaba confirmar los 2 mensajes enviados, se confirmaron %d con error %+v", confirmados, err)
+	}
+	estado, _ = diario.Estado()
+	if obtenido := fmt.Sprint(cuerpos(estado["ana"])); obtenido != "[2 3 4 5]" {
+		t.Errorf("Se esperaban los mensajes [2 3 4 5] en el diario, se obtuvo %s", obtenido)
+	}
+	restantes, _, err := s.retirar("ana", "sesion", LARGO_LOTE, 0)
+	if err != nil {
+		t.Fatalf("No se pudo retirar: %s", err)
+	}
+	if obtenido := fmt.Sprint(cuerpos(restantes)); obtenido != "[2 3 4 5]" {
+		t.Errorf("Se esperaban los mensajes [2 3 4 5] en la bandeja, se obtuvo %s", obtenido)
+	}
+}
+
+// Un flujo de prueba que solo tiene contexto.
+type flujoPrueba struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (f *flujoPrueba) Context() context.Context {
+	return f.ctx
+}
+
+func (f *flujoPrueba) SetHeader(metadata.MD) error {
+	return nil
+}
+
+// Los interceptores de llamadas unarias y de flujos aceptan y rechazan los mismos tokens,
+// y dejan en el contexto el mismo usuario y la misma sesión.
+func TestInterceptores(t *testing.T) {
+	s := NuevoServidor(ConInactividadMaxima(0))
+	s.TablaAutenticacionUsuario.Abrir("ana", "sesion", "token", time.Now().Add(time.Hour))
+
+	casos := []struct {
+		nombre string
+		ctx    context.Context
+		metodo string
+		codigo codes.Code
+	}{
+		{"sin metadatos", context.Background(), "/mensajero.Mensajero/Suscribir", codes.Unauthenticated},
+		{"sin token", metadata.NewIncomingContext(context.Background(), metadata.Pairs()), "/mensajero.Mensajero/Suscribir", codes.Unauthenticated},
+		{"token desconocido", metadata.NewIncomingContext(context.Background(), metadata.Pairs("token", "otro")), "/mensajero.Mensajero/Suscribir", codes.Unauthenticated},
+		{"token válido", metadata.NewIncomingContext(context.Background(), metadata.Pairs("token", "token")), "/mensajero.Mensajero/Suscribir", codes.OK},
+		{"método público", context.Background(), "/mensajero.Mensajero/Conectar", codes.OK},
+	}
+	for _, caso := range casos {
+		var usuarioUnario, usuarioFlujo interface{}
+		_, err := s.Interceptor(caso.ctx, nil, &grpc.UnaryServerInfo{FullMethod: caso.metodo}, func(ctx context.Context, _ interface{}) (interface{}, error) {
+			usuarioUnario = ctx.Value("nombreUsuario")
+			return nil, nil
+		})
+		if status.Code(err) != caso.codigo {
+			t.Errorf("%s: se esperaba %s del interceptor unario, se obtuvo %+v", caso.nombre, caso.codigo, err)
+		}
+		err = s.InterceptorFlujo(nil, &flujoPrueba{ctx: caso.ctx}, &grpc.StreamServerInfo{FullMethod: caso.metodo}, func(_ interface{}, flujo grpc.ServerStream) error {
+			usuarioFlujo = flujo.Context().Value("nombreUsuario")
+			if sesion := flujo.Context().Value("idSesion"); usuarioFlujo != nil && sesion != "sesion" {
+				t.Errorf("%s: se esperaba la sesión en el contexto del flujo, se obtuvo %v", caso.nombre, sesion)
+			}
+			return nil
+		})
+		if status.Code(err) != caso.codigo {
+			t.Errorf("%s: se esperaba %s del interceptor de flujos, se obtuvo %+v", caso.nombre, caso.codigo, err)
+		}
+		if usuarioUnario != usuarioFlujo {
+			t.Errorf("%s: los interceptores dejaron usuarios distintos: %v y %v", caso.nombre, usuarioUnario, usuarioFlujo)
+		}
+		if caso.nombre == "token válido" && usuarioFlujo != "ana" {
+			t.Errorf("Se esperaba el usuario ana en el contexto, se obtuvo %v", usuarioFlujo)
+		}
+	}
+}
@@ -2,94 +2,568 @@ package pkg
 
 import (
 	"context"
-	"crypto/md5"
+	"crypto/rand"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 const LARGO_LOTE = 50
 const LARGO_BUZON = 1024
 
-// Una función hash para Conectar, úsela para generar nuevos tokens.
-// No se usa en ningún otro lugar.
-func hash(nombre string) (resultado string) {
-	return fmt.Sprintf("%x", md5.Sum([]byte(nombre)))
+// La cantidad máxima predeterminada de mensajes que devuelve ObtenerLimitado, pida el
+// cliente lo que pida.
+const LARGO_LOTE_MAXIMO = 500
+
+// La espera máxima predeterminada de ObtenerLimitado cuando no hay mensajes.
+const ESPERA_MAXIMA = 30 * time.Second
+
+// El plazo predeterminado para confirmar un mensaje entregado antes de que vuelva a la
+// bandeja para entregarse otra vez.
+const PLAZO_VISIBILIDAD = 30 * time.Second
+
+// El plazo predeterminado de un token de autenticación, que el cliente puede extender
+// con Renovar.
+const DURACION_SESION = 24 * time.Hour
+
+// El tiempo predeterminado que puede pasar una sesión sin usarse antes de que el servidor
+// la cierre.
+const INACTIVIDAD_MAXIMA = 2 * time.Minute
+
+// Devuelve un token de autenticación aleatorio para Conectar.
+func nuevoToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(token), nil
 }
 
-// La implementación del servidor
+// La implementación del servidor. Los manejadores de gRPC se ejecutan de manera
+// concurrente, por lo que todo el estado compartido vive en almacenes seguros para
+// ese uso; el servidor se maneja siempre mediante un puntero.
 type Servidor struct {
 	UnimplementedMensajeroServer
-	// Un mapa de tokens de autenticación
-	TablaAutenticacionUsuario map[string]string
-	// Un mapa de los usuarios a los mensajes en su bandeja de entrada.
-	// La bandeja de entrada está modelada como un canal de tamaño MAILBOX_SIZE.
-	BandejasEntrada map[string](chan *MensajeApp)
+	// Las sesiones activas: qué token de autenticación corresponde a cada usuario
+	TablaAutenticacionUsuario *AlmacenSesiones
+	// Los usuarios conocidos, estén conectados o no. Cada uno tiene su bandeja de entrada
+	Directorio *DirectorioUsuarios
+	// Los canales con sus miembros
+	Canales *DirectorioCanales
+	// Las bandejas de entrada de los usuarios. De manera predeterminada cada bandeja
+	// vive en memoria con capacidad para LARGO_BUZON mensajes.
+	BandejasEntrada AlmacenBandejas
+	// Los mensajes enviados y recibidos por cada usuario, que se consultan con Historial
+	Archivo *ArchivoMensajes
+
+	// Las cuentas de los usuarios, si el servidor exige contraseña para conectarse
+	credenciales *AlmacenCredenciales
+	// Cuántas cuentas se pueden crear desde cada dirección
+	limiteAltas *limiteAltas
+	// El diario en disco donde se registran los usuarios y los mensajes, si se configuró uno
+	diario *Diario
+	// Qué hacer cuando la bandeja de un destinatario está llena: la política del
+	// servidor y las particulares de algunos usuarios
+	politicaDesborde PoliticaDesborde
+	politicasUsuario map[string]PoliticaDesborde
+	// Dónde se guardan los mensajes que no entran en la bandeja con PoliticaVolcarADisco
+	volcado *AlmacenVolcado
+	// Cuánto tiempo vale un token de autenticación desde que se emite o se renueva
+	duracionSesion time.Duration
+	// Cuánto tiempo puede pasar una sesión sin usarse antes de cerrarse; 0 si no se cierran
+	inactividadMaxima time.Duration
+	// Detienen al segador de sesiones inactivas al cerrar el servidor
+	detener chan struct{}
+	listo   sync.WaitGroup
+	// Cuánto tiempo queda reservado un mensaje entregado a la espera de su confirmación
+	plazoVisibilidad time.Duration
+	// La cantidad máxima de mensajes que devuelve ObtenerLimitado
+	largoLoteMaximo int
+	// Cuánto puede esperar como máximo ObtenerLimitado a que llegue un mensaje
+	esperaMaxima time.Duration
+	// Los usuarios que pueden difundir mensajes a todos los conectados
+	administradores map[string]bool
+	// Serializan las operaciones sobre la bandeja de cada usuario, de modo que las
+	// políticas de desborde y el diario vean siempre el orden real de la bandeja
+	candadosBandeja *candadosPorUsuario
+	// Despiertan a quienes esperan mensajes nuevos, como las conversaciones abiertas
+	avisos *avisosPorUsuario
+	// Las copias de los mensajes que confirmó una sesión, para las demás sesiones del usuario
+	copias *copiasPorSesion
+	// Los flujos abiertos de cada sesión, para terminarlos cuando la sesión se cierra
+	flujos *flujosPorSesion
+	// Serializa los cambios en los canales para registrarlos en el diario en orden
+	candadoCanales sync.Mutex
+	// Donde se escribe una línea por cada llamada atendida
+	registro *Registro
+	// Los contadores que se exportan con ManejadorMetricas
+	metricas *metricas
 }
 
-func NuevoServidor() Servidor {
-	return Servidor{
-		TablaAutenticacionUsuario: make(map[string]string),
-		BandejasEntrada:           make(map[string](chan *MensajeApp)),
+// Una opción de configuración para `NuevoServidor`.
+type OpcionServidor func(*Servidor)
+
+// Hace que el servidor guarde las bandejas de entrada en el almacén indicado en lugar
+// de mantenerlas en memoria.
+func ConAlmacenBandejas(almacen AlmacenBandejas) OpcionServidor {
+	return func(s *Servidor) {
+		s.BandejasEntrada = almacen
 	}
 }
 
-// Un interceptor del lado del servidor que asigna los tokens de autenticación en nuestro `contexto` a los nombres de usuario.
-// Rechaza las llamadas si no tienen un token de autenticación válido. Nota: hemos hecho nuestro interceptor
-// en este caso un método en nuestra estructura del Servidor para que pueda tener acceso a las variables privadas del Servidor
-// - sin embargo, este no es un requisito estricto para los interceptores en general.
-func (s Servidor) Interceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (respuesta interface{}, err error) {
-	fmt.Println(info.FullMethod)
-	// permite que las llamadas al punto final de Conectar pasen
-	if info.FullMethod == "/mensajero.Mensajero/Conectar" {
-		return handler(ctx, req)
+// Hace que el servidor exija una cuenta del almacén indicado, con su contraseña, para
+// conectarse. Sin un almacén de credenciales cualquiera puede conectarse con cualquier
+// nombre que no esté en uso.
+func ConCredenciales(almacen *AlmacenCredenciales) OpcionServidor {
+	return func(s *Servidor) {
+		s.credenciales = almacen
+	}
+}
+
+// Indica cuántas cuentas se pueden crear con CrearCuenta desde una misma dirección en cada
+// intervalo. De manera predeterminada son ALTAS_POR_INTERVALO en cada INTERVALO_ALTAS; con
+// 0 no hay límite.
+func ConLimiteAltas(altas int, intervalo time.Duration) OpcionServidor {
+	return func(s *Servidor) {
+		s.limiteAltas = nuevoLimiteAltas(altas, intervalo)
+	}
+}
+
+// Hace que el servidor registre en el diario los usuarios conocidos y los mensajes
+// aceptados y confirmados. Al crear el servidor se reconstruyen el directorio de usuarios
+// y las bandejas de entrada a partir del estado guardado en el diario.
+func ConDiario(diario *Diario) OpcionServidor {
+	return func(s *Servidor) {
+		s.diario = diario
 	}
+}
+
+// Indica qué hacer cuando la bandeja de entrada de un destinatario está llena. De
+// manera predeterminada se usa PoliticaRechazar.
+func ConPoliticaDesborde(politica PoliticaDesborde) OpcionServidor {
+	return func(s *Servidor) {
+		s.politicaDesborde = politica
+	}
+}
+
+// Indica qué hacer cuando la bandeja de entrada de un usuario en particular está
+// llena, en lugar de la política del servidor.
+func ConPoliticaDesbordeUsuario(usuario string, politica PoliticaDesborde) OpcionServidor {
+	return func(s *Servidor) {
+		s.politicasUsuario[usuario] = politica
+	}
+}
 
+// Indica dónde guardar los mensajes que no entran en la bandeja de los usuarios con
+// PoliticaVolcarADisco. Sin un almacén de volcado esa política rechaza los mensajes.
+func ConVolcado(volcado *AlmacenVolcado) OpcionServidor {
+	return func(s *Servidor) {
+		s.volcado = volcado
+	}
+}
+
+// Indica cuánto tiempo vale un token de autenticación desde que se emite con Conectar o
+// se renueva con Renovar. De manera predeterminada es DURACION_SESION.
+func ConDuracionSesion(duracion time.Duration) OpcionServidor {
+	return func(s *Servidor) {
+		s.duracionSesion = duracion
+	}
+}
+
+// Indica cuánto tiempo puede pasar una sesión sin que el cliente haga ninguna llamada
+// antes de que el servidor la cierre. De manera predeterminada es INACTIVIDAD_MAXIMA; con
+// 0 las sesiones solo se cierran al vencer.
+func ConInactividadMaxima(inactividad time.Duration) OpcionServidor {
+	return func(s *Servidor) {
+		s.inactividadMaxima = inactividad
+	}
+}
+
+// Indica cuánto tiempo tiene un usuario para confirmar un mensaje que se le entregó
+// antes de que vuelva a su bandeja. De manera predeterminada es PLAZO_VISIBILIDAD.
+func ConPlazoVisibilidad(plazo time.Duration) OpcionServidor {
+	return func(s *Servidor) {
+		s.plazoVisibilidad = plazo
+	}
+}
+
+// Indica la cantidad máxima de mensajes que devuelve ObtenerLimitado. De manera
+// predeterminada es LARGO_LOTE_MAXIMO.
+func ConLargoLoteMaximo(largo int) OpcionServidor {
+	return func(s *Servidor) {
+		s.largoLoteMaximo = largo
+	}
+}
+
+// Indica cuánto puede esperar como máximo ObtenerLimitado a que llegue un mensaje, pida
+// el cliente lo que pida. De manera predeterminada es ESPERA_MAXIMA.
+func ConEsperaMaxima(espera time.Duration) OpcionServidor {
+	return func(s *Servidor) {
+		s.esperaMaxima = espera
+	}
+}
+
+// Indica qué usuarios pueden difundir mensajes con Difundir. De manera predeterminada
+// nadie puede.
+func ConAdministradores(usuarios ...string) OpcionServidor {
+	return func(s *Servidor) {
+		for _, usuario := range usuarios {
+			s.administradores[usuario] = true
+		}
+	}
+}
+
+// Indica cuántos mensajes guarda el servidor en el historial de cada usuario. De manera
+// predeterminada son LARGO_ARCHIVO; con 0 no se guarda ninguno.
+func ConLargoArchivo(largo int) OpcionServidor {
+	return func(s *Servidor) {
+		s.Archivo = NuevoArchivoMensajes(largo)
+	}
+}
+
+// Indica dónde registra el servidor las llamadas que atiende. De manera predeterminada
+// las escribe en la salida estándar con OpcionesRegistroPredeterminadas.
+func ConRegistro(registro *Registro) OpcionServidor {
+	return func(s *Servidor) {
+		s.registro = registro
+	}
+}
+
+func NuevoServidor(opciones ...OpcionServidor) *Servidor {
+	s := &Servidor{
+		TablaAutenticacionUsuario: NuevoAlmacenSesiones(),
+		Directorio:                NuevoDirectorioUsuarios(),
+		Canales:                   NuevoDirectorioCanales(),
+		BandejasEntrada:           NuevoAlmacenBandejasMemoria(LARGO_BUZON),
+		Archivo:                   NuevoArchivoMensajes(LARGO_ARCHIVO),
+		limiteAltas:               nuevoLimiteAltas(ALTAS_POR_INTERVALO, INTERVALO_ALTAS),
+		politicaDesborde:          PoliticaRechazar,
+		politicasUsuario:          make(map[string]PoliticaDesborde),
+		duracionSesion:            DURACION_SESION,
+		inactividadMaxima:         INACTIVIDAD_MAXIMA,
+		detener:                   make(chan struct{}),
+		plazoVisibilidad:          PLAZO_VISIBILIDAD,
+		largoLoteMaximo:           LARGO_LOTE_MAXIMO,
+		esperaMaxima:              ESPERA_MAXIMA,
+		administradores:           make(map[string]bool),
+		candadosBandeja:           nuevosCandadosPorUsuario(),
+		avisos:                    nuevosAvisosPorUsuario(),
+		copias:                    nuevasCopiasPorSesion(),
+		flujos:                    nuevosFlujosPorSesion(),
+		registro:                  NuevoRegistro(os.Stdout, OpcionesRegistroPredeterminadas),
+		metricas:                  nuevasMetricas(),
+	}
+	for _, opcion := range opciones {
+		opcion(s)
+	}
+	if s.diario != nil {
+		if err := s.restaurar(); err != nil {
+			s.registro.evento(NivelError, "No se pudo restaurar el estado del diario", [2]string{"error", err.Error()})
+		}
+		s.restaurarCanales()
+	}
+	if s.inactividadMaxima > 0 {
+		s.listo.Add(1)
+		go s.segarSesiones()
+	}
+	return s
+}
+
+// Detiene las tareas en segundo plano del servidor. Los almacenes que recibió como
+// opciones, como el diario, los cierra quien los abrió.
+func (s *Servidor) Cerrar() {
+	close(s.detener)
+	s.listo.Wait()
+}
+
+// Devuelve el token de autenticación presente en los metadatos de la llamada.
+func tokenDeLlamada(ctx context.Context) (string, error) {
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
-		return nil, errors.New("no se pudieron leer los metadatos de la solicitud")
+		return "", nuevoError(codes.Unauthenticated, RAZON_SIN_TOKEN, nil, "no se pudieron leer los metadatos de la solicitud")
 	}
-
 	// si el token está presente en los metadatos
-	if valores, ok := md["token"]; ok {
-		if len(valores) == 1 {
-			// si el usuario se encuentra presente en s.TablaAutenticacionUsuario
-			if usuario, ok := s.TablaAutenticacionUsuario[valores[0]]; ok {
-				return handler(context.WithValue(context.Background(), "nombreUsuario", usuario), req)
-			}
-		}
+	if valores := md["token"]; len(valores) == 1 {
+		return valores[0], nil
+	}
+	return "", nuevoError(codes.Unauthenticated, RAZON_SIN_TOKEN, nil, "no se proporcionó un token de autenticación")
+}
+
+// Valida el token de autenticación presente en los metadatos de la llamada y devuelve
+// un contexto derivado de `ctx` con el nombre del usuario dueño del token y el
+// identificador de su sesión. Lo usan tanto el interceptor de llamadas unarias como el
+// de flujos. Un token desconocido o vencido se rechaza con codes.Unauthenticated.
+func (s *Servidor) autenticar(ctx context.Context) (context.Context, error) {
+	token, err := tokenDeLlamada(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, errors.New("no se pudo obtener el usuario del token de autenticación, si se proporcionó")
+	// si el usuario se encuentra presente en s.TablaAutenticacionUsuario
+	usuario, sesion, err := s.TablaAutenticacionUsuario.Usuario(token)
+	if err != nil {
+		return nil, errorSesion(err)
+	}
+	s.TablaAutenticacionUsuario.Actividad(usuario, sesion)
+	ctx = context.WithValue(ctx, "nombreUsuario", usuario)
+	return context.WithValue(ctx, "idSesion", sesion), nil
+}
+
+// Convierte el error de AlmacenSesiones para una sesión que ya no vale en el error de
+// gRPC con el que se rechaza la llamada.
+func errorSesion(err error) error {
+	if errors.Is(err, ErrSesionVencida) {
+		return nuevoError(codes.Unauthenticated, RAZON_SESION_VENCIDA, nil, "la sesión venció, vuelva a conectarse")
+	}
+	return nuevoError(codes.Unauthenticated, RAZON_TOKEN_INVALIDO, nil, "no se pudo obtener el usuario del token de autenticación")
+}
+
+// Los métodos que se pueden llamar sin un token de autenticación, ya que son los que lo
+// obtienen.
+var metodosPublicos = map[string]bool{
+	"/mensajero.Mensajero/Conectar":    true,
+	"/mensajero.Mensajero/CrearCuenta": true,
+}
+
+// Devuelve el usuario que intenta conectarse o crear su cuenta en una llamada a un método
+// público, para el registro.
+func usuarioPublico(ctx context.Context, req interface{}) string {
+	if usuario, ok := usuarioDelCertificado(ctx); ok {
+		return usuario
+	}
+	if registracion, ok := req.(*Registracion); ok {
+		return registracion.UsuarioOrigen
+	}
+	return ""
+}
+
+// Un interceptor del lado del servidor que asigna los tokens de autenticación en nuestro `contexto` a los nombres de usuario.
+// Rechaza las llamadas si no tienen un token de autenticación válido. Los errores de los
+// manejadores que no son errores de gRPC se informan como codes.Internal. Cada llamada
+// queda en el registro y en las métricas del servidor, y su identificador de solicitud
+// vuelve al cliente en los encabezados de la respuesta. Nota: hemos hecho nuestro
+// interceptor en este caso un método en nuestra estructura del Servidor para que pueda
+// tener acceso a las variables privadas del Servidor - sin embargo, este no es un
+// requisito estricto para los interceptores en general.
+func (s *Servidor) Interceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (respuesta interface{}, err error) {
+	llamada := s.registro.iniciar(ctx, info.FullMethod)
+	grpc.SetHeader(ctx, metadata.Pairs(CLAVE_ID_SOLICITUD, llamada.id))
+	defer func() {
+		llamada.terminar(req, err)
+		s.metricas.llamada(info.FullMethod, llamada.inicio, err)
+	}()
+
+	// permite que las llamadas a los puntos finales de Conectar y CrearCuenta pasen
+	if metodosPublicos[info.FullMethod] {
+		llamada.usuario = usuarioPublico(ctx, req)
+		respuesta, err = handler(ctx, req)
+		return respuesta, aErrorGrpc(err)
+	}
+
+	ctx, err = s.autenticar(ctx)
+	if err != nil {
+		return nil, err
+	}
+	llamada.usuario = ctx.Value("nombreUsuario").(string)
+	respuesta, err = handler(ctx, req)
+	return respuesta, aErrorGrpc(err)
+}
+
+// Un flujo cuyo contexto lleva el nombre del usuario autenticado.
+type flujoAutenticado struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (f *flujoAutenticado) Context() context.Context {
+	return f.ctx
+}
+
+// El equivalente a `Interceptor` para las llamadas con flujos, como Conversar: valida el
+// token de la misma manera, con los mismos métodos públicos, y deja el nombre del usuario
+// en el contexto del flujo. Sin este interceptor los flujos no tendrían ningún control de
+// acceso. Además termina el flujo con codes.Unauthenticated si su sesión se cierra o vence
+// mientras está abierto. El flujo queda en el registro y en las métricas cuando termina.
+func (s *Servidor) InterceptorFlujo(srv interface{}, flujo grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	llamada := s.registro.iniciar(flujo.Context(), info.FullMethod)
+	flujo.SetHeader(metadata.Pairs(CLAVE_ID_SOLICITUD, llamada.id))
+	defer func() {
+		llamada.terminar(nil, err)
+		s.metricas.llamada(info.FullMethod, llamada.inicio, err)
+	}()
+
+	if metodosPublicos[info.FullMethod] {
+		llamada.usuario = usuarioPublico(flujo.Context(), nil)
+		return aErrorGrpc(handler(srv, flujo))
+	}
+
+	ctx, err := s.autenticar(flujo.Context())
+	if err != nil {
+		return err
+	}
+	llamada.usuario = ctx.Value("nombreUsuario").(string)
+
+	// el flujo termina cuando se cierra su sesión, aunque siga abierto
+	sesion := ctx.Value("idSesion").(string)
+	ctx, terminar := s.flujos.abrir(ctx, sesion)
+	go s.vigilarFlujo(ctx, llamada.usuario, sesion)
+	err = handler(srv, &flujoAutenticado{ServerStream: flujo, ctx: ctx})
+	if errSesion := terminar(); errSesion != nil {
+		err = errSesion
+	}
+	return aErrorGrpc(err)
+}
+
+// Rechaza con codes.InvalidArgument los nombres de usuario vacíos y los que empiezan con
+// PREFIJO_COMANDO o PREFIJO_CANAL, a los que el cliente no podría enviarles mensajes
+// directos.
+func validarUsuario(usuario string) error {
+	if usuario == "" {
+		return nuevoError(codes.InvalidArgument, RAZON_ARGUMENTO_INVALIDO, nil, "el nombre de usuario no puede estar vacío")
+	}
+	if strings.HasPrefix(usuario, PREFIJO_COMANDO) || strings.HasPrefix(usuario, PREFIJO_CANAL) {
+		return nuevoError(codes.InvalidArgument, RAZON_ARGUMENTO_INVALIDO, map[string]string{"usuario": usuario}, "el nombre de usuario no puede empezar con %s ni con %s", PREFIJO_COMANDO, PREFIJO_CANAL)
+	}
+	return nil
 }
 
 // Implementación de Conectar definido en el archivo `.proto`.
 // Convierte el nombre de usuario proporcionado por `Registracion` en un objeto `TokenAutenticacion`.
-// El token devuelto es único para el usuario; si el usuario ya inició sesión,
-// la conexión debe ser rechazada. Esta función crea una entrada correspondiente
-// en `s.TablaAutenticacionUsuario` y `s.BandejasEntrada`.
-func (s Servidor) Conectar(_ context.Context, r *Registracion) (*TokenAutenticacion, error) {
+// El token devuelto es aleatorio y vale por s.duracionSesion; el servidor solo guarda su
+// hash. Si el servidor usa cuentas, la contraseña debe ser la de la cuenta del usuario.
+// Esta función crea una entrada correspondiente en `s.TablaAutenticacionUsuario` y, la
+// primera vez que el usuario se conecta, lo agrega a `s.Directorio` con su bandeja en
+// `s.BandejasEntrada`. Si el usuario ya era conocido conserva su bandeja, con los mensajes
+// que recibió mientras no estaba conectado.
+//
+// Cada conexión abre una sesión nueva, aunque el usuario ya tenga otras vigentes, siempre
+// que pruebe quién es con su contraseña, su certificado o el token de una sesión anterior.
+// Sin esa prueba, como en un servidor sin cuentas, la conexión se rechaza si el usuario ya
+// está conectado: de otro modo cualquiera podría abrir una sesión ajena y leer su bandeja.
+//
+// Un cliente que terminó sin desconectarse puede reanudar su sesión con el token anterior,
+// después de autenticarse como cualquier otra conexión, o cerrar las sesiones que dejó
+// abiertas con `cerrarOtras`, que también requiere haber probado quién es.
+//
+// Si la conexión usa TLS mutuo, el usuario es el nombre común del certificado del cliente,
+// que reemplaza a la contraseña; `r.UsuarioOrigen` puede quedar vacío o debe coincidir.
+func (s *Servidor) Conectar(ctx context.Context, r *Registracion) (*TokenAutenticacion, error) {
+
+	usuario := r.UsuarioOrigen
+	// con TLS mutuo el certificado del cliente dice quién es el usuario
+	usuarioCertificado, conCertificado := usuarioDelCertificado(ctx)
+	if conCertificado {
+		if usuario != "" && usuario != usuarioCertificado {
+			return nil, nuevoError(codes.PermissionDenied, RAZON_SIN_PERMISO, map[string]string{"usuario": usuarioCertificado}, "el certificado del cliente es de %s, no de %s", usuarioCertificado, usuario)
+		}
+		usuario = usuarioCertificado
+	}
+	if err := validarUsuario(usuario); err != nil {
+		return nil, err
+	}
+	if s.credenciales != nil && !conCertificado && !s.credenciales.Verificar(usuario, r.Contrasena) {
+		return nil, nuevoError(codes.Unauthenticated, RAZON_CREDENCIALES_INCORRECTAS, nil, "usuario o contraseña incorrectos")
+	}
+	// sin cuentas ni certificado, solo el token anterior prueba quién es el usuario
+	identificado := s.credenciales != nil || conCertificado || r.TokenAnterior != ""
+
+	token, err := nuevoToken()
+	if err != nil {
+		return nil, nuevoError(codes.Internal, RAZON_INTERNA, nil, "no se pudo generar el token: %s", err)
+	}
+	vence := time.Now().Add(s.duracionSesion)
 
-	token := hash(r.UsuarioOrigen)
+	var idSesion string
+	if r.TokenAnterior != "" {
+		// la sesión reanudada conserva su identificador y con él las copias que esperan
+		// a la sesión
+		idSesion, err = s.TablaAutenticacionUsuario.Reanudar(usuario, r.TokenAnterior, token, vence)
+		if err != nil {
+			return nil, nuevoError(codes.NotFound, RAZON_SESION_INEXISTENTE, nil, "no se pudo reanudar la sesión: %s", err)
+		}
+	} else {
+		idSesion, err = nuevoId()
+		if err != nil {
+			return nil, nuevoError(codes.Internal, RAZON_INTERNA, nil, "no se pudo generar la sesión: %s", err)
+		}
+		if identificado {
+			s.TablaAutenticacionUsuario.Abrir(usuario, idSesion, token, vence)
+		} else if !s.TablaAutenticacionUsuario.AbrirPrimera(usuario, idSesion, token, vence) {
+			return nil, nuevoError(codes.AlreadyExists, RAZON_USUARIO_CONECTADO, map[string]string{"usuario": usuario}, "El usuario %s se encuentra conectado", usuario)
+		}
+	}
+	if r.CerrarOtras && identificado {
+		for _, otra := range s.TablaAutenticacionUsuario.CerrarOtras(usuario, idSesion) {
+			s.descartarSesion(otra)
+		}
+	}
 
-	if _, ok := s.TablaAutenticacionUsuario[token]; !ok {
-		s.TablaAutenticacionUsuario[token] = r.UsuarioOrigen
-		s.BandejasEntrada[r.UsuarioOrigen] = make(chan *MensajeApp, LARGO_BUZON)
+	if s.Directorio.Registrar(usuario) {
+		if err := s.crearBandeja(usuario); err != nil {
+			s.Directorio.Olvidar(usuario)
+			s.TablaAutenticacionUsuario.Cerrar(usuario, idSesion)
+			return nil, nuevoError(codes.Internal, RAZON_INTERNA, nil, "no se pudo crear la bandeja de entrada: %s", err)
+		}
+	}
 
-		return &TokenAutenticacion{
-			Token: token,
-		}, nil
+	return s.tokenAutenticacion(token, vence, idSesion), nil
+
+}
+
+// Implementación de Renovar definido en el archivo `.proto`.
+// El interceptor ya validó el token, pero puede vencer antes de renovarlo.
+func (s *Servidor) Renovar(ctx context.Context, _ *Vacio) (*TokenAutenticacion, error) {
+	token, err := tokenDeLlamada(ctx)
+	if err != nil {
+		return nil, err
+	}
+	vence := time.Now().Add(s.duracionSesion)
+	if err := s.TablaAutenticacionUsuario.Renovar(token, vence); err != nil {
+		razon := RAZON_TOKEN_INVALIDO
+		if errors.Is(err, ErrSesionVencida) {
+			razon = RAZON_SESION_VENCIDA
+		}
+		return nil, nuevoError(codes.Unauthenticated, razon, nil, "no se pudo renovar la sesión: %s", err)
 	}
+	sesion := ctx.Value("idSesion").(string)
+	return s.tokenAutenticacion(token, vence, sesion), nil
+}
 
-	return nil, errors.New("El usuario se encuentra conectado")
+// Arma la respuesta de Conectar y Renovar. El cliente da señales de vida tres veces por
+// plazo de inactividad, de modo que un latido perdido no cierre su sesión.
+func (s *Servidor) tokenAutenticacion(token string, vence time.Time, sesion string) *TokenAutenticacion {
+	respuesta := &TokenAutenticacion{Token: token, Vence: timestamppb.New(vence), Sesion: sesion}
+	if s.inactividadMaxima > 0 {
+		respuesta.Latido = durationpb.New(s.inactividadMaxima / 3)
+	}
+	return respuesta
+}
 
+// Implementación de Latido definido en el archivo `.proto`.
+// El interceptor ya registró la actividad de la sesión al validar el token.
+func (s *Servidor) Latido(_ context.Context, _ *Vacio) (*Correcto, error) {
+	return &Correcto{Ok: true}, nil
 }
 
 // Implementación de Enviar definido en el archivo `.proto`.
 // Debe escribir el mensaje de chat en la bandeja de entrada privada de un usuario de
-// destino en s.BandejasEntrada.
+// destino en s.BandejasEntrada, con el identificador, la fecha y el número de secuencia
+// que le asigna el servidor. El destinatario puede no estar conectado: basta con que
+// esté en s.Directorio; si no lo está, el envío falla con codes.NotFound. Si su bandeja
+// está llena se aplica la política de desborde que le corresponda: el envío puede fallar
+// con codes.ResourceExhausted o, si se descartó el mensaje nuevo, responder con Ok en false.
+// La respuesta lleva el identificador asignado al mensaje.
 //
 // El mensaje de chat debe tener su campo 'Usuario' reemplazado con el usuario remitente
 // (cuando lo reciba inicialmente, tendrá el nombre del destinatario en su lugar).
@@ -99,75 +573,201 @@ func (s Servidor) Conectar(_ context.Context, r *Registracion) (*TokenAutenticac
 //
 // TODO: Implementar `Enviar`. Si se produce algún error, devuelva el mensaje de error
 // que desee.
-func (s Servidor) Enviar(ctx context.Context, msg *MensajeApp) (*Correcto, error) {
+func (s *Servidor) Enviar(ctx context.Context, msg *MensajeApp) (*RespuestaEnviar, error) {
 	// obtengo el usuario remitente del mensaje
 	usuarioRemitente := ctx.Value("nombreUsuario").(string)
+	entregado, err := s.enviar(ctx, usuarioRemitente, msg)
+	if err != nil {
+		return nil, err
+	}
+	// devuelvo un mensaje de confirmación con los datos asignados al mensaje
+	respuesta := &RespuestaEnviar{Ok: entregado, Id: msg.Id}
+	if entregado {
+		respuesta.Fecha, respuesta.Secuencia = msg.Fecha, msg.Secuencia
+	}
+	return respuesta, nil
+}
+
+// Entrega un mensaje del remitente al usuario indicado en `msg.Usuario`, como lo
+// describe `Enviar`, y devuelve si el mensaje quedó para ser entregado. Completa en
+// `msg` los campos que asigna el servidor. Si el mensaje quedó para ser entregado, se
+// guarda también en el historial del remitente.
+func (s *Servidor) enviar(ctx context.Context, usuarioRemitente string, msg *MensajeApp) (bool, error) {
 	// obtengo el usuario destino del mensaje
 	usuarioDestino := msg.Usuario
-	// reemplazo el usuario destino por el usuario remitente
+	if err := s.sellar(usuarioRemitente, msg); err != nil {
+		return false, err
+	}
+	entregado, err := s.entregar(ctx, usuarioDestino, msg)
+	// un mensaje a uno mismo ya quedó en el historial al entregarlo
+	if entregado && usuarioDestino != usuarioRemitente {
+		s.Archivo.Agregar(usuarioRemitente, &EntradaHistorial{Mensaje: msg, Destinatario: usuarioDestino})
+	}
+	return entregado, err
+}
+
+// Prepara un mensaje aceptado del remitente: reemplaza el destinatario por el remitente
+// y asigna el identificador y la fecha. La secuencia se asigna al depositarlo.
+func (s *Servidor) sellar(usuarioRemitente string, msg *MensajeApp) error {
+	id, err := nuevoId()
+	if err != nil {
+		return nuevoError(codes.Internal, RAZON_INTERNA, nil, "no se pudo generar el identificador del mensaje: %s", err)
+	}
 	msg.Usuario = usuarioRemitente
+	msg.Id, msg.Fecha, msg.Secuencia = id, timestamppb.Now(), 0
+	msg.Reentregas, msg.Canal, msg.Difusion = 0, "", false
+	return nil
+}
+
+// Deposita un mensaje ya sellado en la bandeja del destinatario, lo guarda en su
+// historial y despierta a sus flujos abiertos. Devuelve si el mensaje quedó para ser
+// entregado.
+func (s *Servidor) entregar(ctx context.Context, usuarioDestino string, msg *MensajeApp) (bool, error) {
+	// obtengo la bandeja de entrada del usuario destino; un usuario recién registrado
+	// puede estar en el directorio un instante antes de tener su bandeja
+	bandejaEntrada, ok := s.BandejasEntrada.Bandeja(usuarioDestino)
+	if !ok || !s.Directorio.Existe(usuarioDestino) {
+		return false, nuevoError(codes.NotFound, RAZON_USUARIO_INEXISTENTE, map[string]string{"usuario": usuarioDestino}, "El usuario destino %s no existe", usuarioDestino)
+	}
 	// escribo el mensaje en la bandeja de entrada del usuario destino
-	s.BandejasEntrada[usuarioDestino] <- msg
-	// devuelvo un mensaje de confirmación
-	return &Correcto{}, nil
+	entregado, err := s.depositar(ctx, usuarioDestino, bandejaEntrada, msg)
+	if err != nil {
+		return false, err
+	}
+	if entregado {
+		s.Archivo.Agregar(usuarioDestino, &EntradaHistorial{Mensaje: msg, Destinatario: usuarioDestino})
+		// despierto a las conversaciones abiertas del destinatario
+		s.avisos.avisar(usuarioDestino)
+	}
+	return entregado, nil
 }
 
 // Implementación de Obtener definido en el archivo `.proto`.
 // Debe consumir y devolver un número máximo de mensajes de acuerdo a LARGO_LOTE
-// del canal de bandeja de entrada para el usuario actual.
+// del canal de bandeja de entrada para el usuario actual. Los mensajes quedan reservados
+// hasta que el usuario los confirme con Confirmar o venza el plazo de visibilidad, y sus
+// otras sesiones reciben una copia de los que confirme.
 //
 // Sugerencia: use sentencias `select` en un bucle `for` adecuado para consumir del
 // canal mientras haya mensajes restantes.
 //
 // TODO: Implementar Obtener. Si se produce algún error, devuelva el mensaje de error
 // que desee.
-func (s Servidor) Obtener(ctx context.Context, _ *Vacio) (*MensajesApp, error) {
+func (s *Servidor) Obtener(ctx context.Context, _ *Vacio) (*MensajesApp, error) {
 
 	// obtengo el usuario actual
 	usuarioActual := ctx.Value("nombreUsuario").(string)
-	// creo una variable para almacenar los mensajes
-	var mensajes []*MensajeApp
-	// creo una variable para almacenar el número de mensajes que se van a consumir
-	var numeroMensajesConsumidos int
-	// creo una variable para almacenar el número máximo de mensajes que se pueden consumir
-	var numeroMensajesMaximo int = LARGO_LOTE
-	// creo una variable para almacenar el mensaje que se va a consumir
-	var mensaje *MensajeApp
-	// creo una variable para almacenar el canal de bandeja de entrada del usuario actual
-	var bandejaEntrada = s.BandejasEntrada[usuarioActual]
-	// creo una variable para almacenar el número de mensajes que hay en la bandeja de entrada
-	var numeroMensajesBandeja int = len(bandejaEntrada)
-	// si la bandeja de entrada no está vacía
-	if numeroMensajesBandeja > 0 {
-		// mientras haya mensajes en la bandeja de entrada y el número de mensajes consumidos sea menor que el número máximo de mensajes que se pueden consumir
-		for numeroMensajesConsumidos < numeroMensajesMaximo && numeroMensajesBandeja > 0 {
-			// consumo un mensaje de la bandeja de entrada
-			mensaje = <-bandejaEntrada
-			// agrego el mensaje a la lista de mensajes
-			mensajes = append(mensajes, mensaje)
-			// incremento el número de mensajes consumidos
-			numeroMensajesConsumidos++
-			// decremento el número de mensajes en la bandeja de entrada
-			numeroMensajesBandeja--
-		}
+	sesion := ctx.Value("idSesion").(string)
+	// consumo como máximo LARGO_LOTE mensajes de la bandeja de entrada
+	mensajes, _, pendientes, err := s.retirarSesion(usuarioActual, sesion, LARGO_LOTE, 0)
+	if err != nil {
+		return nil, err
 	}
 	// devuelvo la lista de mensajes
 	return &MensajesApp{
-		Mensajes: mensajes,
+		Mensajes:   mensajes,
+		Pendientes: int32(pendientes),
 	}, nil
 
 }
 
+// Implementación de ObtenerLimitado definido en el archivo `.proto`.
+// Funciona como Obtener, con la cantidad de mensajes que pide el usuario, hasta el
+// máximo configurado en el servidor, y el límite de bytes que indique. Si pide una
+// espera y la bandeja está vacía, espera un aviso de mensaje nuevo hasta que venza la
+// espera, limitada a la máxima del servidor, o se cancele la llamada.
+func (s *Servidor) ObtenerLimitado(ctx context.Context, limite *ObtenerConLimite) (*MensajesApp, error) {
+	usuarioActual := ctx.Value("nombreUsuario").(string)
+	sesion := ctx.Value("idSesion").(string)
+
+	if limite.Largo < 0 || limite.MaximoBytes < 0 {
+		return nil, nuevoError(codes.InvalidArgument, RAZON_ARGUMENTO_INVALIDO, nil, "los límites no pueden ser negativos")
+	}
+	var espera time.Duration
+	if limite.Espera != nil {
+		if err := limite.Espera.CheckValid(); err != nil || limite.Espera.AsDuration() < 0 {
+			return nil, nuevoError(codes.InvalidArgument, RAZON_ARGUMENTO_INVALIDO, nil, "la espera debe ser una duración positiva")
+		}
+		espera = limite.Espera.AsDuration()
+	}
+	if espera > s.esperaMaxima {
+		espera = s.esperaMaxima
+	}
+	largo := int(limite.Largo)
+	if largo == 0 {
+		largo = LARGO_LOTE
+	}
+	if largo > s.largoLoteMaximo {
+		largo = s.largoLoteMaximo
+	}
+
+	var vencimiento <-chan time.Time
+	if espera > 0 {
+		temporizador := time.NewTimer(espera)
+		defer temporizador.Stop()
+		vencimiento = temporizador.C
+	}
+	for {
+		// el aviso se pide antes de revisar la bandeja para no perder un mensaje que
+		// llegue entre la revisión y la espera
+		aviso := s.avisos.esperar(usuarioActual)
+		mensajes, _, pendientes, err := s.retirarSesion(usuarioActual, sesion, largo, int(limite.MaximoBytes))
+		if err != nil {
+			return nil, err
+		}
+		if len(mensajes) > 0 || vencimiento == nil {
+			return &MensajesApp{
+				Mensajes:   mensajes,
+				Pendientes: int32(pendientes),
+			}, nil
+		}
+
+		select {
+		case <-aviso:
+		case <-vencimiento:
+			return &MensajesApp{}, nil
+		case <-ctx.Done():
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+	}
+}
+
+// Implementación de Confirmar definido en el archivo `.proto`.
+// Los mensajes confirmados salen de las reservas del usuario y, si hay un diario, se
+// registran como retirados para que no se restauren al reiniciar el servidor. Las otras
+// sesiones del usuario reciben una copia de cada uno.
+func (s *Servidor) Confirmar(ctx context.Context, confirmacion *Confirmacion) (*Correcto, error) {
+	usuario := ctx.Value("nombreUsuario").(string)
+	sesion := ctx.Value("idSesion").(string)
+	confirmados, err := s.confirmar(usuario, sesion, confirmacion.Ids)
+	if err != nil {
+		return nil, err
+	}
+	return &Correcto{Ok: confirmados == len(confirmacion.Ids)}, nil
+}
+
+// Implementación de Difundir definido en el archivo `.proto`.
+// Entrega el mensaje a los usuarios con alguna sesión abierta en
+// `s.TablaAutenticacionUsuario` al momento de la llamada.
+func (s *Servidor) Difundir(ctx context.Context, msg *MensajeApp) (*RespuestaPublicar, error) {
+	usuario := ctx.Value("nombreUsuario").(string)
+	if !s.administradores[usuario] {
+		return nil, nuevoError(codes.PermissionDenied, RAZON_SIN_PERMISO, nil, "El usuario %s no puede difundir mensajes", usuario)
+	}
+
+	if err := s.sellar(usuario, msg); err != nil {
+		return nil, err
+	}
+	msg.Difusion = true
+	return s.repartir(ctx, usuario, msg, s.TablaAutenticacionUsuario.Usuarios()), nil
+}
+
 // Implementación de Listar definido en el archivo `.proto`.
 // Debe devolver el listado de usuarios al momento de la llamada.
-func (s Servidor) Listar(ctx context.Context, _ *Vacio) (*ListaUsuarios, error) {
+func (s *Servidor) Listar(ctx context.Context, _ *Vacio) (*ListaUsuarios, error) {
 
 	u := &ListaUsuarios{
-		Usuarios: []string{},
-	}
-
-	for _, usuario := range s.TablaAutenticacionUsuario {
-		u.Usuarios = append(u.Usuarios, usuario)
+		Usuarios: s.TablaAutenticacionUsuario.Usuarios(),
 	}
 
 	return u, nil
@@ -175,17 +775,14 @@ func (s Servidor) Listar(ctx context.Context, _ *Vacio) (*ListaUsuarios, error)
 }
 
 // Implementación de Desconectar definido en el archivo `.proto`.
-// Debe destruir la bandeja de entrada correspondiente y la entrada en `s.TablaAutenticacionUsuario`.
-func (s Servidor) Desconectar(ctx context.Context, _ *Vacio) (*Correcto, error) {
+// Debe eliminar la sesión de la llamada de `s.TablaAutenticacionUsuario`, invalidando su
+// token; las demás sesiones del usuario siguen abiertas. La bandeja de entrada se
+// conserva, con los mensajes que no se hayan leído, para entregarlos cuando el usuario
+// vuelva a conectarse.
+func (s *Servidor) Desconectar(ctx context.Context, _ *Vacio) (*Correcto, error) {
 	usuario := fmt.Sprintf("%v", ctx.Value("nombreUsuario"))
-	close(s.BandejasEntrada[usuario]) // se asegura de que no se puedan enviar más escrituras en este canal
-	delete(s.BandejasEntrada, usuario)
-
-	for token, u := range s.TablaAutenticacionUsuario {
-		if u == usuario {
-			delete(s.TablaAutenticacionUsuario, token)
-		}
-	}
+	sesion := fmt.Sprintf("%v", ctx.Value("idSesion"))
+	s.cerrarSesion(usuario, sesion)
 
 	return &Correcto{Ok: true}, nil
 }
@@ -0,0 +1,365 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+)
+
+type fragmentoCopias struct {
+	sync.Mutex
+	copias map[string][]*MensajeApp
+	// las copias entregadas a cada sesión que esperan su confirmación
+	reservadas map[string][]reserva
+}
+
+// Las copias de los mensajes que confirmó una sesión de un usuario, a la espera de que las
+// obtengan sus demás sesiones, indexadas por el identificador de cada sesión. Como los
+// mensajes de la bandeja, las copias entregadas quedan reservadas hasta que la sesión las
+// confirme y, si vence el plazo de visibilidad, se le entregan otra vez. Viven solo en
+// memoria: el mensaje original ya se confirmó.
+type copiasPorSesion [NUMERO_FRAGMENTOS]*fragmentoCopias
+
+func nuevasCopiasPorSesion() *copiasPorSesion {
+	var c copiasPorSesion
+	for i := range c {
+		c[i] = &fragmentoCopias{copias: make(map[string][]*MensajeApp), reservadas: make(map[string][]reserva)}
+	}
+	return &c
+}
+
+// Agrega las copias al final de las de la sesión. Si la sesión acumula más de
+// LARGO_BUZON copias se descartan las más antiguas; devuelve cuántas se descartaron.
+func (c *copiasPorSesion) agregar(sesion string, mensajes []*MensajeApp) int {
+	f := c[indiceFragmento(sesion)]
+	f.Lock()
+	defer f.Unlock()
+	copias := append(f.copias[sesion], mensajes...)
+	sobrantes := len(copias) - LARGO_BUZON
+	if sobrantes > 0 {
+		copias = append([]*MensajeApp(nil), copias[sobrantes:]...)
+	}
+	f.copias[sesion] = copias
+	if sobrantes < 0 {
+		return 0
+	}
+	return sobrantes
+}
+
+// Retira y devuelve, en orden, hasta `maximo` copias de la sesión, limitadas a
+// `maximoBytes` como en `retirar`. Las copias quedan reservadas para la sesión hasta
+// `vence`.
+func (c *copiasPorSesion) retirar(sesion string, maximo int, maximoBytes int, vence time.Time) []*MensajeApp {
+	f := c[indiceFragmento(sesion)]
+	f.Lock()
+	defer f.Unlock()
+	copias := f.copias[sesion]
+	if maximo > len(copias) {
+		maximo = len(copias)
+	}
+	corte := cortarPorBytes(copias[:maximo], maximoBytes)
+	retiradas := copias[:corte:corte]
+	if corte == len(copias) {
+		delete(f.copias, sesion)
+	} else {
+		f.copias[sesion] = copias[corte:]
+	}
+	for _, msg := range retiradas {
+		f.reservadas[sesion] = append(f.reservadas[sesion], reserva{msg: msg, sesion: sesion, vence: vence})
+	}
+	return retiradas
+}
+
+// Quita de las copias reservadas para la sesión las de los identificadores indicados y
+// devuelve sus mensajes, en orden. Debe llamarse con el candado del fragmento tomado.
+func (f *fragmentoCopias) quitarReservadas(sesion string, ids []string) []*MensajeApp {
+	buscados := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		buscados[id] = true
+	}
+	var quitadas []*MensajeApp
+	restantes := []reserva{}
+	for _, r := range f.reservadas[sesion] {
+		if buscados[r.msg.Id] {
+			quitadas = append(quitadas, r.msg)
+		} else {
+			restantes = append(restantes, r)
+		}
+	}
+	if len(restantes) == 0 {
+		delete(f.reservadas, sesion)
+	} else {
+		f.reservadas[sesion] = restantes
+	}
+	return quitadas
+}
+
+// Descarta las copias reservadas para la sesión con los identificadores indicados, que la
+// sesión ya recibió. Devuelve cuántas había.
+func (c *copiasPorSesion) confirmar(sesion string, ids []string) int {
+	f := c[indiceFragmento(sesion)]
+	f.Lock()
+	defer f.Unlock()
+	return len(f.quitarReservadas(sesion, ids))
+}
+
+// Vuelve a poner al principio de las copias de la sesión las reservadas que no se llegaron
+// a entregar. Como no se entregaron, no cuentan como reentregas.
+func (c *copiasPorSesion) devolver(sesion string, mensajes []*MensajeApp) {
+	f := c[indiceFragmento(sesion)]
+	f.Lock()
+	defer f.Unlock()
+	ids := make([]string, len(mensajes))
+	for i, msg := range mensajes {
+		ids[i] = msg.Id
+	}
+	f.copias[sesion] = append(f.quitarReservadas(sesion, ids), f.copias[sesion]...)
+}
+
+// Vuelve a poner al principio de las copias de la sesión las reservadas cuyo plazo de
+// visibilidad venció, con su contador de reentregas incrementado, y devuelve cuántas eran.
+func (c *copiasPorSesion) vencer(sesion string) int {
+	f := c[indiceFragmento(sesion)]
+	f.Lock()
+	defer f.Unlock()
+	ahora := time.Now()
+	var vencidas []*MensajeApp
+	restantes := []reserva{}
+	for _, r := range f.reservadas[sesion] {
+		if r.vence.After(ahora) {
+			restantes = append(restantes, r)
+			continue
+		}
+		// como en vencerReservas, se copia el mensaje porque puede estar serializándose
+		msg := proto.Clone(r.msg).(*MensajeApp)
+		msg.Reentregas++
+		vencidas = append(vencidas, msg)
+	}
+	if len(restantes) == 0 {
+		delete(f.reservadas, sesion)
+	} else {
+		f.reservadas[sesion] = restantes
+	}
+	if len(vencidas) > 0 {
+		f.copias[sesion] = append(vencidas, f.copias[sesion]...)
+	}
+	return len(vencidas)
+}
+
+// Devuelve la cantidad de copias que esperan a la sesión, sin contar las reservadas.
+func (c *copiasPorSesion) largo(sesion string) int {
+	f := c[indiceFragmento(sesion)]
+	f.Lock()
+	defer f.Unlock()
+	return len(f.copias[sesion])
+}
+
+// Descarta las copias de una sesión que se cerró, también las reservadas.
+func (c *copiasPorSesion) eliminar(sesion string) {
+	f := c[indiceFragmento(sesion)]
+	f.Lock()
+	delete(f.copias, sesion)
+	delete(f.reservadas, sesion)
+	f.Unlock()
+}
+
+// Retira hasta `maximo` mensajes para una sesión del usuario, como `retirar`. Primero
+// entrega las copias de los mensajes que confirmaron sus otras sesiones y, si no hay,
+// los pendientes de la bandeja del usuario. Tanto las copias como los mensajes quedan
+// reservados para la sesión. Devuelve también si los mensajes son copias y cuántos
+// mensajes quedan pendientes para la sesión.
+func (s *Servidor) retirarSesion(usuario string, sesion string, maximo int, maximoBytes int) ([]*MensajeApp, bool, int, error) {
+	if copias := s.copias.retirar(sesion, maximo, maximoBytes, time.Now().Add(s.plazoVisibilidad)); len(copias) > 0 {
+		time.AfterFunc(s.plazoVisibilidad, func() {
+			if s.copias.vencer(sesion) > 0 {
+				s.avisos.avisar(usuario)
+			}
+		})
+		return copias, true, s.pendientes(usuario) + s.copias.largo(sesion), nil
+	}
+
+	mensajes, pendientes, err := s.retirar(usuario, sesion, maximo, maximoBytes)
+	return mensajes, false, pendientes + s.copias.largo(sesion), err
+}
+
+// Deja una copia de los mensajes confirmados para cada una de las otras sesiones vigentes
+// del usuario, salvo para la que los recibió, y despierta a sus flujos abiertos. Como solo
+// se copian los mensajes confirmados, cada sesión recibe cada mensaje una sola vez aunque
+// el original se haya entregado varias veces. Una sesión que no retira sus copias pierde
+// las más antiguas, lo que queda en el registro y en las métricas del servidor.
+func (s *Servidor) copiarConfirmados(usuario string, confirmados []reserva) {
+	if len(confirmados) == 0 {
+		return
+	}
+	otras := false
+	for _, otra := range s.TablaAutenticacionUsuario.Sesiones(usuario) {
+		var copias []*MensajeApp
+		for _, r := range confirmados {
+			if r.sesion != otra.Id {
+				copias = append(copias, r.msg)
+			}
+		}
+		if len(copias) > 0 {
+			if descartadas := s.copias.agregar(otra.Id, copias); descartadas > 0 {
+				s.metricas.descarteCopias(descartadas)
+				s.registro.evento(NivelAdvertencia, "Se descartaron las copias más antiguas de una sesión que no las retira", [2]string{"usuario", usuario}, [2]string{"sesion", otra.Id}, [2]string{"mensajes", fmt.Sprint(descartadas)})
+			}
+			otras = true
+		}
+	}
+	if otras {
+		s.avisos.avisar(usuario)
+	}
+}
+
+// Implementación de ListarSesiones definido en el archivo `.proto`.
+func (s *Servidor) ListarSesiones(ctx context.Context, _ *Vacio) (*ListaSesiones, error) {
+	usuario := ctx.Value("nombreUsuario").(string)
+	sesion := ctx.Value("idSesion").(string)
+
+	sesiones := s.TablaAutenticacionUsuario.Sesiones(usuario)
+	for _, otra := range sesiones {
+		otra.Actual = otra.Id == sesion
+	}
+	return &ListaSesiones{Sesiones: sesiones}, nil
+}
+
+// Implementación de RevocarSesion definido en el archivo `.proto`.
+// Un usuario solo puede revocar sus propias sesiones.
+func (s *Servidor) RevocarSesion(ctx context.Context, sesion *Sesion) (*Correcto, error) {
+	usuario := ctx.Value("nombreUsuario").(string)
+	return &Correcto{Ok: s.cerrarSesion(usuario, sesion.Id)}, nil
+}
+
+// Cierra una sesión del usuario, descarta las copias que la esperaban y termina sus
+// flujos abiertos. Los mensajes que la sesión tenía reservados vuelven a la bandeja cuando
+// vence su plazo, y la bandeja se conserva para las demás sesiones o para cuando el
+// usuario vuelva a conectarse. Devuelve false si el usuario no tenía esa sesión.
+func (s *Servidor) cerrarSesion(usuario string, sesion string) bool {
+	if !s.TablaAutenticacionUsuario.Cerrar(usuario, sesion) {
+		return false
+	}
+	s.descartarSesion(sesion)
+	return true
+}
+
+// Descarta lo que el servidor guarda para una sesión que ya se cerró en
+// s.TablaAutenticacionUsuario: sus copias y sus flujos abiertos, que terminan con
+// codes.Unauthenticated.
+func (s *Servidor) descartarSesion(sesion string) {
+	s.copias.eliminar(sesion)
+	s.flujos.terminar(sesion, nuevoError(codes.Unauthenticated, RAZON_SESION_INEXISTENTE, nil, "la sesión se cerró, vuelva a conectarse"))
+}
+
+// Cierra periódicamente las sesiones que llevan más de s.inactividadMaxima sin usarse,
+// por ejemplo las de un cliente que terminó sin desconectarse, hasta que se cierre el
+// servidor. La limpieza es la misma que la de Desconectar.
+func (s *Servidor) segarSesiones() {
+	defer s.listo.Done()
+	reloj := time.NewTicker(s.inactividadMaxima / 2)
+	defer reloj.Stop()
+	for {
+		select {
+		case <-reloj.C:
+			limite := time.Now().Add(-s.inactividadMaxima)
+			for _, clave := range s.TablaAutenticacionUsuario.cerrarInactivas(limite) {
+				s.descartarSesion(clave.id)
+				s.registro.evento(NivelInfo, "Se cerró la sesión por inactividad", [2]string{"usuario", clave.usuario}, [2]string{"sesion", clave.id})
+			}
+		case <-s.detener:
+			return
+		}
+	}
+}
+
+// Un flujo abierto de una sesión. err es el error con el que terminó por cerrarse la
+// sesión, si terminó así.
+type flujoSesion struct {
+	cancelar context.CancelFunc
+	err      error
+}
+
+type fragmentoFlujos struct {
+	sync.Mutex
+	flujos map[string]map[*flujoSesion]bool
+}
+
+// Los flujos abiertos, como los de Suscribir y Conversar, indexados por el identificador
+// de su sesión, para terminarlos cuando la sesión se cierra aunque el cliente siga
+// conectado.
+type flujosPorSesion [NUMERO_FRAGMENTOS]*fragmentoFlujos
+
+func nuevosFlujosPorSesion() *flujosPorSesion {
+	var f flujosPorSesion
+	for i := range f {
+		f[i] = &fragmentoFlujos{flujos: make(map[string]map[*flujoSesion]bool)}
+	}
+	return &f
+}
+
+// Registra un flujo de la sesión. Devuelve un contexto derivado de `ctx` que se cancela
+// cuando se termina la sesión, y la función que quita el flujo del registro al terminar,
+// que devuelve el error con el que se terminó la sesión o nil.
+func (f *flujosPorSesion) abrir(ctx context.Context, sesion string) (context.Context, func() error) {
+	ctx, cancelar := context.WithCancel(ctx)
+	flujo := &flujoSesion{cancelar: cancelar}
+	fragmento := f[indiceFragmento(sesion)]
+	fragmento.Lock()
+	if fragmento.flujos[sesion] == nil {
+		fragmento.flujos[sesion] = make(map[*flujoSesion]bool)
+	}
+	fragmento.flujos[sesion][flujo] = true
+	fragmento.Unlock()
+
+	return ctx, func() error {
+		cancelar()
+		fragmento.Lock()
+		defer fragmento.Unlock()
+		delete(fragmento.flujos[sesion], flujo)
+		if len(fragmento.flujos[sesion]) == 0 {
+			delete(fragmento.flujos, sesion)
+		}
+		return flujo.err
+	}
+}
+
+// Termina con `err` los flujos abiertos de la sesión.
+func (f *flujosPorSesion) terminar(sesion string, err error) {
+	fragmento := f[indiceFragmento(sesion)]
+	fragmento.Lock()
+	defer fragmento.Unlock()
+	for flujo := range fragmento.flujos[sesion] {
+		if flujo.err == nil {
+			flujo.err = err
+		}
+		flujo.cancelar()
+	}
+}
+
+// Mantiene al día la sesión de un flujo abierto hasta que `ctx` se cancele: registra su
+// actividad, para que no se cierre por inactividad mientras el flujo espera mensajes, y
+// termina los flujos de la sesión si vence sin que el cliente la renueve.
+func (s *Servidor) vigilarFlujo(ctx context.Context, usuario string, sesion string) {
+	for {
+		vence, err := s.TablaAutenticacionUsuario.Actividad(usuario, sesion)
+		if err != nil {
+			s.flujos.terminar(sesion, errorSesion(err))
+			return
+		}
+		espera := time.Until(vence)
+		if s.inactividadMaxima > 0 && s.inactividadMaxima/2 < espera {
+			espera = s.inactividadMaxima / 2
+		}
+		reloj := time.NewTimer(espera)
+		select {
+		case <-reloj.C:
+		case <-ctx.Done():
+			reloj.Stop()
+			return
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Una cola de mensajes en un archivo: se agrega al final y se lee desde el principio.
+// Cuando se vacía, el archivo se trunca para no crecer indefinidamente.
+type colaDisco struct {
+	candado   sync.Mutex
+	archivo   *os.File
+	lectura   int64
+	escritura int64
+	cantidad  int
+}
+
+// AlmacenVolcado guarda en disco los mensajes que no entran en la bandeja de entrada
+// de un usuario con la política PoliticaVolcarADisco. Cada usuario tiene su propio
+// archivo dentro del directorio del almacén.
+//
+// El volcado no reemplaza al diario: su contenido se descarta al abrirlo, porque al
+// reiniciar el servidor las bandejas se reconstruyen desde el diario.
+type AlmacenVolcado struct {
+	directorio string
+	candado    sync.Mutex
+	colas      map[string]*colaDisco
+}
+
+// Abre un almacén de volcado en el directorio indicado, creándolo si no existe y
+// descartando lo que hubiera quedado de una ejecución anterior.
+func AbrirAlmacenVolcado(directorio string) (*AlmacenVolcado, error) {
+	if err := os.MkdirAll(directorio, 0700); err != nil {
+		return nil, err
+	}
+	anteriores, err := filepath.Glob(filepath.Join(directorio, "*.volcado"))
+	if err != nil {
+		return nil, err
+	}
+	for _, ruta := range anteriores {
+		if err := os.Remove(ruta); err != nil {
+			return nil, err
+		}
+	}
+	return &AlmacenVolcado{directorio: directorio, colas: make(map[string]*colaDisco)}, nil
+}
+
+func (a *AlmacenVolcado) cola(usuario string, crear bool) (*colaDisco, error) {
+	a.candado.Lock()
+	defer a.candado.Unlock()
+	if cola, ok := a.colas[usuario]; ok || !crear {
+		return cola, nil
+	}
+	// el nombre de usuario se codifica para que sea un nombre de archivo válido
+	ruta := filepath.Join(a.directorio, hex.EncodeToString([]byte(usuario))+".volcado")
+	archivo, err := os.OpenFile(ruta, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+	cola := &colaDisco{archivo: archivo}
+	a.colas[usuario] = cola
+	return cola, nil
+}
+
+// Agrega un mensaje al final de la cola del usuario.
+func (a *AlmacenVolcado) Agregar(usuario string, msg *MensajeApp) error {
+	cola, err := a.cola(usuario, true)
+	if err != nil {
+		return err
+	}
+	datos, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	cola.candado.Lock()
+	defer cola.candado.Unlock()
+	var marco bytes.Buffer
+	escribirMarco(&marco, datos)
+	if _, err := cola.archivo.WriteAt(marco.Bytes(), cola.escritura); err != nil {
+		return err
+	}
+	cola.escritura += int64(marco.Len())
+	cola.cantidad++
+	return nil
+}
+
+// Consume y devuelve, en orden de llegada, hasta `maximo` mensajes de la cola del usuario.
+func (a *AlmacenVolcado) Retirar(usuario string, maximo int) ([]*MensajeApp, error) {
+	cola, err := a.cola(usuario, false)
+	if err != nil || cola == nil {
+		return nil, err
+	}
+
+	cola.candado.Lock()
+	defer cola.candado.Unlock()
+	var mensajes []*MensajeApp
+	lector := bufio.NewReader(io.NewSectionReader(cola.archivo, cola.lectura, cola.escritura-cola.lectura))
+	cabecera := make([]byte, largoCabeceraRegistro)
+	for len(mensajes) < maximo && cola.cantidad > 0 {
+		if _, err := io.ReadFull(lector, cabecera); err != nil {
+			return mensajes, err
+		}
+		datos := make([]byte, binary.BigEndian.Uint32(cabecera[0:4]))
+		if _, err := io.ReadFull(lector, datos); err != nil {
+			return mensajes, err
+		}
+		msg := &MensajeApp{}
+		if err := proto.Unmarshal(datos, msg); err != nil {
+			return mensajes, err
+		}
+		mensajes = append(mensajes, msg)
+		cola.lectura += int64(largoCabeceraRegistro + len(datos))
+		cola.cantidad--
+	}
+
+	if cola.cantidad == 0 {
+		cola.lectura, cola.escritura = 0, 0
+		if err := cola.archivo.Truncate(0); err != nil {
+			return mensajes, err
+		}
+	}
+	return mensajes, nil
+}
+
+// Devuelve la cantidad de mensajes en la cola del usuario.
+func (a *AlmacenVolcado) Largo(usuario string) int {
+	cola, _ := a.cola(usuario, false)
+	if cola == nil {
+		return 0
+	}
+	cola.candado.Lock()
+	defer cola.candado.Unlock()
+	return cola.cantidad
+}
+
+// Cierra los archivos del almacén.
+func (a *AlmacenVolcado) Cerrar() error {
+	a.candado.Lock()
+	defer a.candado.Unlock()
+	var primerError error
+	for usuario, cola := range a.colas {
+		if err := cola.archivo.Close(); err != nil && primerError == nil {
+			primerError = err
+		}
+		delete(a.colas, usuario)
+	}
+	return primerError
+}
@@ -0,0 +1,83 @@
+package mensajero
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	mensajero "mensajero/pkg"
+)
+
+// Un almacén de bandejas instrumentado que cuenta los mensajes depositados y retirados
+// y delega el almacenamiento en las bandejas en memoria.
+type almacenContador struct {
+	mensajero.AlmacenBandejas
+	candado     sync.Mutex
+	depositados int
+	retirados   int
+}
+
+type bandejaContadora struct {
+	mensajero.BandejaEntrada
+	almacen *almacenContador
+}
+
+func (a *almacenContador) Crear(usuario string) (mensajero.BandejaEntrada, error) {
+	bandeja, err := a.AlmacenBandejas.Crear(usuario)
+	if err != nil {
+		return nil, err
+	}
+	return &bandejaContadora{BandejaEntrada: bandeja, almacen: a}, nil
+}
+
+func (a *almacenContador) Bandeja(usuario string) (mensajero.BandejaEntrada, bool) {
+	bandeja, ok := a.AlmacenBandejas.Bandeja(usuario)
+	if !ok {
+		return nil, false
+	}
+	return &bandejaContadora{BandejaEntrada: bandeja, almacen: a}, true
+}
+
+func (b *bandejaContadora) Depositar(ctx context.Context, msg *mensajero.MensajeApp) error {
+	b.almacen.candado.Lock()
+	b.almacen.depositados++
+	b.almacen.candado.Unlock()
+	return b.BandejaEntrada.Depositar(ctx, msg)
+}
+
+func (b *bandejaContadora) Retirar(maximo int) ([]*mensajero.MensajeApp, error) {
+	mensajes, err := b.BandejaEntrada.Retirar(maximo)
+	b.almacen.candado.Lock()
+	b.almacen.retirados += len(mensajes)
+	b.almacen.candado.Unlock()
+	return mensajes, err
+}
+
+// Prueba que el servidor usa el almacén de bandejas que se le indica.
+func TestAlmacenBandejasPersonalizado(t *testing.T) {
+
+	usuario := stringAleatorio(12)
+	almacen := &almacenContador{AlmacenBandejas: mensajero.NuevoAlmacenBandejasMemoria(mensajero.LARGO_BUZON)}
+	_, direccion := iniciarServidor(t, mensajero.ConAlmacenBandejas(almacen))
+
+	conexion, cliente, ctx, err := mensajero.ConfigurarCliente(direccion, usuario, 3)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion.Close()
+
+	mensajero.Ejecutar(cliente, ctx, usuario, "uno")
+	mensajero.Ejecutar(cliente, ctx, usuario, "dos")
+	mensajes, err := obtenerSinFechas(cliente, ctx)
+	esperado := fmt.Sprintf("[%s]: uno\n[%s]: dos\n", usuario, usuario)
+	if mensajes != esperado || err != nil {
+		t.Errorf("Se esperaba %q en la llamada a `obtener`, se obtuvo %q con error %+v", esperado, mensajes, err)
+	}
+
+	almacen.candado.Lock()
+	defer almacen.candado.Unlock()
+	if almacen.depositados != 2 || almacen.retirados != 2 {
+		t.Errorf("Se esperaban 2 mensajes depositados y 2 retirados a través del almacén, se obtuvieron %d y %d", almacen.depositados, almacen.retirados)
+	}
+}
@@ -0,0 +1,83 @@
+package mensajero
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	mensajero "mensajero/pkg"
+)
+
+// Probar que se pueden crear canales, unirse, abandonarlos y publicar en ellos
+func TestCanales(t *testing.T) {
+
+	_, direccion := iniciarServidor(t)
+	usuarios := []string{stringAleatorio(12), stringAleatorio(12), stringAleatorio(12)}
+	clientes := []mensajero.MensajeroClient{}
+	contextos := []context.Context{}
+	for _, usuario := range usuarios {
+		conexion, cliente, ctx, err := mensajero.ConfigurarCliente(direccion, usuario, 3)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		defer conexion.Close()
+		clientes = append(clientes, cliente)
+		contextos = append(contextos, ctx)
+	}
+	ejecutar := func(i int, argumentos ...string) (string, error) {
+		return mensajero.Ejecutar(clientes[i], contextos[i], argumentos...)
+	}
+	canal := "#" + stringAleatorio(8)
+
+	if _, err := ejecutar(0, "/crear", canal); err != nil {
+		t.Fatalf("No se pudo crear el canal: %s", err)
+	}
+	if _, err := ejecutar(1, "/crear", canal); err == nil || !strings.Contains(err.Error(), "AlreadyExists") {
+		t.Errorf("Se esperaba AlreadyExists al crear un canal existente, se obtuvo %+v", err)
+	}
+	if _, err := ejecutar(1, "/unirse", canal); err != nil {
+		t.Errorf("No se pudo unir al canal: %s", err)
+	}
+	if _, err := ejecutar(1, "/unirse", "#"+stringAleatorio(9)); err == nil || !strings.Contains(err.Error(), "NotFound") {
+		t.Errorf("Se esperaba NotFound al unirse a un canal inexistente, se obtuvo %+v", err)
+	}
+
+	if canales, err := ejecutar(2, "canales"); canales != canal+"\n" || err != nil {
+		t.Errorf("Se esperaba %q en la llamada a `canales`, se obtuvo %q con error %+v", canal+"\n", canales, err)
+	}
+	miembros, err := ejecutar(2, "/miembros", canal)
+	esperadoA := fmt.Sprintf("%s,%s\n", usuarios[0], usuarios[1])
+	esperadoB := fmt.Sprintf("%s,%s\n", usuarios[1], usuarios[0])
+	if (miembros != esperadoA && miembros != esperadoB) || err != nil {
+		t.Errorf("Se esperaba %q o %q en la llamada a `miembros`, se obtuvo %q con error %+v", esperadoA, esperadoB, miembros, err)
+	}
+
+	// solo los miembros pueden publicar
+	if _, err := ejecutar(2, canal, "intruso"); err == nil || !strings.Contains(err.Error(), "PermissionDenied") {
+		t.Errorf("Se esperaba PermissionDenied al publicar sin ser miembro, se obtuvo %+v", err)
+	}
+
+	if _, err := ejecutar(0, canal, "hola a todos"); err != nil {
+		t.Errorf("No se pudo publicar en el canal: %s", err)
+	}
+	mensajes, err := obtenerSinFechas(clientes[1], contextos[1])
+	if esperado := fmt.Sprintf("[%s] [%s]: hola a todos\n", canal, usuarios[0]); mensajes != esperado || err != nil {
+		t.Errorf("Se esperaba %q, se obtuvo %q con error %+v", esperado, mensajes, err)
+	}
+	// quien publica no recibe su propio mensaje
+	if mensajes, _ := obtenerSinFechas(clientes[0], contextos[0]); mensajes != "\n" {
+		t.Errorf("Se esperaba que quien publica no recibiera su mensaje, se obtuvo %q", mensajes)
+	}
+
+	if _, err := ejecutar(1, "/abandonar", canal); err != nil {
+		t.Errorf("No se pudo abandonar el canal: %s", err)
+	}
+	if _, err := ejecutar(1, "/abandonar", canal); err == nil {
+		t.Errorf("Se esperaba un error al abandonar un canal del que no se es miembro")
+	}
+	ejecutar(0, canal, "ya no está")
+	if mensajes, _ := obtenerSinFechas(clientes[1], contextos[1]); mensajes != "\n" {
+		t.Errorf("Se esperaba que quien abandonó el canal no recibiera mensajes, se obtuvo %q", mensajes)
+	}
+}
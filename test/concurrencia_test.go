@@ -0,0 +1,61 @@
+package mensajero
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+
+	mensajero "mensajero/pkg"
+)
+
+const CANTIDAD_CLIENTES_CONCURRENTES = 200
+
+// Prueba que cientos de clientes pueden conectarse, enviarse mensajes y desconectarse
+// al mismo tiempo sin carreras de datos. Ejecútese con `go test -race`.
+func TestClientesConcurrentes(t *testing.T) {
+
+	servicioMensajero, direccion := iniciarServidor(t)
+
+	usuarios := make([]string, CANTIDAD_CLIENTES_CONCURRENTES)
+	for i := range usuarios {
+		usuarios[i] = fmt.Sprintf("%s-%d", stringAleatorio(8), i)
+	}
+
+	var grupo sync.WaitGroup
+	for i := range usuarios {
+		grupo.Add(1)
+		go func(usuario string) {
+			defer grupo.Done()
+
+			conexion, cliente, ctx, err := mensajero.ConfigurarCliente(direccion, usuario, 10)
+			if err != nil {
+				t.Errorf("No se pudo conectar %s: %s", usuario, err)
+				return
+			}
+			defer conexion.Close()
+
+			for j := 0; j < 10; j++ {
+				// el destinatario puede haberse desconectado ya, por lo que el error se ignora
+				destino := usuarios[rand.Intn(len(usuarios))]
+				mensajero.Ejecutar(cliente, ctx, destino, fmt.Sprintf("%s %d", usuario, j))
+			}
+			if _, err := mensajero.Ejecutar(cliente, ctx, "listar"); err != nil {
+				t.Errorf("Error al listar como %s: %s", usuario, err)
+			}
+			if _, err := mensajero.Ejecutar(cliente, ctx, "obtener"); err != nil {
+				t.Errorf("Error al obtener como %s: %s", usuario, err)
+			}
+
+			_, err = mensajero.Ejecutar(cliente, ctx, "salir")
+			if desconexion, ok := err.(*mensajero.ErrorDesconexion); !ok || desconexion.RazonesAdicionales != "" {
+				t.Errorf("Se esperaba una desconexión limpia de %s, se obtuvo %v", usuario, err)
+			}
+		}(usuarios[i])
+	}
+	grupo.Wait()
+
+	if largo := servicioMensajero.TablaAutenticacionUsuario.Largo(); largo != 0 {
+		t.Errorf("Se esperaba que no quedaran sesiones, quedaron %d: %+v", largo, servicioMensajero.TablaAutenticacionUsuario.Usuarios())
+	}
+}
@@ -0,0 +1,62 @@
+package mensajero
+
+import (
+	"testing"
+	"time"
+
+	mensajero "mensajero/pkg"
+)
+
+// Probar que un mensaje obtenido y no confirmado se vuelve a entregar cuando vence su
+// plazo de visibilidad, y que deja de entregarse una vez confirmado
+func TestConfirmacion(t *testing.T) {
+
+	usuario1 := stringAleatorio(12)
+	usuario2 := stringAleatorio(12)
+	plazo := 200 * time.Millisecond
+	_, direccion := iniciarServidor(t, mensajero.ConPlazoVisibilidad(plazo))
+
+	conexion1, cliente1, ctx1, err := mensajero.ConfigurarCliente(direccion, usuario1, 3)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion1.Close()
+	conexion2, cliente2, ctx2, err := mensajero.ConfigurarCliente(direccion, usuario2, 3)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion2.Close()
+
+	mensajero.Ejecutar(cliente1, ctx1, usuario2, "hola")
+
+	primera, err := cliente2.Obtener(ctx2, &mensajero.Vacio{})
+	if err != nil || len(primera.Mensajes) != 1 {
+		t.Fatalf("Se esperaba un mensaje, se obtuvo %+v con error %+v", primera, err)
+	}
+	if mensajes, _ := cliente2.Obtener(ctx2, &mensajero.Vacio{}); len(mensajes.Mensajes) != 0 {
+		t.Errorf("Se esperaba que el mensaje reservado no se entregara antes de su plazo")
+	}
+
+	time.Sleep(2 * plazo)
+	segunda, err := cliente2.Obtener(ctx2, &mensajero.Vacio{})
+	if err != nil || len(segunda.Mensajes) != 1 {
+		t.Fatalf("Se esperaba que el mensaje se entregara otra vez, se obtuvo %+v con error %+v", segunda, err)
+	}
+	msg := segunda.Mensajes[0]
+	if msg.Id != primera.Mensajes[0].Id || msg.Reentregas != 1 {
+		t.Errorf("Se esperaba el mismo mensaje con una reentrega, se obtuvo %+v", msg)
+	}
+
+	confirmacion := &mensajero.Confirmacion{Ids: []string{msg.Id}}
+	if correcto, err := cliente2.Confirmar(ctx2, confirmacion); err != nil || !correcto.Ok {
+		t.Errorf("Se esperaba confirmar el mensaje, se obtuvo %+v con error %+v", correcto, err)
+	}
+	if correcto, err := cliente2.Confirmar(ctx2, confirmacion); err != nil || correcto.Ok {
+		t.Errorf("Se esperaba que una segunda confirmación respondiera Ok en false, se obtuvo %+v con error %+v", correcto, err)
+	}
+
+	time.Sleep(2 * plazo)
+	if mensajes, _ := cliente2.Obtener(ctx2, &mensajero.Vacio{}); len(mensajes.Mensajes) != 0 {
+		t.Errorf("Se esperaba que el mensaje confirmado no se entregara otra vez, se obtuvo %+v", mensajes.Mensajes)
+	}
+}
@@ -0,0 +1,96 @@
+package mensajero
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+	mensajero "mensajero/pkg"
+)
+
+// Espera un mensaje de la conversación o falla la prueba.
+func recibirMensaje(t *testing.T, conversacion *mensajero.Conversacion) *mensajero.MensajeApp {
+	select {
+	case msg, ok := <-conversacion.Mensajes:
+		if !ok {
+			t.Fatalf("La conversación terminó antes de recibir el mensaje")
+		}
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatalf("No se recibió el mensaje a tiempo")
+	}
+	return nil
+}
+
+// Probar que una conversación recibe los mensajes apenas llegan y que sus envíos
+// llegan a los demás usuarios
+func TestConversacion(t *testing.T) {
+
+	usuario1 := stringAleatorio(12)
+	usuario2 := stringAleatorio(12)
+	_, direccion := iniciarServidor(t)
+
+	conexion1, cliente1, ctx1, err := mensajero.ConfigurarCliente(direccion, usuario1, 3)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion1.Close()
+	conexion2, cliente2, ctx2, err := mensajero.ConfigurarCliente(direccion, usuario2, 3)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion2.Close()
+
+	// un mensaje que ya estaba en la bandeja se entrega al abrir la conversación
+	mensajero.Ejecutar(cliente2, ctx2, usuario1, "antes")
+
+	conversacion, err := mensajero.AbrirConversacion(cliente1, ctx1)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if msg := recibirMensaje(t, conversacion); msg.Usuario != usuario2 || msg.Cuerpo != "antes" {
+		t.Errorf("Se esperaba el mensaje \"antes\" de %s, se obtuvo %+v", usuario2, msg)
+	}
+	mensajero.Ejecutar(cliente2, ctx2, usuario1, "durante")
+	if msg := recibirMensaje(t, conversacion); msg.Usuario != usuario2 || msg.Cuerpo != "durante" {
+		t.Errorf("Se esperaba el mensaje \"durante\" de %s, se obtuvo %+v", usuario2, msg)
+	}
+
+	if _, err := conversacion.Enviar(usuario2, "respuesta"); err != nil {
+		t.Errorf("No se pudo enviar por la conversación: %s", err)
+	}
+	if _, err := conversacion.Enviar(stringAleatorio(13), "nadie"); err == nil || !strings.Contains(err.Error(), "NotFound") {
+		t.Errorf("Se esperaba NotFound al enviar a un usuario desconocido, se obtuvo %+v", err)
+	}
+	mensajes, err := obtenerSinFechas(cliente2, ctx2)
+	if esperado := "[" + usuario1 + "]: respuesta\n"; mensajes != esperado || err != nil {
+		t.Errorf("Se esperaba %q, se obtuvo %q con error %+v", esperado, mensajes, err)
+	}
+
+	if err := conversacion.Cerrar(); err != nil {
+		t.Errorf("Se esperaba que la conversación terminara sin errores, se obtuvo %s", err)
+	}
+}
+
+// Probar que no se puede abrir una conversación sin un token válido
+func TestConversacionSinToken(t *testing.T) {
+
+	_, direccion := iniciarServidor(t)
+	conexion, cliente, _, err := mensajero.ConfigurarCliente(direccion, stringAleatorio(12), 3)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion.Close()
+
+	ctx := metadata.NewOutgoingContext(context.Background(), metadata.Pairs("token", "inválido"))
+	flujo, err := cliente.Conversar(ctx)
+	if err == nil {
+		_, err = flujo.Recv()
+	}
+	if err == nil {
+		t.Errorf("Se esperaba un error al conversar con un token inválido")
+	}
+}
@@ -0,0 +1,84 @@
+package mensajero
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	mensajero "mensajero/pkg"
+)
+
+// Probar que un servidor con cuentas solo deja conectarse con la contraseña correcta
+func TestCuentas(t *testing.T) {
+
+	usuario := stringAleatorio(12)
+	credenciales, err := mensajero.AbrirAlmacenCredenciales(filepath.Join(t.TempDir(), "cuentas.json"), mensajero.OpcionesCredenciales{Costo: 1 << 10, Bloque: 8, Paralelismo: 1})
+	if err != nil {
+		t.Fatalf("No se pudo abrir el almacén de cuentas: %s", err)
+	}
+	_, direccion := iniciarServidor(t, mensajero.ConCredenciales(credenciales))
+
+	// sin cuenta no se puede conectar
+	if _, _, _, err := mensajero.ConfigurarCliente(direccion, usuario, 3, mensajero.ConContrasena("secreto")); err == nil {
+		t.Fatalf("Se esperaba un error al conectarse sin cuenta")
+	}
+
+	conexion, cliente, ctx, err := mensajero.ConfigurarCliente(direccion, usuario, 3, mensajero.ConContrasena("secreto"), mensajero.ConCuentaNueva())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion.Close()
+	mensajero.Ejecutar(cliente, ctx, "salir")
+
+	if _, err := mensajero.RegistrarConContrasena(cliente, usuario, "incorrecta"); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Se esperaba Unauthenticated con una contraseña incorrecta, se obtuvo %+v", err)
+	}
+	if _, err := mensajero.Registrar(cliente, usuario); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Se esperaba Unauthenticated sin contraseña, se obtuvo %+v", err)
+	}
+	registracion := &mensajero.Registracion{UsuarioOrigen: usuario, Contrasena: "otra"}
+	if _, err := cliente.CrearCuenta(ctx, registracion); status.Code(err) != codes.AlreadyExists {
+		t.Errorf("Se esperaba AlreadyExists al repetir la cuenta, se obtuvo %+v", err)
+	}
+
+	ctx, err = mensajero.RegistrarConContrasena(cliente, usuario, "secreto")
+	if err != nil {
+		t.Fatalf("No se pudo volver a conectar con la contraseña correcta: %s", err)
+	}
+	if _, err := mensajero.Ejecutar(cliente, ctx, "listar"); err != nil {
+		t.Errorf("Se esperaba poder usar la sesión, se obtuvo %s", err)
+	}
+}
+
+// Probar que las altas inválidas no gastan el límite de altas de la dirección
+func TestLimiteAltasSoloCuentasValidas(t *testing.T) {
+
+	_, direccion := iniciarServidor(t, conCuentas(t), mensajero.ConLimiteAltas(2, time.Minute))
+
+	conexion, cliente, _, err := mensajero.ConfigurarCliente(direccion, stringAleatorio(12), 3, mensajero.ConContrasena(contrasenaPrueba), mensajero.ConCuentaNueva())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion.Close()
+
+	for _, registracion := range []*mensajero.Registracion{
+		{UsuarioOrigen: "", Contrasena: contrasenaPrueba},
+		{UsuarioOrigen: "/" + stringAleatorio(12), Contrasena: contrasenaPrueba},
+		{UsuarioOrigen: stringAleatorio(12)},
+		{UsuarioOrigen: stringAleatorio(12)},
+	} {
+		if _, err := cliente.CrearCuenta(context.Background(), registracion); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Se esperaba InvalidArgument al crear la cuenta %+v, se obtuvo %+v", registracion, err)
+		}
+	}
+
+	if _, err := cliente.CrearCuenta(context.Background(), &mensajero.Registracion{UsuarioOrigen: stringAleatorio(12), Contrasena: contrasenaPrueba}); err != nil {
+		t.Errorf("Se esperaba poder crear la segunda cuenta, se obtuvo %+v", err)
+	}
+	if _, err := cliente.CrearCuenta(context.Background(), &mensajero.Registracion{UsuarioOrigen: stringAleatorio(12), Contrasena: contrasenaPrueba}); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Se esperaba ResourceExhausted al superar el límite de altas, se obtuvo %+v", err)
+	}
+}
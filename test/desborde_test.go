@@ -0,0 +1,168 @@
+package mensajero
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	mensajero "mensajero/pkg"
+)
+
+const CAPACIDAD_PRUEBA = 3
+
+// Prueba cada política de desborde enviando cinco mensajes a una bandeja con lugar
+// para tres.
+func TestPoliticasDesborde(t *testing.T) {
+	casos := []struct {
+		nombre string
+		// la política del servidor y, si no es vacía, la particular del receptor
+		opciones []mensajero.OpcionServidor
+		// el código y el valor de Ok esperados para cada envío
+		codigos  []codes.Code
+		ok       []bool
+		esperado []string
+	}{
+		{
+			nombre:   "rechazar",
+			opciones: []mensajero.OpcionServidor{mensajero.ConPoliticaDesborde(mensajero.PoliticaRechazar)},
+			codigos:  []codes.Code{codes.OK, codes.OK, codes.OK, codes.ResourceExhausted, codes.ResourceExhausted},
+			ok:       []bool{true, true, true, false, false},
+			esperado: []string{"0", "1", "2"},
+		},
+		{
+			nombre:   "descartar-antiguo",
+			opciones: []mensajero.OpcionServidor{mensajero.ConPoliticaDesborde(mensajero.PoliticaDescartarAntiguo)},
+			codigos:  []codes.Code{codes.OK, codes.OK, codes.OK, codes.OK, codes.OK},
+			ok:       []bool{true, true, true, true, true},
+			esperado: []string{"2", "3", "4"},
+		},
+		{
+			nombre:   "descartar-nuevo",
+			opciones: []mensajero.OpcionServidor{mensajero.ConPoliticaDesborde(mensajero.PoliticaDescartarNuevo)},
+			codigos:  []codes.Code{codes.OK, codes.OK, codes.OK, codes.OK, codes.OK},
+			ok:       []bool{true, true, true, false, false},
+			esperado: []string{"0", "1", "2"},
+		},
+		{
+			nombre:   "volcar",
+			opciones: []mensajero.OpcionServidor{mensajero.ConPoliticaDesborde(mensajero.PoliticaVolcarADisco)},
+			codigos:  []codes.Code{codes.OK, codes.OK, codes.OK, codes.OK, codes.OK},
+			ok:       []bool{true, true, true, true, true},
+			esperado: []string{"0", "1", "2", "3", "4"},
+		},
+		{
+			// la política del receptor tiene prioridad sobre la del servidor
+			nombre:   "por usuario",
+			opciones: []mensajero.OpcionServidor{mensajero.ConPoliticaDesborde(mensajero.PoliticaRechazar), mensajero.ConPoliticaDesbordeUsuario("receptor", mensajero.PoliticaDescartarAntiguo)},
+			codigos:  []codes.Code{codes.OK, codes.OK, codes.OK, codes.OK, codes.OK},
+			ok:       []bool{true, true, true, true, true},
+			esperado: []string{"2", "3", "4"},
+		},
+	}
+
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			volcado, err := mensajero.AbrirAlmacenVolcado(t.TempDir())
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+			defer volcado.Cerrar()
+
+			opciones := append([]mensajero.OpcionServidor{
+				mensajero.ConAlmacenBandejas(mensajero.NuevoAlmacenBandejasMemoria(CAPACIDAD_PRUEBA)),
+				mensajero.ConVolcado(volcado),
+			}, caso.opciones...)
+			_, direccion := iniciarServidor(t, opciones...)
+
+			conexionReceptor, clienteReceptor, ctxReceptor, err := mensajero.ConfigurarCliente(direccion, "receptor", 3)
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+			defer conexionReceptor.Close()
+			conexionEmisor, clienteEmisor, ctxEmisor, err := mensajero.ConfigurarCliente(direccion, "emisor", 3)
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+			defer conexionEmisor.Close()
+
+			for i := 0; i < 5; i++ {
+				respuesta, err := clienteEmisor.Enviar(ctxEmisor, &mensajero.MensajeApp{Usuario: "receptor", Cuerpo: fmt.Sprintf("%d", i)})
+				if status.Code(err) != caso.codigos[i] {
+					t.Errorf("Se esperaba el código %s en el envío %d, se obtuvo %+v", caso.codigos[i], i, err)
+				}
+				if ok := err == nil && respuesta.Ok; ok != caso.ok[i] {
+					t.Errorf("Se esperaba Ok %t en el envío %d, se obtuvo %t", caso.ok[i], i, ok)
+				}
+			}
+
+			mensajes, err := clienteReceptor.Obtener(ctxReceptor, &mensajero.Vacio{})
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+			obtenidos := []string{}
+			for _, msg := range mensajes.Mensajes {
+				obtenidos = append(obtenidos, msg.Cuerpo)
+			}
+			if strings.Join(obtenidos, ",") != strings.Join(caso.esperado, ",") {
+				t.Errorf("Se esperaban los mensajes %v, se obtuvo %v", caso.esperado, obtenidos)
+			}
+		})
+	}
+}
+
+// Prueba que con la política de volcado se conserva el orden aunque se retiren
+// mensajes mientras hay otros volcados a disco.
+func TestVolcadoConservaOrden(t *testing.T) {
+	volcado, err := mensajero.AbrirAlmacenVolcado(t.TempDir())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer volcado.Cerrar()
+	_, direccion := iniciarServidor(t,
+		mensajero.ConAlmacenBandejas(mensajero.NuevoAlmacenBandejasMemoria(CAPACIDAD_PRUEBA)),
+		mensajero.ConPoliticaDesborde(mensajero.PoliticaVolcarADisco),
+		mensajero.ConVolcado(volcado),
+	)
+
+	usuario := stringAleatorio(12)
+	conexion, cliente, ctx, err := mensajero.ConfigurarCliente(direccion, usuario, 3)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion.Close()
+
+	// cada ronda envía más mensajes de los que se retiran en un lote, de modo que
+	// siempre quedan mensajes volcados cuando llegan los de la ronda siguiente
+	enviados, obtenidos := []string{}, []string{}
+	for ronda := 0; ronda < 6; ronda++ {
+		for i := 0; i < mensajero.LARGO_LOTE+10; i++ {
+			cuerpo := fmt.Sprintf("%d-%d", ronda, i)
+			if _, err := cliente.Enviar(ctx, &mensajero.MensajeApp{Usuario: usuario, Cuerpo: cuerpo}); err != nil {
+				t.Fatalf(err.Error())
+			}
+			enviados = append(enviados, cuerpo)
+		}
+		mensajes, err := cliente.Obtener(ctx, &mensajero.Vacio{})
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		for _, msg := range mensajes.Mensajes {
+			obtenidos = append(obtenidos, msg.Cuerpo)
+		}
+	}
+	for len(obtenidos) < len(enviados) {
+		mensajes, err := cliente.Obtener(ctx, &mensajero.Vacio{})
+		if err != nil || len(mensajes.Mensajes) == 0 {
+			t.Fatalf("Se esperaban más mensajes, se obtuvo %+v con error %+v", mensajes, err)
+		}
+		for _, msg := range mensajes.Mensajes {
+			obtenidos = append(obtenidos, msg.Cuerpo)
+		}
+	}
+
+	if strings.Join(obtenidos, ",") != strings.Join(enviados, ",") {
+		t.Errorf("Se esperaban los mensajes en orden %v, se obtuvo %v", enviados, obtenidos)
+	}
+}
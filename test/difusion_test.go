@@ -0,0 +1,58 @@
+package mensajero
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	mensajero "mensajero/pkg"
+)
+
+// Probar que un administrador puede difundir un mensaje a los usuarios conectados, y que
+// los demás usuarios no pueden hacerlo
+func TestDifusion(t *testing.T) {
+
+	administrador := stringAleatorio(12)
+	conectado := stringAleatorio(12)
+	desconectado := stringAleatorio(12)
+	_, direccion := iniciarServidor(t, mensajero.ConAdministradores(administrador))
+
+	conexionAdministrador, clienteAdministrador, ctxAdministrador, err := mensajero.ConfigurarCliente(direccion, administrador, 3)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexionAdministrador.Close()
+	conexionConectado, clienteConectado, ctxConectado, err := mensajero.ConfigurarCliente(direccion, conectado, 3)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexionConectado.Close()
+	conexionDesconectado, clienteDesconectado, ctxDesconectado, err := mensajero.ConfigurarCliente(direccion, desconectado, 3)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexionDesconectado.Close()
+	mensajero.Ejecutar(clienteDesconectado, ctxDesconectado, "salir")
+
+	if _, err := mensajero.Ejecutar(clienteConectado, ctxConectado, "/difundir", "no autorizado"); err == nil || !strings.Contains(err.Error(), "PermissionDenied") {
+		t.Errorf("Se esperaba PermissionDenied al difundir sin ser administrador, se obtuvo %+v", err)
+	}
+
+	if _, err := mensajero.Ejecutar(clienteAdministrador, ctxAdministrador, "/difundir", "aviso general"); err != nil {
+		t.Fatalf("No se pudo difundir: %s", err)
+	}
+	mensajes, err := obtenerSinFechas(clienteConectado, ctxConectado)
+	if esperado := fmt.Sprintf("[difusión] [%s]: aviso general\n", administrador); mensajes != esperado || err != nil {
+		t.Errorf("Se esperaba %q, se obtuvo %q con error %+v", esperado, mensajes, err)
+	}
+
+	// quien no estaba conectado no recibe la difusión al volver
+	conexionDesconectado, clienteDesconectado, ctxDesconectado, err = mensajero.ConfigurarCliente(direccion, desconectado, 3)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexionDesconectado.Close()
+	if mensajes, _ := obtenerSinFechas(clienteDesconectado, ctxDesconectado); mensajes != "\n" {
+		t.Errorf("Se esperaba que un usuario desconectado no recibiera la difusión, se obtuvo %q", mensajes)
+	}
+}
@@ -0,0 +1,183 @@
+package mensajero
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	mensajero "mensajero/pkg"
+)
+
+// Probar que cada falla llega al cliente con su código de gRPC y la razón de su ErrorInfo,
+// y que una bandeja llena sugiere cuándo reintentar
+func TestCodigosDeError(t *testing.T) {
+
+	usuario := stringAleatorio(12)
+	lleno := stringAleatorio(12)
+	_, direccion := iniciarServidor(t,
+		mensajero.ConAlmacenBandejas(mensajero.NuevoAlmacenBandejasMemoria(1)),
+		mensajero.ConDuracionSesion(500*time.Millisecond),
+	)
+
+	conexion, cliente, ctx, err := mensajero.ConfigurarCliente(direccion, usuario, 3)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion.Close()
+	if _, err := mensajero.Registrar(cliente, lleno); err != nil {
+		t.Fatalf("No se pudo registrar: %s", err)
+	}
+	if _, err := cliente.Enviar(ctx, &mensajero.MensajeApp{Usuario: lleno, Cuerpo: "primero"}); err != nil {
+		t.Fatalf("No se pudo enviar: %s", err)
+	}
+	if _, err := cliente.CrearCanal(ctx, &mensajero.Canal{Nombre: "general"}); err != nil {
+		t.Fatalf("No se pudo crear el canal: %s", err)
+	}
+	ctxVencido, err := mensajero.Registrar(cliente, stringAleatorio(12))
+	if err != nil {
+		t.Fatalf("No se pudo registrar: %s", err)
+	}
+	sinToken := context.Background()
+	tokenFalso := metadata.NewOutgoingContext(context.Background(), metadata.Pairs("token", "falso"))
+
+	casos := []struct {
+		nombre string
+		llamar func() error
+		codigo codes.Code
+		razon  string
+	}{
+		{"sin token", func() error {
+			_, err := cliente.Listar(sinToken, &mensajero.Vacio{})
+			return err
+		}, codes.Unauthenticated, mensajero.RAZON_SIN_TOKEN},
+		{"token desconocido", func() error {
+			_, err := cliente.Listar(tokenFalso, &mensajero.Vacio{})
+			return err
+		}, codes.Unauthenticated, mensajero.RAZON_TOKEN_INVALIDO},
+		{"usuario vacío", func() error {
+			_, err := cliente.Conectar(context.Background(), &mensajero.Registracion{})
+			return err
+		}, codes.InvalidArgument, mensajero.RAZON_ARGUMENTO_INVALIDO},
+		{"sesión inexistente", func() error {
+			_, err := cliente.Conectar(context.Background(), &mensajero.Registracion{UsuarioOrigen: usuario, TokenAnterior: "falso"})
+			return err
+		}, codes.NotFound, mensajero.RAZON_SESION_INEXISTENTE},
+		{"servidor sin cuentas", func() error {
+			_, err := cliente.CrearCuenta(context.Background(), &mensajero.Registracion{UsuarioOrigen: usuario, Contrasena: "clave"})
+			return err
+		}, codes.FailedPrecondition, mensajero.RAZON_SIN_CUENTAS},
+		{"destinatario inexistente", func() error {
+			_, err := cliente.Enviar(ctx, &mensajero.MensajeApp{Usuario: stringAleatorio(12), Cuerpo: "hola"})
+			return err
+		}, codes.NotFound, mensajero.RAZON_USUARIO_INEXISTENTE},
+		{"bandeja llena", func() error {
+			_, err := cliente.Enviar(ctx, &mensajero.MensajeApp{Usuario: lleno, Cuerpo: "segundo"})
+			return err
+		}, codes.ResourceExhausted, mensajero.RAZON_BANDEJA_LLENA},
+		{"canal existente", func() error {
+			_, err := cliente.CrearCanal(ctx, &mensajero.Canal{Nombre: "general"})
+			return err
+		}, codes.AlreadyExists, mensajero.RAZON_CANAL_EXISTENTE},
+		{"canal inexistente", func() error {
+			_, err := cliente.UnirseCanal(ctx, &mensajero.Canal{Nombre: stringAleatorio(12)})
+			return err
+		}, codes.NotFound, mensajero.RAZON_CANAL_INEXISTENTE},
+		{"límite negativo", func() error {
+			_, err := cliente.ObtenerLimitado(ctx, &mensajero.ObtenerConLimite{Largo: -1})
+			return err
+		}, codes.InvalidArgument, mensajero.RAZON_ARGUMENTO_INVALIDO},
+		{"espera negativa", func() error {
+			_, err := cliente.ObtenerLimitado(ctx, &mensajero.ObtenerConLimite{Espera: durationpb.New(-time.Second)})
+			return err
+		}, codes.InvalidArgument, mensajero.RAZON_ARGUMENTO_INVALIDO},
+		{"difusión sin permiso", func() error {
+			_, err := cliente.Difundir(ctx, &mensajero.MensajeApp{Cuerpo: "hola"})
+			return err
+		}, codes.PermissionDenied, mensajero.RAZON_SIN_PERMISO},
+		{"sesión vencida", func() error {
+			time.Sleep(600 * time.Millisecond)
+			_, err := cliente.Listar(ctxVencido, &mensajero.Vacio{})
+			return err
+		}, codes.Unauthenticated, mensajero.RAZON_SESION_VENCIDA},
+	}
+	for _, caso := range casos {
+		err := caso.llamar()
+		if status.Code(err) != caso.codigo || mensajero.RazonError(err) != caso.razon {
+			t.Errorf("%s: se esperaba %s con la razón %s, se obtuvo %+v con la razón %q", caso.nombre, caso.codigo, caso.razon, err, mensajero.RazonError(err))
+		}
+	}
+
+	_, err = cliente.Enviar(ctx, &mensajero.MensajeApp{Usuario: lleno, Cuerpo: "tercero"})
+	if espera, ok := mensajero.EsperaReintento(err); !ok || espera != mensajero.REINTENTO_BANDEJA_LLENA {
+		t.Errorf("Se esperaba que la bandeja llena sugiriera reintentar en %s, se obtuvo %s", mensajero.REINTENTO_BANDEJA_LLENA, espera)
+	}
+}
+
+// Probar que los errores de Ejecutar y ConfigurarCliente se reconocen con errors.Is según
+// lo que pasó, sin perder el código de gRPC
+func TestErroresCliente(t *testing.T) {
+
+	usuario := stringAleatorio(12)
+	lleno := stringAleatorio(12)
+	_, direccion := iniciarServidor(t,
+		mensajero.ConAlmacenBandejas(mensajero.NuevoAlmacenBandejasMemoria(1)),
+	)
+
+	conexion, cliente, ctx, err := mensajero.ConfigurarCliente(direccion, usuario, 3)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion.Close()
+	if _, err := mensajero.Registrar(cliente, lleno); err != nil {
+		t.Fatalf("No se pudo registrar: %s", err)
+	}
+	if _, err := mensajero.Ejecutar(cliente, ctx, lleno, "primero"); err != nil {
+		t.Fatalf("No se pudo enviar: %s", err)
+	}
+
+	_, err = mensajero.Ejecutar(cliente, ctx, stringAleatorio(12), "hola")
+	if !errors.Is(err, mensajero.ErrDestinatarioInexistente) || status.Code(err) != codes.NotFound {
+		t.Errorf("Se esperaba ErrDestinatarioInexistente con NotFound, se obtuvo %+v", err)
+	}
+	_, err = mensajero.Ejecutar(cliente, ctx, lleno, "segundo")
+	if !errors.Is(err, mensajero.ErrBandejaLlena) {
+		t.Errorf("Se esperaba ErrBandejaLlena, se obtuvo %+v", err)
+	}
+	if _, ok := mensajero.EsperaReintento(err); !ok {
+		t.Errorf("Se esperaba que la bandeja llena sugiriera cuándo reintentar, se obtuvo %+v", err)
+	}
+	_, err = mensajero.Ejecutar(cliente, ctx, "/unirse", "#"+stringAleatorio(9))
+	if !errors.Is(err, mensajero.ErrCanalInexistente) {
+		t.Errorf("Se esperaba ErrCanalInexistente, se obtuvo %+v", err)
+	}
+	var errCliente *mensajero.ErrorCliente
+	if !errors.As(err, &errCliente) || errCliente.Operacion != "unirse al canal" {
+		t.Errorf("Se esperaba un ErrorCliente al unirse al canal, se obtuvo %+v", err)
+	}
+
+	_, err = mensajero.Ejecutar(cliente, ctx, "salir")
+	if !errors.Is(err, mensajero.ErrDesconectado) {
+		t.Errorf("Se esperaba ErrDesconectado, se obtuvo %+v", err)
+	}
+	_, err = mensajero.Ejecutar(cliente, ctx, "listar")
+	if !errors.Is(err, mensajero.ErrNoAutenticado) || mensajero.RazonError(err) != mensajero.RAZON_TOKEN_INVALIDO {
+		t.Errorf("Se esperaba ErrNoAutenticado después de salir, se obtuvo %+v", err)
+	}
+
+	// una dirección en la que nadie escucha
+	escucha, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	cerrada := escucha.Addr().String()
+	escucha.Close()
+	if _, _, _, err := mensajero.ConfigurarCliente(cerrada, usuario, 1); !errors.Is(err, mensajero.ErrServidorNoDisponible) {
+		t.Errorf("Se esperaba ErrServidorNoDisponible, se obtuvo %+v", err)
+	}
+}
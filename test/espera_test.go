@@ -0,0 +1,73 @@
+package mensajero
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	mensajero "mensajero/pkg"
+)
+
+// Probar que ObtenerLimitado con una espera responde en cuanto llega un mensaje, y sin
+// mensajes cuando vence la espera pedida, la máxima del servidor o el plazo del cliente
+func TestObtenerConEspera(t *testing.T) {
+
+	usuario1 := stringAleatorio(12)
+	usuario2 := stringAleatorio(12)
+	esperaMaxima := 300 * time.Millisecond
+	_, direccion := iniciarServidor(t, mensajero.ConEsperaMaxima(esperaMaxima))
+
+	conexion1, cliente1, ctx1, err := mensajero.ConfigurarCliente(direccion, usuario1, 3)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion1.Close()
+	conexion2, cliente2, ctx2, err := mensajero.ConfigurarCliente(direccion, usuario2, 3)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion2.Close()
+
+	esperar := func(ctx context.Context, espera time.Duration) (*mensajero.MensajesApp, time.Duration, error) {
+		inicio := time.Now()
+		mensajes, err := cliente2.ObtenerLimitado(ctx, &mensajero.ObtenerConLimite{Espera: durationpb.New(espera)})
+		return mensajes, time.Since(inicio), err
+	}
+
+	// llega un mensaje durante la espera
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		mensajero.Ejecutar(cliente1, ctx1, usuario2, "hola")
+	}()
+	mensajes, _, err := esperar(ctx2, time.Minute)
+	if err != nil || len(mensajes.Mensajes) != 1 || mensajes.Mensajes[0].Cuerpo != "hola" {
+		t.Errorf("Se esperaba recibir \"hola\" durante la espera, se obtuvo %+v con error %+v", mensajes, err)
+	}
+
+	// vence la espera pedida
+	mensajes, duracion, err := esperar(ctx2, 100*time.Millisecond)
+	if err != nil || len(mensajes.Mensajes) != 0 || duracion < 100*time.Millisecond || duracion >= esperaMaxima {
+		t.Errorf("Se esperaba una respuesta vacía luego de 100ms, se obtuvo %+v en %s con error %+v", mensajes, duracion, err)
+	}
+
+	// vence la espera máxima del servidor; como el mensaje anterior no se confirmó
+	// tampoco debe reentregarse todavía
+	mensajes, duracion, err = esperar(ctx2, time.Minute)
+	if err != nil || len(mensajes.Mensajes) != 0 || duracion < esperaMaxima || duracion > 10*esperaMaxima {
+		t.Errorf("Se esperaba una respuesta vacía luego de %s, se obtuvo %+v en %s con error %+v", esperaMaxima, mensajes, duracion, err)
+	}
+
+	// vence el plazo de la llamada
+	ctx, cancelar := context.WithTimeout(ctx2, 50*time.Millisecond)
+	defer cancelar()
+	if _, _, err := esperar(ctx, time.Minute); status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("Se esperaba DeadlineExceeded al vencer el plazo de la llamada, se obtuvo %+v", err)
+	}
+
+	if _, _, err := esperar(ctx2, -time.Second); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Se esperaba InvalidArgument con una espera negativa, se obtuvo %+v", err)
+	}
+}
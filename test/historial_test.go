@@ -0,0 +1,92 @@
+package mensajero
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	mensajero "mensajero/pkg"
+)
+
+// Probar que el historial conserva los mensajes ya obtenidos, en ambos sentidos, y que se
+// puede recorrer de a páginas con el cursor
+func TestHistorial(t *testing.T) {
+
+	usuario1 := stringAleatorio(12)
+	usuario2 := stringAleatorio(12)
+	usuario3 := stringAleatorio(12)
+	_, direccion := iniciarServidor(t)
+
+	conexion1, cliente1, ctx1, err := mensajero.ConfigurarCliente(direccion, usuario1, 3)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion1.Close()
+	conexion2, cliente2, ctx2, err := mensajero.ConfigurarCliente(direccion, usuario2, 3)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion2.Close()
+	conexion3, cliente3, ctx3, err := mensajero.ConfigurarCliente(direccion, usuario3, 3)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion3.Close()
+
+	for i := 0; i < 3; i++ {
+		mensajero.Ejecutar(cliente1, ctx1, usuario2, fmt.Sprintf("ida %d", i))
+		mensajero.Ejecutar(cliente2, ctx2, usuario1, fmt.Sprintf("vuelta %d", i))
+	}
+	mensajero.Ejecutar(cliente3, ctx3, usuario1, "de otro")
+	// los mensajes obtenidos siguen en el historial
+	obtenerSinFechas(cliente1, ctx1)
+
+	mensajes, err := mensajero.Ejecutar(cliente1, ctx1, "/historial", usuario2)
+	mensajes = fechaMensaje.ReplaceAllString(mensajes, "")
+	esperado := ""
+	for i := 0; i < 3; i++ {
+		esperado += fmt.Sprintf("[%s]: ida %d\n[%s]: vuelta %d\n", usuario1, i, usuario2, i)
+	}
+	if mensajes != esperado || err != nil {
+		t.Errorf("Se esperaba el historial %q, se obtuvo %q con error %+v", esperado, mensajes, err)
+	}
+	// el prefijo de los comandos es opcional
+	mensajes, err = mensajero.Ejecutar(cliente1, ctx1, "historial", usuario2)
+	mensajes = fechaMensaje.ReplaceAllString(mensajes, "")
+	if mensajes != esperado || err != nil {
+		t.Errorf("Se esperaba el historial %q con `historial %s`, se obtuvo %q con error %+v", esperado, usuario2, mensajes, err)
+	}
+
+	cuerpos := []string{}
+	cursor := ""
+	for paginas := 0; ; paginas++ {
+		if paginas > 4 {
+			t.Fatalf("Se esperaban 4 páginas, se obtuvieron más")
+		}
+		pagina, err := cliente1.Historial(ctx1, &mensajero.ConsultaHistorial{Largo: 2, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("No se pudo consultar el historial: %s", err)
+		}
+		for _, entrada := range pagina.Entradas {
+			cuerpos = append(cuerpos, entrada.Mensaje.Cuerpo)
+		}
+		if cursor = pagina.Cursor; cursor == "" {
+			break
+		}
+	}
+	if obtenido, esperado := fmt.Sprint(cuerpos), "[de otro vuelta 2 ida 2 vuelta 1 ida 1 vuelta 0 ida 0]"; obtenido != esperado {
+		t.Errorf("Se esperaba recorrer el historial %s, se obtuvo %s", esperado, obtenido)
+	}
+
+	futuro := timestamppb.New(time.Now().Add(time.Hour))
+	if pagina, err := cliente1.Historial(ctx1, &mensajero.ConsultaHistorial{Desde: futuro}); err != nil || len(pagina.Entradas) != 0 {
+		t.Errorf("Se esperaba un historial vacío desde una fecha futura, se obtuvo %+v con error %+v", pagina, err)
+	}
+
+	if _, err := cliente1.Historial(ctx1, &mensajero.ConsultaHistorial{Cursor: "no es un cursor"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Se esperaba InvalidArgument con un cursor inválido, se obtuvo %+v", err)
+	}
+}
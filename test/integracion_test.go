@@ -2,8 +2,9 @@ package mensajero
 
 import (
 	"fmt"
-	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"math/rand"
 	"testing"
 	"time"
@@ -30,42 +31,25 @@ func TestAutenticacionUsuarioUnico(t *testing.T) {
 
 	usuario := stringAleatorio(12)
 
-	servicioMensajero := mensajero.NuevoServidor()
-	servidorReal := grpc.NewServer(
-		grpc.UnaryInterceptor(servicioMensajero.Interceptor),
-	)
+	servicioMensajero, direccion := iniciarServidor(t)
 
-	if len(servicioMensajero.TablaAutenticacionUsuario) != 0 {
-		t.Errorf("Se esperaba un elemento en TablaAutenticacionUsuario, encontrado %+v", servicioMensajero.TablaAutenticacionUsuario)
+	if servicioMensajero.TablaAutenticacionUsuario.Largo() != 0 {
+		t.Errorf("Se esperaba un elemento en TablaAutenticacionUsuario, encontrado %+v", servicioMensajero.TablaAutenticacionUsuario.Usuarios())
 	}
 
-	listen, puerto, _ := mensajero.AbrirListener("")
-	direccion:= fmt.Sprintf("localhost:%s", puerto)
-
-	go func() {
-		mensajero.RegisterMensajeroServer(servidorReal, servicioMensajero)
-		if err := servidorReal.Serve(listen); err != nil {
-			t.Fatalf(err.Error())
-		}
-	}()
-
-	defer func() {
-		servidorReal.GracefulStop()
-	}()
-
 	conexion, _, ctx, err := mensajero.ConfigurarCliente(direccion, usuario, 3)
 	if err != nil {
 		t.Fatalf(err.Error())
 	}
 	defer conexion.Close()
 
-	if len(servicioMensajero.TablaAutenticacionUsuario) != 1 {
-		t.Errorf("Se esperaba un elemento en TablaAutenticacionUsuario, encontrado %+v", servicioMensajero.TablaAutenticacionUsuario)
+	if servicioMensajero.TablaAutenticacionUsuario.Largo() != 1 {
+		t.Errorf("Se esperaba un elemento en TablaAutenticacionUsuario, encontrado %+v", servicioMensajero.TablaAutenticacionUsuario.Usuarios())
 	}
 
-	for _, valor := range servicioMensajero.TablaAutenticacionUsuario {
+	for _, valor := range servicioMensajero.TablaAutenticacionUsuario.Usuarios() {
 		if valor != usuario {
-			t.Errorf("El Usuario %s debe estar en la tabla de usuarios, pero la tabla de usuarios tenía %+v", usuario, servicioMensajero.TablaAutenticacionUsuario)
+			t.Errorf("El Usuario %s debe estar en la tabla de usuarios, pero la tabla de usuarios tenía %+v", usuario, servicioMensajero.TablaAutenticacionUsuario.Usuarios())
 		}
 	}
 
@@ -83,24 +67,7 @@ func TestAutenticacionUsuarioUnico(t *testing.T) {
 func TestUnSoloClienteInteractua(t *testing.T) {
 
 	usuario := stringAleatorio(12)
-	servicioMensajero := mensajero.NuevoServidor()
-	servidorReal := grpc.NewServer(
-		grpc.UnaryInterceptor(servicioMensajero.Interceptor),
-	)
-
-	listen, puerto, _ := mensajero.AbrirListener("")
-	direccion:= fmt.Sprintf("localhost:%s", puerto)
-
-	go func() {
-		mensajero.RegisterMensajeroServer(servidorReal, servicioMensajero)
-		if err := servidorReal.Serve(listen); err != nil {
-			t.Fatalf(err.Error())
-		}
-	}()
-
-	defer func() {
-		servidorReal.GracefulStop()
-	}()
+	_, direccion := iniciarServidor(t)
 
 	conexion, cliente, ctx, err := mensajero.ConfigurarCliente(direccion, usuario, 3)
 	if err != nil {
@@ -115,7 +82,7 @@ func TestUnSoloClienteInteractua(t *testing.T) {
 	}
 
 	mensajero.Ejecutar(cliente, ctx, usuario, "hola")
-	mensaje, err := mensajero.Ejecutar(cliente, ctx, "obtener")
+	mensaje, err := obtenerSinFechas(cliente, ctx)
 	esperado = fmt.Sprintf("[%s]: hola\n", usuario)
 	if mensaje != esperado || err != nil {
 		t.Errorf("Se esperaba %q en la llamada a `obtener` después de un mensaje, se obtuvo %q con error %+v", esperado, mensaje, err)
@@ -124,7 +91,7 @@ func TestUnSoloClienteInteractua(t *testing.T) {
 	mensajero.Ejecutar(cliente, ctx, usuario, "mensaje de varias partes 1")
 	mensajero.Ejecutar(cliente, ctx, usuario, "mensaje de varias partes 2")
 
-	mensajes, err := mensajero.Ejecutar(cliente, ctx, "obtener")
+	mensajes, err := obtenerSinFechas(cliente, ctx)
 	esperado = fmt.Sprintf("[%s]: mensaje de varias partes 1\n[%s]: mensaje de varias partes 2\n", usuario, usuario)
 	if mensajes != esperado || err != nil {
 		t.Errorf("Se esperaba %q en la llamada a `obtener` después de varios mensajes, se obtuvo %q con error %+v", esperado, mensaje, err)
@@ -137,7 +104,7 @@ func TestUnSoloClienteInteractua(t *testing.T) {
 			esperado += fmt.Sprintf("[%s]: %d\n", usuario, i)
 		}
 	}
-	mensajes, err = mensajero.Ejecutar(cliente, ctx, "obtener")
+	mensajes, err = obtenerSinFechas(cliente, ctx)
 	if mensajes != esperado {
 		t.Errorf("Se esperaba %s, se obtuvo %s al solicitar más mensajes que el largo del lote", esperado, mensajes)
 	}
@@ -149,24 +116,7 @@ func TestMultipleClients(t *testing.T) {
 	usuario1 := stringAleatorio(12)
 	usuario2 := stringAleatorio(12)
 
-	servicioMensajero := mensajero.NuevoServidor()
-	servidorReal := grpc.NewServer(
-		grpc.UnaryInterceptor(servicioMensajero.Interceptor),
-	)
-
-	listen, puerto, _ := mensajero.AbrirListener("")
-	direccion:= fmt.Sprintf("localhost:%s", puerto)
-
-	go func() {
-		mensajero.RegisterMensajeroServer(servidorReal, servicioMensajero)
-		if err := servidorReal.Serve(listen); err != nil {
-			t.Fatalf(err.Error())
-		}
-	}()
-
-	defer func() {
-		servidorReal.GracefulStop()
-	}()
+	_, direccion := iniciarServidor(t)
 
 	conexion1, cliente1, ctx1, err := mensajero.ConfigurarCliente(direccion, usuario1, 3)
 	if err != nil {
@@ -196,8 +146,53 @@ func TestMultipleClients(t *testing.T) {
 		}
 	}
 
-	mensajes, err := mensajero.Ejecutar(cliente1, ctx1, "obtener")
+	mensajes, err := obtenerSinFechas(cliente1, ctx1)
 	if mensajes != esperado {
 		t.Errorf("Se esperaba %s, se obtuvo %s al solicitar más mensajes que el largo del lote", esperado, mensajes)
 	}
 }
+
+// Probar que los mensajes a un usuario desconectado se guardan hasta que vuelve a
+// conectarse y que los mensajes a un usuario desconocido fallan con NotFound
+func TestEntregaFueraDeLinea(t *testing.T) {
+
+	usuario1 := stringAleatorio(12)
+	usuario2 := stringAleatorio(12)
+
+	_, direccion := iniciarServidor(t)
+
+	conexion1, cliente1, ctx1, err := mensajero.ConfigurarCliente(direccion, usuario1, 3)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion1.Close()
+
+	conexion2, cliente2, ctx2, err := mensajero.ConfigurarCliente(direccion, usuario2, 3)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	mensajero.Ejecutar(cliente1, ctx1, usuario2, "antes de salir")
+	mensajero.Ejecutar(cliente2, ctx2, "salir")
+	conexion2.Close()
+
+	if _, err := cliente1.Enviar(ctx1, &mensajero.MensajeApp{Usuario: usuario2, Cuerpo: "mientras no estaba"}); err != nil {
+		t.Errorf("Se esperaba poder enviar a un usuario desconectado, se obtuvo el error %+v", err)
+	}
+
+	_, err = cliente1.Enviar(ctx1, &mensajero.MensajeApp{Usuario: stringAleatorio(13), Cuerpo: "hola"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Se esperaba NotFound al enviar a un usuario desconocido, se obtuvo %+v", err)
+	}
+
+	conexion2, cliente2, ctx2, err = mensajero.ConfigurarCliente(direccion, usuario2, 3)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion2.Close()
+
+	mensajes, err := obtenerSinFechas(cliente2, ctx2)
+	esperado := fmt.Sprintf("[%s]: antes de salir\n[%s]: mientras no estaba\n", usuario1, usuario1)
+	if mensajes != esperado || err != nil {
+		t.Errorf("Se esperaba %q al volver a conectarse, se obtuvo %q con error %+v", esperado, mensajes, err)
+	}
+}
@@ -0,0 +1,71 @@
+package mensajero
+
+import (
+	"fmt"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	mensajero "mensajero/pkg"
+)
+
+// Probar que ObtenerLimitado respeta la cantidad pedida, el máximo del servidor y el
+// límite de bytes, e informa cuántos mensajes quedan
+func TestObtenerLimitado(t *testing.T) {
+
+	usuario := stringAleatorio(12)
+	_, direccion := iniciarServidor(t, mensajero.ConLargoLoteMaximo(5))
+
+	conexion, cliente, ctx, err := mensajero.ConfigurarCliente(direccion, usuario, 3)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion.Close()
+
+	for i := 0; i < 10; i++ {
+		mensajero.Ejecutar(cliente, ctx, usuario, fmt.Sprintf("%d", i))
+	}
+
+	casos := []struct {
+		nombre     string
+		limite     *mensajero.ObtenerConLimite
+		cuerpos    string
+		pendientes int32
+	}{
+		{"la cantidad pedida", &mensajero.ObtenerConLimite{Largo: 3}, "[0 1 2]", 7},
+		{"el máximo del servidor", &mensajero.ObtenerConLimite{Largo: 100}, "[3 4 5 6 7]", 2},
+		// un mensaje solo ya supera el límite, pero se devuelve igual
+		{"el límite de bytes", &mensajero.ObtenerConLimite{Largo: 2, MaximoBytes: 1}, "[8]", 1},
+	}
+	for _, caso := range casos {
+		mensajes, err := cliente.ObtenerLimitado(ctx, caso.limite)
+		if err != nil {
+			t.Fatalf("Con %s: %s", caso.nombre, err)
+		}
+		cuerpos := []string{}
+		for _, msg := range mensajes.Mensajes {
+			cuerpos = append(cuerpos, msg.Cuerpo)
+		}
+		if obtenido := fmt.Sprint(cuerpos); obtenido != caso.cuerpos || mensajes.Pendientes != caso.pendientes {
+			t.Errorf("Con %s se esperaban %s y %d pendientes, se obtuvo %s y %d", caso.nombre, caso.cuerpos, caso.pendientes, obtenido, mensajes.Pendientes)
+		}
+	}
+
+	if _, err := cliente.ObtenerLimitado(ctx, &mensajero.ObtenerConLimite{Largo: -1}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Se esperaba InvalidArgument con un largo negativo, se obtuvo %+v", err)
+	}
+
+	mensajes, err := mensajero.Ejecutar(cliente, ctx, "obtener", "1")
+	mensajes = fechaMensaje.ReplaceAllString(mensajes, "")
+	if esperado := "[" + usuario + "]: 9\n"; mensajes != esperado || err != nil {
+		t.Errorf("Se esperaba %q con `obtener 1`, se obtuvo %q con error %+v", esperado, mensajes, err)
+	}
+
+	// el prefijo de los comandos es opcional
+	mensajero.Ejecutar(cliente, ctx, usuario, "10")
+	mensajes, err = mensajero.Ejecutar(cliente, ctx, "/obtener", "1")
+	mensajes = fechaMensaje.ReplaceAllString(mensajes, "")
+	if esperado := "[" + usuario + "]: 10\n"; mensajes != esperado || err != nil {
+		t.Errorf("Se esperaba %q con `/obtener 1`, se obtuvo %q con error %+v", esperado, mensajes, err)
+	}
+}
@@ -0,0 +1,139 @@
+package mensajero
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	mensajero "mensajero/pkg"
+)
+
+// Probar que el servidor asigna a cada mensaje un identificador único, la fecha en que
+// lo aceptó y un número de secuencia creciente para el destinatario
+func TestIdentificacionMensajes(t *testing.T) {
+
+	usuario1 := stringAleatorio(12)
+	usuario2 := stringAleatorio(12)
+	_, direccion := iniciarServidor(t)
+
+	conexion1, cliente1, ctx1, err := mensajero.ConfigurarCliente(direccion, usuario1, 3)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion1.Close()
+	conexion2, cliente2, ctx2, err := mensajero.ConfigurarCliente(direccion, usuario2, 3)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion2.Close()
+
+	inicio := time.Now().Add(-time.Second)
+	respuestas := []*mensajero.RespuestaEnviar{}
+	for _, cuerpo := range []string{"uno", "dos", "tres"} {
+		respuesta, err := cliente1.Enviar(ctx1, &mensajero.MensajeApp{Usuario: usuario2, Cuerpo: cuerpo, Secuencia: 99})
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		respuestas = append(respuestas, respuesta)
+	}
+	fin := time.Now().Add(time.Second)
+
+	ids := map[string]bool{}
+	for i, respuesta := range respuestas {
+		if respuesta.Id == "" || ids[respuesta.Id] {
+			t.Errorf("Se esperaba un identificador único, se obtuvo %q", respuesta.Id)
+		}
+		ids[respuesta.Id] = true
+		if i > 0 && respuesta.Secuencia <= respuestas[i-1].Secuencia {
+			t.Errorf("Se esperaba una secuencia creciente, se obtuvo %d luego de %d", respuesta.Secuencia, respuestas[i-1].Secuencia)
+		}
+	}
+
+	mensajes, err := cliente2.Obtener(ctx2, &mensajero.Vacio{})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(mensajes.Mensajes) != len(respuestas) {
+		t.Fatalf("Se esperaban %d mensajes, se obtuvieron %d", len(respuestas), len(mensajes.Mensajes))
+	}
+	for i, msg := range mensajes.Mensajes {
+		if msg.Id != respuestas[i].Id || msg.Secuencia != respuestas[i].Secuencia {
+			t.Errorf("El mensaje %d llegó con id %q y secuencia %d, se esperaba %q y %d", i, msg.Id, msg.Secuencia, respuestas[i].Id, respuestas[i].Secuencia)
+		}
+		if fecha := msg.Fecha.AsTime(); fecha.Before(inicio) || fecha.After(fin) {
+			t.Errorf("El mensaje %d tiene la fecha %s, fuera del momento del envío", i, fecha)
+		}
+	}
+
+	// la salida de obtener muestra la fecha de cada mensaje
+	mensajero.Ejecutar(cliente1, ctx1, usuario2, "con fecha")
+	salida, err := mensajero.Ejecutar(cliente2, ctx2, "obtener")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !fechaMensaje.MatchString(salida) || !strings.HasSuffix(salida, "["+usuario1+"]: con fecha\n") {
+		t.Errorf("Se esperaba el mensaje con su fecha, se obtuvo %q", salida)
+	}
+}
+
+// Probar que un usuario con el nombre de un comando recibe mensajes directos, porque los
+// comandos con argumento llevan el prefijo "/", salvo "obtener <cantidad>" e
+// "historial <usuario>"
+func TestMensajeUsuarioConNombreDeComando(t *testing.T) {
+
+	remitente := stringAleatorio(12)
+	_, direccion := iniciarServidor(t)
+
+	conexion, cliente, ctx, err := mensajero.ConfigurarCliente(direccion, remitente, 3)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion.Close()
+
+	for _, usuario := range []string{"difundir", "crear", "obtener"} {
+		conexionDestino, clienteDestino, ctxDestino, err := mensajero.ConfigurarCliente(direccion, usuario, 3)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		defer conexionDestino.Close()
+
+		if !mensajero.EsMensajeDirecto(usuario, "hola") {
+			t.Errorf("Se esperaba que %q fuera un mensaje directo", usuario+" hola")
+		}
+		if _, err := mensajero.Ejecutar(cliente, ctx, usuario, "hola"); err != nil {
+			t.Fatalf("No se pudo enviar a %s: %s", usuario, err)
+		}
+		if mensajes, err := obtenerSinFechas(clienteDestino, ctxDestino); mensajes != "["+remitente+"]: hola\n" || err != nil {
+			t.Errorf("Se esperaba que %s recibiera el mensaje, se obtuvo %q con error %+v", usuario, mensajes, err)
+		}
+	}
+
+	if _, err := mensajero.Ejecutar(cliente, ctx, "/desconocido", "algo"); err == nil {
+		t.Errorf("Se esperaba un error con un comando desconocido")
+	}
+	for _, comando := range [][]string{{"obtener", "1"}, {"historial", "hola"}} {
+		if mensajero.EsMensajeDirecto(comando...) {
+			t.Errorf("Se esperaba que %q fuera un comando", strings.Join(comando, " "))
+		}
+	}
+}
+
+// Probar que no se puede conectar un usuario cuyo nombre empieza como un comando o un
+// canal, ya que no se le podrían enviar mensajes directos
+func TestUsuarioInvalido(t *testing.T) {
+
+	_, direccion := iniciarServidor(t)
+	conexion, cliente, _, err := mensajero.ConfigurarCliente(direccion, stringAleatorio(12), 3)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion.Close()
+
+	for _, usuario := range []string{"/obtener", "#general"} {
+		if _, err := mensajero.Registrar(cliente, usuario); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Se esperaba InvalidArgument al conectarse como %q, se obtuvo %+v", usuario, err)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package mensajero
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+	mensajero "mensajero/pkg"
+)
+
+// Probar que las métricas cuentan las llamadas de cada método, los fallos de autenticación,
+// los mensajes rechazados y descartados, y muestran los usuarios conectados y las bandejas
+// según la capacidad del almacén
+func TestMetricas(t *testing.T) {
+
+	usuario := stringAleatorio(12)
+	lleno := stringAleatorio(12)
+	descartador := stringAleatorio(12)
+	servicioMensajero, direccion := iniciarServidor(t,
+		mensajero.ConAlmacenBandejas(mensajero.NuevoAlmacenBandejasMemoria(1)),
+		mensajero.ConPoliticaDesbordeUsuario(descartador, mensajero.PoliticaDescartarNuevo),
+	)
+
+	conexion, cliente, ctx, err := mensajero.ConfigurarCliente(direccion, usuario, 3)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion.Close()
+	for _, otro := range []string{lleno, descartador} {
+		if _, err := mensajero.Registrar(cliente, otro); err != nil {
+			t.Fatalf("No se pudo registrar: %s", err)
+		}
+	}
+	for _, destino := range []string{lleno, lleno, descartador, descartador} {
+		cliente.Enviar(ctx, &mensajero.MensajeApp{Usuario: destino, Cuerpo: "hola"})
+	}
+	cliente.Listar(metadata.NewOutgoingContext(context.Background(), metadata.Pairs("token", "falso")), &mensajero.Vacio{})
+
+	respuesta := httptest.NewRecorder()
+	servicioMensajero.ManejadorMetricas().ServeHTTP(respuesta, httptest.NewRequest("GET", "/metrics", nil))
+	if tipo := respuesta.Header().Get("Content-Type"); !strings.HasPrefix(tipo, "text/plain") {
+		t.Errorf("Se esperaba el formato de texto de Prometheus, se obtuvo %q", tipo)
+	}
+	metricas := respuesta.Body.String()
+	for _, linea := range []string{
+		`mensajero_rpc_llamadas_total{metodo="/mensajero.Mensajero/Enviar",codigo="OK"} 3`,
+		`mensajero_rpc_llamadas_total{metodo="/mensajero.Mensajero/Enviar",codigo="ResourceExhausted"} 1`,
+		`mensajero_rpc_llamadas_total{metodo="/mensajero.Mensajero/Listar",codigo="Unauthenticated"} 1`,
+		`mensajero_rpc_duracion_segundos_bucket{metodo="/mensajero.Mensajero/Enviar",le="+Inf"} 4`,
+		`mensajero_rpc_duracion_segundos_count{metodo="/mensajero.Mensajero/Enviar"} 4`,
+		`mensajero_autenticacion_fallos_total{razon="TOKEN_INVALIDO"} 1`,
+		`mensajero_mensajes_rechazados_total 1`,
+		`mensajero_mensajes_descartados_total{politica="descartar-nuevo"} 1`,
+		`mensajero_usuarios_conectados 3`,
+		`mensajero_mensajes_pendientes 2`,
+		`mensajero_bandeja_profundidad_bucket{le="0"} 1`,
+		`mensajero_bandeja_capacidad 1`,
+		`mensajero_bandeja_profundidad_bucket{le="1"} 3`,
+		`mensajero_bandeja_profundidad_count 3`,
+		`# TYPE mensajero_bandeja_profundidad histogram`,
+	} {
+		if !strings.Contains(metricas, linea+"\n") {
+			t.Errorf("Se esperaba la línea %s en las métricas:\n%s", linea, metricas)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package mensajero
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	mensajero "mensajero/pkg"
+)
+
+// Variables de entorno con las que TestRecuperacionTrasCaida configura el proceso hijo.
+const (
+	VARIABLE_DIARIO = "MENSAJERO_PRUEBA_DIARIO"
+	VARIABLE_PUERTO = "MENSAJERO_PRUEBA_PUERTO"
+)
+
+// No es una prueba en sí misma: es el servidor que TestRecuperacionTrasCaida ejecuta
+// en un proceso aparte para poder matarlo mientras escribe.
+func TestProcesoServidorConDiario(t *testing.T) {
+	ruta := os.Getenv(VARIABLE_DIARIO)
+	if ruta == "" {
+		t.Skip("solo se ejecuta como proceso hijo de TestRecuperacionTrasCaida")
+	}
+
+	diario, err := mensajero.AbrirDiario(ruta, mensajero.OpcionesDiarioPredeterminadas)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	// el servidor escucha en el puerto que eligió el proceso padre, por lo que no usa
+	// iniciarServidor
+	servicioMensajero := mensajero.NuevoServidor(mensajero.ConDiario(diario))
+	defer servicioMensajero.Cerrar()
+	servidorReal := grpc.NewServer(
+		grpc.UnaryInterceptor(servicioMensajero.Interceptor),
+		grpc.StreamInterceptor(servicioMensajero.InterceptorFlujo),
+	)
+	listen, err := net.Listen("tcp", ":"+os.Getenv(VARIABLE_PUERTO))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	mensajero.RegisterMensajeroServer(servidorReal, servicioMensajero)
+	servidorReal.Serve(listen)
+}
+
+// Devuelve un puerto libre en el que el proceso hijo pueda escuchar.
+func puertoLibre(t *testing.T) string {
+	listen, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer listen.Close()
+	return strconv.Itoa(listen.Addr().(*net.TCPAddr).Port)
+}
+
+// Prueba que los mensajes confirmados sobreviven a que el servidor muera con SIGKILL
+// mientras varios remitentes escriben en el diario.
+func TestRecuperacionTrasCaida(t *testing.T) {
+	if os.Getenv(VARIABLE_DIARIO) != "" {
+		t.Skip("no se ejecuta dentro del proceso hijo")
+	}
+
+	ruta := filepath.Join(t.TempDir(), "diario")
+	puerto := puertoLibre(t)
+	direccion := fmt.Sprintf("localhost:%s", puerto)
+
+	proceso := exec.Command(os.Args[0], "-test.run=^TestProcesoServidorConDiario$")
+	proceso.Env = append(os.Environ(), VARIABLE_DIARIO+"="+ruta, VARIABLE_PUERTO+"="+puerto)
+	if err := proceso.Start(); err != nil {
+		t.Fatalf("No se pudo iniciar el servidor: %s", err)
+	}
+	defer proceso.Process.Kill()
+
+	receptor := stringAleatorio(12)
+	emisor := stringAleatorio(12)
+	conexionReceptor, _, _, err := mensajero.ConfigurarCliente(direccion, receptor, 10)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexionReceptor.Close()
+	conexionEmisor, cliente, ctx, err := mensajero.ConfigurarCliente(direccion, emisor, 10)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexionEmisor.Close()
+
+	const remitentes = 4
+	const confirmadosAntesDeMatar = 200
+	var candado sync.Mutex
+	confirmados := map[string]bool{}
+	suficientes := make(chan struct{})
+	var grupo sync.WaitGroup
+	for r := 0; r < remitentes; r++ {
+		grupo.Add(1)
+		go func(r int) {
+			defer grupo.Done()
+			for i := 0; ; i++ {
+				cuerpo := fmt.Sprintf("%d-%d", r, i)
+				if _, err := cliente.Enviar(ctx, &mensajero.MensajeApp{Usuario: receptor, Cuerpo: cuerpo}); err != nil {
+					// el servidor murió
+					return
+				}
+				candado.Lock()
+				confirmados[cuerpo] = true
+				if len(confirmados) == confirmadosAntesDeMatar {
+					close(suficientes)
+				}
+				candado.Unlock()
+			}
+		}(r)
+	}
+
+	select {
+	case <-suficientes:
+	case <-time.After(30 * time.Second):
+		t.Fatalf("El servidor no confirmó %d mensajes a tiempo", confirmadosAntesDeMatar)
+	}
+	proceso.Process.Kill()
+	proceso.Wait()
+	grupo.Wait()
+
+	diario, err := mensajero.AbrirDiario(ruta, mensajero.OpcionesDiarioPredeterminadas)
+	if err != nil {
+		t.Fatalf("No se pudo abrir el diario luego de la caída: %s", err)
+	}
+	defer diario.Cerrar()
+	servicioMensajero := mensajero.NuevoServidor(mensajero.ConDiario(diario))
+	defer servicioMensajero.Cerrar()
+
+	bandejaEntrada, ok := servicioMensajero.BandejasEntrada.Bandeja(receptor)
+	if !ok {
+		t.Fatalf("Se esperaba que se restaurara la bandeja de %s", receptor)
+	}
+	mensajes, _ := bandejaEntrada.Retirar(mensajero.LARGO_BUZON)
+
+	recuperados := map[string]bool{}
+	ultimo := make([]int, remitentes)
+	for i := range ultimo {
+		ultimo[i] = -1
+	}
+	for _, msg := range mensajes {
+		if msg.Usuario != emisor {
+			t.Errorf("Se esperaba un mensaje de %s, se obtuvo uno de %s", emisor, msg.Usuario)
+		}
+		if recuperados[msg.Cuerpo] {
+			t.Errorf("El mensaje %s se recuperó dos veces", msg.Cuerpo)
+		}
+		recuperados[msg.Cuerpo] = true
+
+		// los mensajes de cada remitente deben conservar el orden en que se enviaron
+		partes := strings.SplitN(msg.Cuerpo, "-", 2)
+		r, _ := strconv.Atoi(partes[0])
+		i, _ := strconv.Atoi(partes[1])
+		if i <= ultimo[r] {
+			t.Errorf("El mensaje %s se recuperó fuera de orden", msg.Cuerpo)
+		}
+		ultimo[r] = i
+	}
+
+	for cuerpo := range confirmados {
+		if !recuperados[cuerpo] {
+			t.Errorf("El mensaje confirmado %s se perdió en la caída", cuerpo)
+		}
+	}
+	// solo pueden aparecer de más los mensajes que estaban en vuelo al matar el proceso
+	if extra := len(recuperados) - len(confirmados); extra > remitentes {
+		t.Errorf("Se recuperaron %d mensajes sin confirmar, se esperaban como máximo %d", extra, remitentes)
+	}
+}
@@ -0,0 +1,95 @@
+package mensajero
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	mensajero "mensajero/pkg"
+)
+
+// Un búfer que el servidor puede escribir mientras la prueba lo lee.
+type bufferSeguro struct {
+	candado sync.Mutex
+	datos   bytes.Buffer
+}
+
+func (b *bufferSeguro) Write(datos []byte) (int, error) {
+	b.candado.Lock()
+	defer b.candado.Unlock()
+	return b.datos.Write(datos)
+}
+
+// Devuelve las líneas JSON escritas hasta ahora con el identificador de solicitud indicado.
+func (b *bufferSeguro) lineas(id string) []map[string]string {
+	b.candado.Lock()
+	defer b.candado.Unlock()
+	lineas := []map[string]string{}
+	for _, texto := range strings.Split(strings.TrimSpace(b.datos.String()), "\n") {
+		var campos map[string]string
+		if json.Unmarshal([]byte(texto), &campos) == nil && campos["id_solicitud"] == id {
+			lineas = append(lineas, campos)
+		}
+	}
+	return lineas
+}
+
+// Probar que cada llamada queda en el registro con el usuario, la dirección, el código y el
+// identificador de solicitud que envió el cliente o que generó el servidor, y que el
+// cuerpo de los mensajes no aparece
+func TestRegistro(t *testing.T) {
+
+	salida := &bufferSeguro{}
+	registro := mensajero.NuevoRegistro(salida, mensajero.OpcionesRegistro{Formato: mensajero.FormatoJSON, Nivel: mensajero.NivelDepuracion})
+	_, direccion := iniciarServidor(t, mensajero.ConRegistro(registro))
+
+	usuario := stringAleatorio(12)
+	conexion, cliente, ctx, err := mensajero.ConfigurarCliente(direccion, usuario, 3)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion.Close()
+
+	var encabezados metadata.MD
+	ctxSolicitud := metadata.AppendToOutgoingContext(ctx, mensajero.CLAVE_ID_SOLICITUD, "solicitud-1")
+	if _, err := cliente.Enviar(ctxSolicitud, &mensajero.MensajeApp{Usuario: usuario, Cuerpo: "no debe verse"}, grpc.Header(&encabezados)); err != nil {
+		t.Fatalf("No se pudo enviar: %s", err)
+	}
+	if ids := encabezados.Get(mensajero.CLAVE_ID_SOLICITUD); len(ids) != 1 || ids[0] != "solicitud-1" {
+		t.Errorf("Se esperaba recibir el identificador de la solicitud, se obtuvo %v", ids)
+	}
+	lineas := salida.lineas("solicitud-1")
+	if len(lineas) != 1 {
+		t.Fatalf("Se esperaba una línea para la solicitud, se obtuvieron %v", lineas)
+	}
+	linea := lineas[0]
+	if linea["metodo"] != "/mensajero.Mensajero/Enviar" || linea["usuario"] != usuario || linea["codigo"] != "OK" || linea["direccion"] == "" || linea["duracion"] == "" {
+		t.Errorf("Campos inesperados en la línea del registro: %v", linea)
+	}
+	if strings.Contains(linea["solicitud"], "no debe verse") || !strings.Contains(linea["solicitud"], mensajero.OCULTO) {
+		t.Errorf("Se esperaba el cuerpo del mensaje oculto en el registro, se obtuvo %q", linea["solicitud"])
+	}
+
+	// sin identificador el servidor genera uno, también para los flujos
+	flujo, err := cliente.Suscribir(ctx, &mensajero.Vacio{})
+	if err != nil {
+		t.Fatalf("No se pudo suscribir: %s", err)
+	}
+	encabezados, err = flujo.Header()
+	if err != nil {
+		t.Fatalf("No se pudieron leer los encabezados: %s", err)
+	}
+	if ids := encabezados.Get(mensajero.CLAVE_ID_SOLICITUD); len(ids) != 1 || ids[0] == "" {
+		t.Errorf("Se esperaba un identificador de solicitud generado por el servidor, se obtuvo %v", ids)
+	}
+
+	_, err = cliente.Listar(metadata.AppendToOutgoingContext(context.Background(), mensajero.CLAVE_ID_SOLICITUD, "solicitud-2"), &mensajero.Vacio{})
+	if lineas := salida.lineas("solicitud-2"); err == nil || len(lineas) != 1 || lineas[0]["codigo"] != "Unauthenticated" || lineas[0]["nivel"] != "advertencia" {
+		t.Errorf("Se esperaba registrar la llamada sin token como advertencia, se obtuvo %v", lineas)
+	}
+}
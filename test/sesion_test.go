@@ -0,0 +1,190 @@
+package mensajero
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	mensajero "mensajero/pkg"
+)
+
+// Probar que los tokens son aleatorios, que una sesión vencida se rechaza con
+// Unauthenticated y que una renovada sigue vigente
+func TestSesionVencida(t *testing.T) {
+
+	usuario := stringAleatorio(12)
+	_, direccion := iniciarServidor(t, mensajero.ConDuracionSesion(300*time.Millisecond))
+
+	conexion, cliente, ctx, err := mensajero.ConfigurarCliente(direccion, usuario, 3)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion.Close()
+
+	// dos sesiones sucesivas del mismo usuario no comparten el token
+	token, err := cliente.Renovar(ctx, &mensajero.Vacio{})
+	if err != nil {
+		t.Fatalf("No se pudo renovar la sesión: %s", err)
+	}
+	mensajero.Ejecutar(cliente, ctx, "salir")
+	otroToken, err := cliente.Conectar(context.Background(), &mensajero.Registracion{UsuarioOrigen: usuario})
+	if err != nil {
+		t.Fatalf("No se pudo volver a conectar: %s", err)
+	}
+	if otroToken.Token == token.Token {
+		t.Errorf("Se esperaba un token distinto en cada sesión")
+	}
+
+	ctx, err = mensajero.Registrar(cliente, stringAleatorio(12))
+	if err != nil {
+		t.Fatalf("No se pudo registrar: %s", err)
+	}
+	// renovando la sesión sigue vigente después de su plazo original
+	detener := mensajero.MantenerSesion(cliente, ctx)
+	time.Sleep(500 * time.Millisecond)
+	if _, err := mensajero.Ejecutar(cliente, ctx, "listar"); err != nil {
+		t.Errorf("Se esperaba que la sesión renovada siguiera vigente, se obtuvo %s", err)
+	}
+	detener()
+
+	time.Sleep(500 * time.Millisecond)
+	if _, err := mensajero.Ejecutar(cliente, ctx, "listar"); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Se esperaba Unauthenticated con la sesión vencida, se obtuvo %+v", err)
+	}
+	if _, err := cliente.Renovar(ctx, &mensajero.Vacio{}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Se esperaba que no se pudiera renovar la sesión vencida, se obtuvo %+v", err)
+	}
+}
+
+// Probar que un cliente que terminó sin salir reanuda su sesión con el token guardado,
+// conservando los mensajes que le llegaron, y que puede cerrar las sesiones que quedaron
+// abiertas
+func TestReanudarSesion(t *testing.T) {
+
+	usuario := stringAleatorio(12)
+	remitente := stringAleatorio(12)
+	_, direccion := iniciarServidor(t, conCuentas(t))
+	archivo := filepath.Join(t.TempDir(), "sesion")
+
+	conexion, cliente, ctxAnterior, err := mensajero.ConfigurarCliente(direccion, usuario, 3, mensajero.ConContrasena(contrasenaPrueba), mensajero.ConCuentaNueva(), mensajero.ConArchivoSesion(archivo))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	// el cliente termina sin desconectarse
+	conexion.Close()
+
+	conexionRemitente, clienteRemitente, ctxRemitente, err := mensajero.ConfigurarCliente(direccion, remitente, 3, mensajero.ConContrasena(contrasenaPrueba), mensajero.ConCuentaNueva())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexionRemitente.Close()
+	mensajero.Ejecutar(clienteRemitente, ctxRemitente, usuario, "mientras no estabas")
+
+	conexion, cliente, ctx, err := mensajero.ConfigurarCliente(direccion, usuario, 3, mensajero.ConContrasena(contrasenaPrueba), mensajero.ConArchivoSesion(archivo))
+	if err != nil {
+		t.Fatalf("No se pudo reanudar la sesión: %s", err)
+	}
+	defer conexion.Close()
+	if _, err := mensajero.Ejecutar(cliente, ctxAnterior, "listar"); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Se esperaba que el token anterior dejara de valer, se obtuvo %+v", err)
+	}
+	sesiones, err := cliente.ListarSesiones(ctx, &mensajero.Vacio{})
+	if err != nil || len(sesiones.Sesiones) != 1 {
+		t.Errorf("Se esperaba una única sesión reanudada, se obtuvo %v con error %+v", sesiones, err)
+	}
+	esperado := fmt.Sprintf("[%s]: mientras no estabas\n", remitente)
+	if mensajes, err := obtenerSinFechas(cliente, ctx); mensajes != esperado || err != nil {
+		t.Errorf("Se esperaba %q, se obtuvo %q con error %+v", esperado, mensajes, err)
+	}
+
+	// una sesión guardada que ya no existe no impide conectarse
+	mensajero.Ejecutar(cliente, ctx, "salir")
+	conexion, cliente, ctx, err = mensajero.ConfigurarCliente(direccion, usuario, 3, mensajero.ConContrasena(contrasenaPrueba), mensajero.ConArchivoSesion(archivo))
+	if err != nil {
+		t.Fatalf("Se esperaba abrir una sesión nueva, se obtuvo %s", err)
+	}
+	defer conexion.Close()
+
+	// otra conexión cierra las sesiones que quedaron abiertas
+	conexionOtra, clienteOtra, ctxOtra, err := mensajero.ConfigurarCliente(direccion, usuario, 3, mensajero.ConContrasena(contrasenaPrueba), mensajero.ConCerrarOtras())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexionOtra.Close()
+	if _, err := mensajero.Ejecutar(cliente, ctx, "listar"); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Se esperaba que la otra sesión se cerrara, se obtuvo %+v", err)
+	}
+	if sesiones, err := clienteOtra.ListarSesiones(ctxOtra, &mensajero.Vacio{}); err != nil || len(sesiones.Sesiones) != 1 {
+		t.Errorf("Se esperaba una única sesión, se obtuvo %v con error %+v", sesiones, err)
+	}
+}
+
+// Probar que en un servidor sin cuentas nadie abre una segunda sesión de un usuario
+// conectado ni cierra las suyas, ya que nada prueba que sea el mismo usuario
+func TestSesionAjenaSinCuentas(t *testing.T) {
+
+	usuario := stringAleatorio(12)
+	_, direccion := iniciarServidor(t)
+
+	conexion, cliente, ctx, err := mensajero.ConfigurarCliente(direccion, usuario, 3)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion.Close()
+
+	for _, registracion := range []*mensajero.Registracion{
+		{UsuarioOrigen: usuario},
+		{UsuarioOrigen: usuario, CerrarOtras: true},
+	} {
+		_, err := cliente.Conectar(context.Background(), registracion)
+		if status.Code(err) != codes.AlreadyExists || mensajero.RazonError(err) != mensajero.RAZON_USUARIO_CONECTADO {
+			t.Errorf("Se esperaba AlreadyExists al conectarse como un usuario conectado con %+v, se obtuvo %+v", registracion, err)
+		}
+	}
+	if _, err := mensajero.Ejecutar(cliente, ctx, "listar"); err != nil {
+		t.Errorf("Se esperaba que la sesión del usuario siguiera abierta, se obtuvo %+v", err)
+	}
+}
+
+// Probar que el servidor cierra las sesiones que dejaron de dar señales de vida, sin
+// perder su bandeja, y que el cliente mantiene viva la suya con latidos
+func TestSesionInactiva(t *testing.T) {
+
+	activo := stringAleatorio(12)
+	inactivo := stringAleatorio(12)
+	_, direccion := iniciarServidor(t, mensajero.ConInactividadMaxima(300*time.Millisecond))
+
+	conexion, cliente, ctx, err := mensajero.ConfigurarCliente(direccion, activo, 3)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion.Close()
+	// un cliente que se registra por su cuenta no da señales de vida
+	ctxInactivo, err := mensajero.Registrar(cliente, inactivo)
+	if err != nil {
+		t.Fatalf("No se pudo registrar: %s", err)
+	}
+	mensajero.Ejecutar(cliente, ctx, inactivo, "para después")
+
+	time.Sleep(time.Second)
+	if usuarios, err := mensajero.Ejecutar(cliente, ctx, "listar"); usuarios != activo+"\n" || err != nil {
+		t.Errorf("Se esperaba que solo %s siguiera conectado, se obtuvo %q con error %+v", activo, usuarios, err)
+	}
+	if _, err := mensajero.Ejecutar(cliente, ctxInactivo, "listar"); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Se esperaba Unauthenticated en la sesión inactiva, se obtuvo %+v", err)
+	}
+
+	// la bandeja se conserva para cuando vuelva a conectarse
+	ctxInactivo, err = mensajero.Registrar(cliente, inactivo)
+	if err != nil {
+		t.Fatalf("No se pudo volver a registrar: %s", err)
+	}
+	esperado := fmt.Sprintf("[%s]: para después\n", activo)
+	if mensajes, err := obtenerSinFechas(cliente, ctxInactivo); mensajes != esperado || err != nil {
+		t.Errorf("Se esperaba %q, se obtuvo %q con error %+v", esperado, mensajes, err)
+	}
+}
@@ -0,0 +1,175 @@
+package mensajero
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	mensajero "mensajero/pkg"
+)
+
+// Probar que un usuario puede conectarse desde varios dispositivos, que todas sus
+// sesiones reciben los mensajes, que salir de una no cierra las demás y que puede revocar
+// otra de sus sesiones
+func TestVariasSesiones(t *testing.T) {
+
+	usuario := stringAleatorio(12)
+	remitente := stringAleatorio(12)
+	_, direccion := iniciarServidor(t, conCuentas(t))
+
+	conexion1, cliente1, ctx1, err := mensajero.ConfigurarCliente(direccion, usuario, 3, mensajero.ConContrasena(contrasenaPrueba), mensajero.ConCuentaNueva())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion1.Close()
+	conexion2, cliente2, ctx2, err := mensajero.ConfigurarCliente(direccion, usuario, 3, mensajero.ConContrasena(contrasenaPrueba))
+	if err != nil {
+		t.Fatalf("Se esperaba poder conectarse desde un segundo dispositivo, se obtuvo %s", err)
+	}
+	defer conexion2.Close()
+	conexionRemitente, clienteRemitente, ctxRemitente, err := mensajero.ConfigurarCliente(direccion, remitente, 3, mensajero.ConContrasena(contrasenaPrueba), mensajero.ConCuentaNueva())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexionRemitente.Close()
+
+	if usuarios, _ := mensajero.Ejecutar(clienteRemitente, ctxRemitente, "listar"); strings.Count(usuarios, usuario) != 1 {
+		t.Errorf("Se esperaba ver una sola vez al usuario con dos sesiones, se obtuvo %q", usuarios)
+	}
+
+	// las dos sesiones reciben cada mensaje una sola vez
+	mensajero.Ejecutar(clienteRemitente, ctxRemitente, usuario, "hola")
+	esperado := fmt.Sprintf("[%s]: hola\n", remitente)
+	if mensajes, err := obtenerSinFechas(cliente1, ctx1); mensajes != esperado || err != nil {
+		t.Errorf("Se esperaba %q en la primera sesión, se obtuvo %q con error %+v", esperado, mensajes, err)
+	}
+	if mensajes, err := obtenerSinFechas(cliente2, ctx2); mensajes != esperado || err != nil {
+		t.Errorf("Se esperaba %q en la segunda sesión, se obtuvo %q con error %+v", esperado, mensajes, err)
+	}
+	if mensajes, _ := obtenerSinFechas(cliente1, ctx1); mensajes != "\n" {
+		t.Errorf("Se esperaba que la primera sesión no recibiera el mensaje otra vez, se obtuvo %q", mensajes)
+	}
+
+	sesiones, err := cliente1.ListarSesiones(ctx1, &mensajero.Vacio{})
+	if err != nil || len(sesiones.Sesiones) != 2 {
+		t.Fatalf("Se esperaban dos sesiones, se obtuvo %v con error %+v", sesiones, err)
+	}
+	if listado, _ := mensajero.Ejecutar(cliente1, ctx1, "sesiones"); strings.Count(listado, "(esta sesión)") != 1 {
+		t.Errorf("Se esperaba una sesión marcada como la actual, se obtuvo %q", listado)
+	}
+
+	// salir de la primera sesión no cierra la segunda
+	mensajero.Ejecutar(cliente1, ctx1, "salir")
+	if _, err := mensajero.Ejecutar(cliente1, ctx1, "listar"); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Se esperaba Unauthenticated en la sesión cerrada, se obtuvo %+v", err)
+	}
+	mensajero.Ejecutar(clienteRemitente, ctxRemitente, usuario, "sigo aquí")
+	esperado = fmt.Sprintf("[%s]: sigo aquí\n", remitente)
+	if mensajes, err := obtenerSinFechas(cliente2, ctx2); mensajes != esperado || err != nil {
+		t.Errorf("Se esperaba %q en la sesión que sigue abierta, se obtuvo %q con error %+v", esperado, mensajes, err)
+	}
+
+	// la segunda sesión revoca una tercera
+	conexion3, cliente3, ctx3, err := mensajero.ConfigurarCliente(direccion, usuario, 3, mensajero.ConContrasena(contrasenaPrueba))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion3.Close()
+	sesiones, err = cliente2.ListarSesiones(ctx2, &mensajero.Vacio{})
+	if err != nil || len(sesiones.Sesiones) != 2 {
+		t.Fatalf("Se esperaban dos sesiones, se obtuvo %v con error %+v", sesiones, err)
+	}
+	for _, sesion := range sesiones.Sesiones {
+		if !sesion.Actual {
+			if _, err := mensajero.Ejecutar(cliente2, ctx2, "/revocar", sesion.Id); err != nil {
+				t.Errorf("No se pudo revocar la sesión: %s", err)
+			}
+		}
+	}
+	if _, err := mensajero.Ejecutar(cliente3, ctx3, "listar"); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Se esperaba Unauthenticated en la sesión revocada, se obtuvo %+v", err)
+	}
+	if _, err := mensajero.Ejecutar(cliente2, ctx2, "/revocar", "inexistente"); err == nil {
+		t.Errorf("Se esperaba un error al revocar una sesión inexistente")
+	}
+	if _, err := mensajero.Ejecutar(cliente2, ctx2, "listar"); err != nil {
+		t.Errorf("Se esperaba que la sesión que revocó siguiera abierta, se obtuvo %+v", err)
+	}
+}
+
+// Probar que un mensaje que se vuelve a entregar por no confirmarse a tiempo llega una sola
+// vez a cada sesión, y que las copias se confirman y se vuelven a entregar a su sesión
+// como los mensajes
+func TestSesionesConPlazoVisibilidad(t *testing.T) {
+
+	usuario := stringAleatorio(12)
+	plazo := 200 * time.Millisecond
+	_, direccion := iniciarServidor(t, conCuentas(t), mensajero.ConPlazoVisibilidad(plazo))
+
+	conexion1, cliente1, ctx1, err := mensajero.ConfigurarCliente(direccion, usuario, 3, mensajero.ConContrasena(contrasenaPrueba), mensajero.ConCuentaNueva())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion1.Close()
+	conexion2, cliente2, ctx2, err := mensajero.ConfigurarCliente(direccion, usuario, 3, mensajero.ConContrasena(contrasenaPrueba))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion2.Close()
+	obtener := func(cliente mensajero.MensajeroClient, ctx context.Context) []*mensajero.MensajeApp {
+		mensajes, err := cliente.Obtener(ctx, &mensajero.Vacio{})
+		if err != nil {
+			t.Fatalf("No se pudo obtener: %s", err)
+		}
+		return mensajes.Mensajes
+	}
+	confirmar := func(cliente mensajero.MensajeroClient, ctx context.Context, msg *mensajero.MensajeApp) {
+		if correcto, err := cliente.Confirmar(ctx, &mensajero.Confirmacion{Ids: []string{msg.Id}}); err != nil || !correcto.Ok {
+			t.Errorf("Se esperaba confirmar %q, se obtuvo %v con error %+v", msg.Cuerpo, correcto, err)
+		}
+	}
+
+	// la primera sesión obtiene el mensaje y no lo confirma a tiempo
+	cliente1.Enviar(ctx1, &mensajero.MensajeApp{Usuario: usuario, Cuerpo: "hola"})
+	if mensajes := obtener(cliente1, ctx1); len(mensajes) != 1 {
+		t.Fatalf("Se esperaba un mensaje en la primera sesión, se obtuvo %v", mensajes)
+	}
+	if mensajes := obtener(cliente2, ctx2); len(mensajes) != 0 {
+		t.Errorf("Se esperaba que la segunda sesión no recibiera un mensaje sin confirmar, se obtuvo %v", mensajes)
+	}
+	time.Sleep(2 * plazo)
+
+	// la segunda sesión recibe el mensaje reentregado y, al confirmarlo, la primera una copia
+	mensajes := obtener(cliente2, ctx2)
+	if len(mensajes) != 1 || mensajes[0].Reentregas != 1 {
+		t.Fatalf("Se esperaba el mensaje reentregado en la segunda sesión, se obtuvo %v", mensajes)
+	}
+	confirmar(cliente2, ctx2, mensajes[0])
+	copias := obtener(cliente1, ctx1)
+	if len(copias) != 1 || copias[0].Id != mensajes[0].Id {
+		t.Fatalf("Se esperaba una copia del mensaje en la primera sesión, se obtuvo %v", copias)
+	}
+
+	// la copia sin confirmar vuelve solo a su sesión
+	time.Sleep(2 * plazo)
+	if mensajes := obtener(cliente2, ctx2); len(mensajes) != 0 {
+		t.Errorf("Se esperaba que la segunda sesión no recibiera el mensaje otra vez, se obtuvo %v", mensajes)
+	}
+	copias = obtener(cliente1, ctx1)
+	if len(copias) != 1 || copias[0].Id != mensajes[0].Id {
+		t.Fatalf("Se esperaba la copia otra vez en la primera sesión, se obtuvo %v", copias)
+	}
+	confirmar(cliente1, ctx1, copias[0])
+
+	time.Sleep(2 * plazo)
+	if mensajes := obtener(cliente1, ctx1); len(mensajes) != 0 {
+		t.Errorf("Se esperaba que la copia confirmada no volviera, se obtuvo %v", mensajes)
+	}
+	if mensajes := obtener(cliente2, ctx2); len(mensajes) != 0 {
+		t.Errorf("Se esperaba que el mensaje confirmado no volviera, se obtuvo %v", mensajes)
+	}
+}
@@ -0,0 +1,184 @@
+package mensajero
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	mensajero "mensajero/pkg"
+)
+
+// Espera un mensaje de la suscripción o falla la prueba.
+func recibirSuscripcion(t *testing.T, suscripcion *mensajero.Suscripcion) *mensajero.MensajeApp {
+	select {
+	case msg, ok := <-suscripcion.Mensajes:
+		if !ok {
+			t.Fatalf("La suscripción terminó antes de recibir el mensaje")
+		}
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatalf("No se recibió el mensaje a tiempo")
+	}
+	return nil
+}
+
+// Probar que una suscripción entrega primero lo pendiente y luego cada mensaje que
+// llega, y que lo que llega después de cerrarla no se pierde
+func TestSuscripcion(t *testing.T) {
+
+	usuario1 := stringAleatorio(12)
+	usuario2 := stringAleatorio(12)
+	_, direccion := iniciarServidor(t, mensajero.ConPlazoVisibilidad(200*time.Millisecond))
+
+	conexion1, cliente1, ctx1, err := mensajero.ConfigurarCliente(direccion, usuario1, 3)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion1.Close()
+	conexion2, cliente2, ctx2, err := mensajero.ConfigurarCliente(direccion, usuario2, 3)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion2.Close()
+
+	mensajero.Ejecutar(cliente2, ctx2, usuario1, "pendiente")
+
+	suscripcion, err := mensajero.Suscribirse(cliente1, ctx1)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if msg := recibirSuscripcion(t, suscripcion); msg.Usuario != usuario2 || msg.Cuerpo != "pendiente" {
+		t.Errorf("Se esperaba el mensaje \"pendiente\" de %s, se obtuvo %+v", usuario2, msg)
+	}
+	for _, cuerpo := range []string{"uno", "dos", "tres"} {
+		mensajero.Ejecutar(cliente2, ctx2, usuario1, cuerpo)
+		if msg := recibirSuscripcion(t, suscripcion); msg.Cuerpo != cuerpo {
+			t.Errorf("Se esperaba el mensaje %q, se obtuvo %+v", cuerpo, msg)
+		}
+	}
+
+	if err := suscripcion.Cerrar(); err != nil {
+		t.Errorf("Se esperaba que la suscripción terminara sin errores, se obtuvo %s", err)
+	}
+
+	// el servidor puede enviar el mensaje por la suscripción antes de notar que se cerró;
+	// en ese caso queda sin confirmar y se entrega otra vez al vencer su plazo
+	mensajero.Ejecutar(cliente2, ctx2, usuario1, "después")
+	mensajes, err := cliente1.ObtenerLimitado(ctx1, &mensajero.ObtenerConLimite{Espera: durationpb.New(5 * time.Second)})
+	if err != nil || len(mensajes.Mensajes) != 1 || mensajes.Mensajes[0].Cuerpo != "después" {
+		t.Errorf("Se esperaba el mensaje \"después\", se obtuvo %+v con error %+v", mensajes, err)
+	}
+}
+
+// Probar que no se puede suscribir sin un token válido
+func TestSuscripcionSinToken(t *testing.T) {
+
+	_, direccion := iniciarServidor(t)
+	conexion, cliente, _, err := mensajero.ConfigurarCliente(direccion, stringAleatorio(12), 3)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion.Close()
+
+	ctx := metadata.NewOutgoingContext(context.Background(), metadata.Pairs("token", "inválido"))
+	suscripcion, err := mensajero.Suscribirse(cliente, ctx)
+	if err == nil {
+		if _, abierta := <-suscripcion.Mensajes; abierta {
+			t.Errorf("Se esperaba que la suscripción terminara sin mensajes")
+		}
+		err = suscripcion.Cerrar()
+	}
+	if err == nil {
+		t.Errorf("Se esperaba un error al suscribirse con un token inválido")
+	}
+}
+
+// Espera a que termine la suscripción, sin más mensajes, y devuelve su error.
+func esperarFinSuscripcion(t *testing.T, suscripcion *mensajero.Suscripcion) error {
+	select {
+	case msg, abierta := <-suscripcion.Mensajes:
+		if abierta {
+			t.Fatalf("Se esperaba que la suscripción terminara, se recibió %+v", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("La suscripción no terminó a tiempo")
+	}
+	return suscripcion.Cerrar()
+}
+
+// Probar que una suscripción mantiene viva su sesión mientras está abierta y termina con
+// Unauthenticated cuando otra sesión la revoca
+func TestSuscripcionRevocada(t *testing.T) {
+
+	usuario := stringAleatorio(12)
+	remitente := stringAleatorio(12)
+	_, direccion := iniciarServidor(t, conCuentas(t), mensajero.ConInactividadMaxima(300*time.Millisecond))
+
+	conexion, cliente, ctx, err := mensajero.ConfigurarCliente(direccion, remitente, 3, mensajero.ConContrasena(contrasenaPrueba), mensajero.ConCuentaNueva())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion.Close()
+	if _, err := cliente.CrearCuenta(context.Background(), &mensajero.Registracion{UsuarioOrigen: usuario, Contrasena: contrasenaPrueba}); err != nil {
+		t.Fatalf("No se pudo crear la cuenta: %s", err)
+	}
+	// ni la sesión suscrita ni la que la revoca dan señales de vida por su cuenta
+	ctxSuscrito, err := mensajero.RegistrarConContrasena(cliente, usuario, contrasenaPrueba)
+	if err != nil {
+		t.Fatalf("No se pudo registrar: %s", err)
+	}
+	suscripcion, err := mensajero.Suscribirse(cliente, ctxSuscrito)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	time.Sleep(time.Second)
+	mensajero.Ejecutar(cliente, ctx, usuario, "sigo suscrito")
+	if msg := recibirSuscripcion(t, suscripcion); msg.Cuerpo != "sigo suscrito" {
+		t.Errorf("Se esperaba el mensaje \"sigo suscrito\", se obtuvo %+v", msg)
+	}
+
+	ctxOtra, err := mensajero.RegistrarConContrasena(cliente, usuario, contrasenaPrueba)
+	if err != nil {
+		t.Fatalf("No se pudo registrar: %s", err)
+	}
+	sesiones, err := cliente.ListarSesiones(ctxOtra, &mensajero.Vacio{})
+	if err != nil || len(sesiones.Sesiones) != 2 {
+		t.Fatalf("Se esperaban dos sesiones, se obtuvo %v con error %+v", sesiones, err)
+	}
+	for _, sesion := range sesiones.Sesiones {
+		if !sesion.Actual {
+			cliente.RevocarSesion(ctxOtra, sesion)
+		}
+	}
+	if err := esperarFinSuscripcion(t, suscripcion); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Se esperaba Unauthenticated al revocar la sesión suscrita, se obtuvo %+v", err)
+	}
+}
+
+// Probar que una suscripción termina cuando vence su sesión sin renovarse
+func TestSuscripcionVencida(t *testing.T) {
+
+	_, direccion := iniciarServidor(t, mensajero.ConDuracionSesion(300*time.Millisecond), mensajero.ConInactividadMaxima(0))
+
+	conexion, cliente, _, err := mensajero.ConfigurarCliente(direccion, stringAleatorio(12), 3)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion.Close()
+	ctx, err := mensajero.Registrar(cliente, stringAleatorio(12))
+	if err != nil {
+		t.Fatalf("No se pudo registrar: %s", err)
+	}
+	suscripcion, err := mensajero.Suscribirse(cliente, ctx)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := esperarFinSuscripcion(t, suscripcion); mensajero.RazonError(err) != mensajero.RAZON_SESION_VENCIDA {
+		t.Errorf("Se esperaba %s al vencer la sesión suscrita, se obtuvo %+v", mensajero.RAZON_SESION_VENCIDA, err)
+	}
+}
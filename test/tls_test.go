@@ -0,0 +1,206 @@
+package mensajero
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	mensajero "mensajero/pkg"
+)
+
+// Una autoridad de certificación creada para la prueba, que firma los certificados del
+// servidor y de los clientes y los guarda en un directorio temporal.
+type autoridadPrueba struct {
+	t           *testing.T
+	directorio  string
+	certificado *x509.Certificate
+	clave       *ecdsa.PrivateKey
+	// el archivo PEM con el certificado de la autoridad
+	archivo string
+}
+
+func nuevaAutoridad(t *testing.T, nombre string) *autoridadPrueba {
+	a := &autoridadPrueba{t: t, directorio: t.TempDir()}
+	plantilla := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: nombre},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	a.certificado, a.clave, a.archivo, _ = a.firmar(nombre, plantilla)
+	return a
+}
+
+// Emite un certificado para el servidor en localhost y devuelve los archivos del
+// certificado y de su clave.
+func (a *autoridadPrueba) certificadoServidor() (string, string) {
+	plantilla := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "localhost"},
+		DNSNames:    []string{"localhost"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1"), net.IPv6loopback},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	_, _, certificado, clave := a.firmar("servidor", plantilla)
+	return certificado, clave
+}
+
+// Emite un certificado de cliente para el usuario y devuelve los archivos del certificado
+// y de su clave.
+func (a *autoridadPrueba) certificadoCliente(usuario string) (string, string) {
+	plantilla := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: usuario},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	_, _, certificado, clave := a.firmar(usuario, plantilla)
+	return certificado, clave
+}
+
+// Firma la plantilla con la autoridad, o consigo misma si es la autoridad, y guarda el
+// certificado y la clave en archivos PEM con el nombre indicado.
+func (a *autoridadPrueba) firmar(nombre string, plantilla *x509.Certificate) (*x509.Certificate, *ecdsa.PrivateKey, string, string) {
+	clave, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		a.t.Fatalf(err.Error())
+	}
+	serie, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		a.t.Fatalf(err.Error())
+	}
+	plantilla.SerialNumber = serie
+	plantilla.NotBefore = time.Now().Add(-time.Minute)
+	plantilla.NotAfter = time.Now().Add(time.Hour)
+
+	emisor, claveEmisor := plantilla, clave
+	if a.certificado != nil {
+		emisor, claveEmisor = a.certificado, a.clave
+	}
+	datos, err := x509.CreateCertificate(rand.Reader, plantilla, emisor, &clave.PublicKey, claveEmisor)
+	if err != nil {
+		a.t.Fatalf(err.Error())
+	}
+	certificado, err := x509.ParseCertificate(datos)
+	if err != nil {
+		a.t.Fatalf(err.Error())
+	}
+	datosClave, err := x509.MarshalECPrivateKey(clave)
+	if err != nil {
+		a.t.Fatalf(err.Error())
+	}
+
+	archivoCertificado := filepath.Join(a.directorio, nombre+".pem")
+	archivoClave := filepath.Join(a.directorio, nombre+".clave.pem")
+	a.escribir(archivoCertificado, &pem.Block{Type: "CERTIFICATE", Bytes: datos})
+	a.escribir(archivoClave, &pem.Block{Type: "EC PRIVATE KEY", Bytes: datosClave})
+	return certificado, clave, archivoCertificado, archivoClave
+}
+
+func (a *autoridadPrueba) escribir(archivo string, bloque *pem.Block) {
+	if err := os.WriteFile(archivo, pem.EncodeToMemory(bloque), 0600); err != nil {
+		a.t.Fatalf(err.Error())
+	}
+}
+
+// Inicia un servidor con TLS y, si se indica una autoridad de clientes, con TLS mutuo.
+func iniciarServidorTLS(t *testing.T, autoridad *autoridadPrueba, autoridadClientes string, opciones ...mensajero.OpcionServidor) (*mensajero.Servidor, string) {
+	certificado, clave := autoridad.certificadoServidor()
+	credenciales, err := mensajero.CredencialesServidor(certificado, clave, autoridadClientes)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	return iniciarServidorGrpc(t, []grpc.ServerOption{grpc.Creds(credenciales)}, opciones...)
+}
+
+// Probar que un cliente que confía en la autoridad del servidor se conecta con TLS, y que
+// no se conectan los que no usan TLS o confían en otra autoridad
+func TestTLS(t *testing.T) {
+
+	autoridad := nuevaAutoridad(t, "autoridad")
+	_, direccion := iniciarServidorTLS(t, autoridad, "")
+
+	usuario := stringAleatorio(12)
+	conexion, cliente, ctx, err := mensajero.ConfigurarCliente(direccion, usuario, 3, mensajero.ConTLS(autoridad.archivo))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion.Close()
+	if _, err := mensajero.Ejecutar(cliente, ctx, usuario, "cifrado"); err != nil {
+		t.Fatalf("No se pudo enviar: %s", err)
+	}
+	if mensajes, err := obtenerSinFechas(cliente, ctx); err != nil || mensajes != "["+usuario+"]: cifrado\n" {
+		t.Errorf("Se esperaba recibir el mensaje por TLS, se obtuvo %q, %v", mensajes, err)
+	}
+
+	if _, _, _, err := mensajero.ConfigurarCliente(direccion, stringAleatorio(12), 1); err == nil {
+		t.Errorf("Se esperaba que un cliente sin TLS no pudiera conectarse")
+	}
+	otra := nuevaAutoridad(t, "otra")
+	if _, _, _, err := mensajero.ConfigurarCliente(direccion, stringAleatorio(12), 1, mensajero.ConTLS(otra.archivo)); err == nil {
+		t.Errorf("Se esperaba que un cliente que confía en otra autoridad no pudiera conectarse")
+	}
+	if _, _, _, err := mensajero.ConfigurarCliente(direccion, stringAleatorio(12), 1, mensajero.ConTLS(filepath.Join(t.TempDir(), "nada.pem"))); err == nil {
+		t.Errorf("Se esperaba un error con un archivo de autoridades inexistente")
+	}
+}
+
+// Probar que con TLS mutuo el usuario es el CN del certificado del cliente, sin contraseña,
+// y que no se puede usar el certificado de otro usuario ni conectarse sin certificado
+func TestTLSMutuo(t *testing.T) {
+
+	autoridad := nuevaAutoridad(t, "autoridad")
+	autoridadClientes := nuevaAutoridad(t, "clientes")
+	credenciales, err := mensajero.AbrirAlmacenCredenciales(filepath.Join(t.TempDir(), "cuentas.json"), mensajero.OpcionesCredenciales{Costo: 1 << 10, Bloque: 8, Paralelismo: 1})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	_, direccion := iniciarServidorTLS(t, autoridad, autoridadClientes.archivo, mensajero.ConCredenciales(credenciales))
+
+	usuario := stringAleatorio(12)
+	certificado, clave := autoridadClientes.certificadoCliente(usuario)
+	if cn, err := mensajero.UsuarioCertificado(certificado); err != nil || cn != usuario {
+		t.Fatalf("Se esperaba que el certificado fuera de %s, se obtuvo %q, %v", usuario, cn, err)
+	}
+	conexion, cliente, ctx, err := mensajero.ConfigurarCliente(direccion, "", 3,
+		mensajero.ConTLS(autoridad.archivo),
+		mensajero.ConCertificadoCliente(certificado, clave),
+	)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer conexion.Close()
+	if usuarios, err := mensajero.Ejecutar(cliente, ctx, "listar"); err != nil || usuarios != usuario+"\n" {
+		t.Errorf("Se esperaba que el usuario fuera %s, se obtuvo %q, %v", usuario, usuarios, err)
+	}
+
+	_, _, _, err = mensajero.ConfigurarCliente(direccion, stringAleatorio(12), 3,
+		mensajero.ConTLS(autoridad.archivo),
+		mensajero.ConCertificadoCliente(certificado, clave),
+	)
+	if err == nil || !strings.Contains(err.Error(), "PermissionDenied") {
+		t.Errorf("Se esperaba que no se pudiera usar el certificado de otro usuario, se obtuvo %v", err)
+	}
+
+	if _, _, _, err := mensajero.ConfigurarCliente(direccion, stringAleatorio(12), 1, mensajero.ConTLS(autoridad.archivo)); err == nil {
+		t.Errorf("Se esperaba que un cliente sin certificado no pudiera conectarse")
+	}
+	extrano, claveExtrano := nuevaAutoridad(t, "extraña").certificadoCliente(usuario)
+	_, _, _, err = mensajero.ConfigurarCliente(direccion, "", 1,
+		mensajero.ConTLS(autoridad.archivo),
+		mensajero.ConCertificadoCliente(extrano, claveExtrano),
+	)
+	if err == nil {
+		t.Errorf("Se esperaba que no se aceptara un certificado de otra autoridad")
+	}
+}
@@ -0,0 +1,65 @@
+package mensajero
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"google.golang.org/grpc"
+	mensajero "mensajero/pkg"
+)
+
+// Inicia un servidor con las opciones indicadas en un puerto aleatorio y devuelve el
+// servicio y su dirección. El servidor se detiene al terminar la prueba.
+func iniciarServidor(t *testing.T, opciones ...mensajero.OpcionServidor) (*mensajero.Servidor, string) {
+	return iniciarServidorGrpc(t, nil, opciones...)
+}
+
+// Como `iniciarServidor`, con opciones adicionales para el servidor de gRPC, como sus
+// credenciales.
+func iniciarServidorGrpc(t *testing.T, opcionesGrpc []grpc.ServerOption, opciones ...mensajero.OpcionServidor) (*mensajero.Servidor, string) {
+	servicioMensajero := mensajero.NuevoServidor(opciones...)
+	servidorReal := grpc.NewServer(append([]grpc.ServerOption{
+		grpc.UnaryInterceptor(servicioMensajero.Interceptor),
+		grpc.StreamInterceptor(servicioMensajero.InterceptorFlujo),
+	}, opcionesGrpc...)...)
+
+	listen, puerto, err := mensajero.AbrirListener("")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	mensajero.RegisterMensajeroServer(servidorReal, servicioMensajero)
+	go servidorReal.Serve(listen)
+	t.Cleanup(func() {
+		servidorReal.GracefulStop()
+		servicioMensajero.Cerrar()
+	})
+
+	return servicioMensajero, fmt.Sprintf("localhost:%s", puerto)
+}
+
+// La contraseña de las cuentas que crean las pruebas.
+const contrasenaPrueba = "secreto"
+
+// Devuelve la opción de un servidor con cuentas, guardadas en un almacén nuevo con un
+// costo bajo para que las pruebas no demoren. Sin cuentas el servidor no deja abrir una
+// segunda sesión de un usuario conectado, ya que nada prueba que sea el mismo.
+func conCuentas(t *testing.T) mensajero.OpcionServidor {
+	credenciales, err := mensajero.AbrirAlmacenCredenciales(filepath.Join(t.TempDir(), "cuentas.json"), mensajero.OpcionesCredenciales{Costo: 1 << 10, Bloque: 8, Paralelismo: 1})
+	if err != nil {
+		t.Fatalf("No se pudo abrir el almacén de cuentas: %s", err)
+	}
+	return mensajero.ConCredenciales(credenciales)
+}
+
+// La fecha con la que el cliente muestra cada mensaje.
+var fechaMensaje = regexp.MustCompile(`(?m)^\[\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}\] `)
+
+// Ejecuta "obtener" y quita la fecha de cada mensaje, que depende del momento del envío.
+func obtenerSinFechas(cliente mensajero.MensajeroClient, ctx context.Context) (string, error) {
+	mensajes, err := mensajero.Ejecutar(cliente, ctx, "obtener")
+	return fechaMensaje.ReplaceAllString(mensajes, ""), err
+}